@@ -0,0 +1,544 @@
+package main
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/logging"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/scriptgen"
+	"sih2025/internal/signing"
+	"sih2025/internal/state"
+)
+
+// openapiYAML describes /api/v1; keep it in step with registerAPIv1.
+//
+//go:embed openapi.yaml
+var openapiYAML []byte
+
+// Error codes of the /api/v1 error envelope
+const (
+	codeInvalidRequest = "invalid_request"
+	codeNotFound       = "not_found"
+	codeConflict       = "conflict"
+	codeFixFailed      = "fix_failed"
+	codeRevertFailed   = "revert_failed"
+	codeInternal       = "internal_error"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// apiError is the body of every /api/v1 error: {"error": {...}}.
+type apiError struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	RequestID string      `json:"request_id,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+// pagination is the "pagination" member of a /api/v1 list response.
+type pagination struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	Total      int `json:"total"`
+	TotalPages int `json:"total_pages"`
+}
+
+// respond writes a /api/v1 success envelope: {"data": ...}.
+func respond(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, gin.H{"data": data})
+}
+
+// respondPage writes one page of a list: {"data": [...], "pagination": {...}}.
+func respondPage(c *gin.Context, data interface{}, page, perPage, total int) {
+	c.JSON(200, gin.H{"data": data, "pagination": pagination{
+		Page:       page,
+		PerPage:    perPage,
+		Total:      total,
+		TotalPages: (total + perPage - 1) / perPage,
+	}})
+}
+
+// apiFail writes a /api/v1 error envelope carrying the request's
+// correlation ID, so a failure can be found in the logs.
+func apiFail(c *gin.Context, status int, code, message string, details interface{}) {
+	c.AbortWithStatusJSON(status, gin.H{"error": apiError{
+		Code:      code,
+		Message:   message,
+		RequestID: logging.ID(c.Request.Context()),
+		Details:   details,
+	}})
+}
+
+// pageQuery reads ?page= (from 1) and ?per_page= (up to maxPageSize).
+func pageQuery(c *gin.Context) (int, int, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return 0, 0, fmt.Errorf("page must be a positive number")
+	}
+	perPage, err := strconv.Atoi(c.DefaultQuery("per_page", strconv.Itoa(defaultPageSize)))
+	if err != nil || perPage < 1 || perPage > maxPageSize {
+		return 0, 0, fmt.Errorf("per_page must be between 1 and %d", maxPageSize)
+	}
+	return page, perPage, nil
+}
+
+// pageBounds is the slice [lo:hi] of n items shown on a page.
+func pageBounds(n, page, perPage int) (int, int) {
+	lo := (page - 1) * perPage
+	if lo > n {
+		lo = n
+	}
+	hi := lo + perPage
+	if hi > n {
+		hi = n
+	}
+	return lo, hi
+}
+
+// bindOptionalJSON reads a JSON body that may be left out altogether.
+func bindOptionalJSON(c *gin.Context, v interface{}) error {
+	if err := c.ShouldBindJSON(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func validProfile(profile string) bool {
+	return profile == "strict" || profile == "moderate" || profile == "basic"
+}
+
+func findRule(pol *policy.Policy, id string) (policy.Rule, bool) {
+	for _, rule := range pol.Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return policy.Rule{}, false
+}
+
+// ruleMatches applies GET /api/v1/rules' severity, category and type
+// filters; a rule's tags count as categories too.
+func ruleMatches(r policy.Rule, severity, category, ruleType string) bool {
+	if severity != "" && !strings.EqualFold(r.Severity, severity) {
+		return false
+	}
+	if ruleType != "" && r.Type != ruleType {
+		return false
+	}
+	if category == "" || strings.EqualFold(r.Category, category) {
+		return true
+	}
+	for _, tag := range r.Tags {
+		if strings.EqualFold(tag, category) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleView is a rule as GET /api/v1/rules shows it: the policy entry and
+// whether a fix for it is currently applied on this machine.
+type ruleView struct {
+	policy.Rule
+	Fixed bool `json:"fixed"`
+}
+
+// waiverView is a waiver as GET /api/v1/waivers shows it.
+type waiverView struct {
+	state.Waiver
+	Expired bool `json:"expired"`
+}
+
+// legacySuccessors maps the unversioned /api routes to their /api/v1
+// replacements, for the Link header of deprecated responses.
+var legacySuccessors = map[string]string{
+	"/api/status":             "/api/v1/status",
+	"/api/scan":               "/api/v1/scans",
+	"/api/fix":                "/api/v1/fixes",
+	"/api/rollback":           "/api/v1/rules/{id}/revert",
+	"/api/export":             "/api/v1/reports/{format}",
+	"/api/reset":              "/api/v1/fixes/revert",
+	"/api/waivers":            "/api/v1/waivers",
+	"/api/waivers/:id":        "/api/v1/waivers/{id}",
+	"/api/remediation":        "/api/v1/remediation",
+	"/api/history":            "/api/v1/fixes",
+	"/api/history/:id/revert": "/api/v1/fixes/{id}/revert",
+	"/api/config":             "/api/v1/config",
+}
+
+// legacyDeprecatedAt is when /api/v1 replaced the unversioned /api routes.
+var legacyDeprecatedAt = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+
+// deprecatedAPI marks responses of the unversioned /api routes as deprecated
+// since legacyDeprecatedAt (RFC 9745: "@" and Unix seconds) and links to
+// their successor. They keep working as before.
+func deprecatedAPI() gin.HandlerFunc {
+	deprecation := "@" + strconv.FormatInt(legacyDeprecatedAt.Unix(), 10)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if next, ok := legacySuccessors[c.FullPath()]; ok {
+			c.Header("Link", "<"+next+`>; rel="successor-version"`)
+		}
+		c.Next()
+	}
+}
+
+// registerAPIv1 mounts the versioned API. Every JSON response is either
+// {"data": ...} (lists add "pagination") or {"error": {"code", "message",
+// "request_id", "details"}}; openapi.yaml is the reference.
+func registerAPIv1(r *gin.Engine) {
+	openapiJSON, err := yaml.YAMLToJSON(openapiYAML)
+	if err != nil {
+		fatal("invalid OpenAPI document", "err", err)
+	}
+
+	v1 := r.Group("/api/v1")
+
+	// 1. API DESCRIPTION
+	v1.GET("/openapi.yaml", func(c *gin.Context) { c.Data(200, "application/yaml", openapiYAML) })
+	v1.GET("/openapi.json", func(c *gin.Context) { c.Data(200, "application/json", openapiJSON) })
+
+	// 2. STATUS
+	v1.GET("/status", func(c *gin.Context) {
+		respond(c, 200, gin.H{"status": "online", "os": osLabel(), "host": thisHost()})
+	})
+
+	// 3. RULES (the current policy)
+	v1.GET("/rules", func(c *gin.Context) {
+		page, perPage, err := pageQuery(c)
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, err.Error(), nil)
+			return
+		}
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			apiFail(c, 500, codeInternal, "Failed to load policy", nil)
+			return
+		}
+		rules := pol.Rules
+		if profile := c.Query("profile"); profile != "" {
+			if !validProfile(profile) {
+				apiFail(c, 400, codeInvalidRequest, "profile must be strict, moderate or basic", nil)
+				return
+			}
+			rules = policy.FilterByProfile(rules, profile)
+		}
+		var matched []ruleView
+		for _, rule := range rules {
+			if ruleMatches(rule, c.Query("severity"), c.Query("category"), c.Query("type")) {
+				matched = append(matched, ruleView{Rule: rule})
+			}
+		}
+		fixed, err := engine.FixedRules(c.Request.Context(), platform.GetPlatform())
+		if err != nil {
+			apiFail(c, 500, codeInternal, "Failed to read the fix history", nil)
+			return
+		}
+		lo, hi := pageBounds(len(matched), page, perPage)
+		out := make([]ruleView, 0, hi-lo)
+		for _, v := range matched[lo:hi] {
+			v.Fixed = fixed[v.ID]
+			out = append(out, v)
+		}
+		respondPage(c, out, page, perPage, len(matched))
+	})
+
+	v1.GET("/rules/:id", func(c *gin.Context) {
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			apiFail(c, 500, codeInternal, "Failed to load policy", nil)
+			return
+		}
+		rule, ok := findRule(pol, c.Param("id"))
+		if !ok {
+			apiFail(c, 404, codeNotFound, "Rule not found", nil)
+			return
+		}
+		fixed, err := engine.FixedRules(c.Request.Context(), platform.GetPlatform())
+		if err != nil {
+			apiFail(c, 500, codeInternal, "Failed to read the fix history", nil)
+			return
+		}
+		respond(c, 200, ruleView{Rule: rule, Fixed: fixed[rule.ID]})
+	})
+
+	// Roll back the rule's current fix
+	v1.POST("/rules/:id/revert", func(c *gin.Context) {
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			apiFail(c, 500, codeInternal, "Failed to load policy", nil)
+			return
+		}
+		rule, ok := findRule(pol, c.Param("id"))
+		if !ok {
+			apiFail(c, 404, codeNotFound, "Rule not found", nil)
+			return
+		}
+		if err := engine.RevertFixBy(c.Request.Context(), platform.GetPlatform(), rule, webActor(c)); err != nil {
+			apiFail(c, 422, codeRevertFailed, err.Error(), nil)
+			return
+		}
+		respond(c, 200, gin.H{"id": rule.ID, "status": "reverted"})
+	})
+
+	// 4. SCANS, RESULTS AND REPORTS
+	registerScanRoutes(v1)
+
+	// 5. FIXES (apply, history, revert)
+	v1.POST("/fixes", func(c *gin.Context) {
+		var req struct {
+			RuleID string `json:"rule_id"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || req.RuleID == "" {
+			apiFail(c, 400, codeInvalidRequest, "rule_id is required", nil)
+			return
+		}
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			apiFail(c, 500, codeInternal, "Failed to load policy", nil)
+			return
+		}
+		rule, ok := findRule(pol, req.RuleID)
+		if !ok {
+			apiFail(c, 404, codeNotFound, "Rule not found", nil)
+			return
+		}
+		res, err := engine.ApplyFixBy(c.Request.Context(), platform.GetPlatform(), rule, webActor(c))
+		if err != nil {
+			apiFail(c, 422, codeFixFailed, err.Error(), res)
+			return
+		}
+		// A rule that already passed is left alone: nothing was created
+		if res.Unchanged {
+			respond(c, 200, res)
+			return
+		}
+		if res.HistoryID != 0 {
+			c.Header("Location", fmt.Sprintf("/api/v1/fixes/%d", res.HistoryID))
+		}
+		respond(c, 201, res)
+	})
+
+	v1.GET("/fixes", func(c *gin.Context) {
+		f, page, perPage, err := historyFilter(c)
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, err.Error(), nil)
+			return
+		}
+		entries, total, err := state.ListHistory(f)
+		if err != nil {
+			apiFail(c, 500, codeInternal, err.Error(), nil)
+			return
+		}
+		if entries == nil {
+			entries = []state.HistoryEntry{}
+		}
+		respondPage(c, entries, page, perPage, total)
+	})
+
+	v1.GET("/fixes/:id", func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, "Invalid fix id", nil)
+			return
+		}
+		entry, err := state.GetHistoryEntry(id)
+		if err != nil {
+			apiFail(c, 500, codeInternal, err.Error(), nil)
+			return
+		}
+		if entry == nil {
+			apiFail(c, 404, codeNotFound, "Fix not found", nil)
+			return
+		}
+		respond(c, 200, entry)
+	})
+
+	// Revert one fix; later changes to the same rule, file or key need "force"
+	v1.POST("/fixes/:id/revert", func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, "Invalid fix id", nil)
+			return
+		}
+		var req struct {
+			Force bool `json:"force"`
+		}
+		if err := bindOptionalJSON(c, &req); err != nil {
+			apiFail(c, 400, codeInvalidRequest, "Invalid request body", nil)
+			return
+		}
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			apiFail(c, 500, codeInternal, "Failed to load policy", nil)
+			return
+		}
+		res, err := engine.RevertEntry(c.Request.Context(), platform.GetPlatform(), pol, id, webActor(c), req.Force)
+		var conflict *engine.ConflictError
+		switch {
+		case errors.Is(err, engine.ErrEntryNotFound):
+			apiFail(c, 404, codeNotFound, "Fix not found", nil)
+		case errors.As(err, &conflict):
+			apiFail(c, 409, codeConflict, err.Error(), gin.H{"conflicts": conflict.Conflicts})
+		case err != nil:
+			apiFail(c, 422, codeRevertFailed, err.Error(), nil)
+		default:
+			respond(c, 200, res)
+		}
+	})
+
+	// Revert every recorded fix, or those since a transaction or time
+	v1.POST("/fixes/revert", func(c *gin.Context) {
+		var req struct {
+			BeforeTx string    `json:"before_tx"`
+			Before   time.Time `json:"before"`
+		}
+		if err := bindOptionalJSON(c, &req); err != nil {
+			apiFail(c, 400, codeInvalidRequest, "Invalid request body", nil)
+			return
+		}
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			apiFail(c, 500, codeInternal, "Failed to load policy", nil)
+			return
+		}
+		res, err := engine.RevertAllBy(c.Request.Context(), platform.GetPlatform(), pol, engine.RevertScope{BeforeTx: req.BeforeTx, Before: req.Before}, webActor(c))
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, err.Error(), nil)
+			return
+		}
+		respond(c, 200, res)
+	})
+
+	// 6. WAIVERS (risk acceptance)
+	v1.GET("/waivers", func(c *gin.Context) {
+		page, perPage, err := pageQuery(c)
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, err.Error(), nil)
+			return
+		}
+		list, err := state.ListWaivers()
+		if err != nil {
+			apiFail(c, 500, codeInternal, err.Error(), nil)
+			return
+		}
+		now := time.Now()
+		lo, hi := pageBounds(len(list), page, perPage)
+		out := make([]waiverView, 0, hi-lo)
+		for _, w := range list[lo:hi] {
+			out = append(out, waiverView{Waiver: w, Expired: w.Expired(now)})
+		}
+		respondPage(c, out, page, perPage, len(list))
+	})
+
+	v1.POST("/waivers", func(c *gin.Context) {
+		var req waiverRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apiFail(c, 400, codeInvalidRequest, "Invalid request body", nil)
+			return
+		}
+		w, err := req.toWaiver()
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, err.Error(), nil)
+			return
+		}
+		id, err := state.AddWaiver(w)
+		if err != nil {
+			apiFail(c, 500, codeInternal, err.Error(), nil)
+			return
+		}
+		w.ID = id
+		slog.InfoContext(c.Request.Context(), "waiver added", "rule", w.RuleID, "scope", w.Scope, "target", w.Target, "approver", w.Approver, "expires", w.ExpiresAt.Format(time.RFC3339))
+		c.Header("Location", fmt.Sprintf("/api/v1/waivers/%d", id))
+		respond(c, 201, waiverView{Waiver: w})
+	})
+
+	v1.DELETE("/waivers/:id", func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, "Invalid waiver id", nil)
+			return
+		}
+		found, err := state.DeleteWaiver(id)
+		if err != nil {
+			apiFail(c, 500, codeInternal, err.Error(), nil)
+			return
+		}
+		if !found {
+			apiFail(c, 404, codeNotFound, "Waiver not found", nil)
+			return
+		}
+		c.Status(204)
+	})
+
+	// 7. REMEDIATION SCRIPTS (zip, like GET /api/remediation)
+	v1.GET("/remediation", func(c *gin.Context) {
+		profile := c.DefaultQuery("profile", cfg.DefaultProfile)
+		if !validProfile(profile) {
+			apiFail(c, 400, codeInvalidRequest, "profile must be strict, moderate or basic", nil)
+			return
+		}
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			apiFail(c, 500, codeInternal, "Failed to load policy", nil)
+			return
+		}
+		data, name, count, err := remediationZip(pol, profile, c.DefaultQuery("format", scriptgen.FormatShell), c.Query("all") == "1")
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, err.Error(), nil)
+			return
+		}
+		if err := signRemediationZip(data, name); err != nil {
+			apiFail(c, 500, codeInternal, "Failed to sign the zip: "+err.Error(), nil)
+			return
+		}
+		slog.InfoContext(c.Request.Context(), "remediations exported", "rules", count, "file", name)
+		c.Header("Content-Disposition", "attachment; filename="+name)
+		c.Data(200, "application/zip", data)
+	})
+	// Detached signature of a zip downloaded above, named as in its Content-Disposition
+	v1.GET("/remediation/:file/signature", func(c *gin.Context) {
+		if !signing.Enabled() {
+			apiFail(c, 404, codeNotFound, "Report signing is disabled", nil)
+			return
+		}
+		path := remediationSignature(c.Param("file"))
+		if path == "" {
+			apiFail(c, 404, codeNotFound, "No signed remediation zip by that name", nil)
+			return
+		}
+		c.Header("Content-Type", "application/json")
+		c.Header("Content-Disposition", "attachment; filename="+filepath.Base(path))
+		c.File(path)
+	})
+
+	// 8. EFFECTIVE CONFIGURATION (secrets redacted)
+	v1.GET("/config", func(c *gin.Context) {
+		respond(c, 200, cfg.Public())
+	})
+
+	// Unknown /api/v1 paths get the error envelope too
+	r.NoRoute(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/v1/") {
+			apiFail(c, 404, codeNotFound, "No such endpoint", nil)
+			return
+		}
+		c.String(404, "404 page not found")
+	})
+}
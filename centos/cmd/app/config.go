@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sih2025/internal/config"
+	"sih2025/internal/engine"
+	"sih2025/internal/logging"
+	"sih2025/internal/platform"
+	"sih2025/internal/report"
+	"sih2025/internal/state"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cfg is the effective configuration, set by setupConfig before anything runs
+var cfg *config.Config
+
+// setupConfig loads the config file (path, else SENTINELX_CONFIG, else the
+// default locations) and the environment, lets override apply command-line
+// flags, then resolves and validates the result. An invalid configuration is
+// fatal.
+func setupConfig(path string, override func(*config.Config)) {
+	c, err := config.Load(path)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if override != nil {
+		override(c)
+	}
+	if err := c.Resolve(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	cfg = c
+
+	state.DBPath = c.DBPath
+	report.Dir = c.ReportDir
+	engine.CheckTimeout = time.Duration(c.Timeouts.Check)
+	platform.SSHTimeout = time.Duration(c.Timeouts.SSH)
+	if err := logging.Setup(c.LogOptions()); err != nil {
+		log.Fatalf("Invalid configuration: log: %v", err)
+	}
+	if c.Source != "" {
+		slog.Info("config loaded", "file", c.Source)
+	}
+}
+
+// setFlags returns the names of the flags given on the command line, so only
+// those override the config file.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	return set
+}
+
+// checkTemplates fails early when the UI templates are not where the config
+// says, instead of on the first page load.
+func checkTemplates() {
+	if _, err := os.Stat(filepath.Join(cfg.TemplatesDir, "index.html")); err != nil {
+		fatal("UI templates not found (set templates_dir or base_dir)", "dir", cfg.TemplatesDir)
+	}
+}
+
+func registerConfigRoutes(api *gin.RouterGroup) {
+	// Effective configuration, secrets redacted
+	api.GET("/config", func(c *gin.Context) {
+		c.JSON(200, cfg.Public())
+	})
+}
+
+// dashboardPort is the ":port" part of a listen address, for the startup
+// banner.
+func dashboardPort(listen string) string {
+	if i := strings.LastIndex(listen, ":"); i >= 0 {
+		return listen[i:]
+	}
+	return listen
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/fleet"
+	"sih2025/internal/logging"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+)
+
+// runCollector implements `sentinelx collector`: it receives scan runs from
+// agents over HTTPS and serves the fleet dashboard.
+func runCollector(args []string) int {
+	fs := flag.NewFlagSet("collector", flag.ExitOnError)
+	listen := fs.String("listen", ":8443", "HTTPS listen address")
+	certFile := fs.String("cert", filepath.Join(cfg.BaseDir, "keys", "collector.crt"), "TLS certificate (generated self-signed if missing)")
+	keyFile := fs.String("key", filepath.Join(cfg.BaseDir, "keys", "collector.key"), "TLS private key")
+	hostnames := fs.String("hostnames", "localhost,127.0.0.1", "names for a generated certificate (comma separated)")
+	token := fs.String("token", cfg.Fleet.Token, "shared token agents must present")
+	viewerToken := fs.String("viewer-token", cfg.Fleet.ViewerToken, "token for the dashboard and read API (default: -token)")
+	signReports := fs.Bool("sign", cfg.Signing.Enabled, "sign exported reports with the tool's Ed25519 key")
+	keyDir := fs.String("key-dir", cfg.Signing.KeyDir, "directory holding the signing key pair")
+	fs.Parse(args)
+
+	fmt.Println("==================================================")
+	fmt.Printf("   SIH 2025 HARDENING ORCHESTRATOR - FLEET COLLECTOR\n")
+	fmt.Println("==================================================")
+
+	initDB()
+	if *signReports {
+		initSigning(*keyDir)
+	}
+	if *token == "" {
+		slog.Warn("no agent token set; any client can submit runs")
+	}
+	if *token == "" && *viewerToken == "" {
+		slog.Warn("no viewer token set; any client can read the fleet's results")
+	}
+
+	created, err := fleet.EnsureCertificate(*certFile, *keyFile, strings.Split(*hostnames, ","))
+	if err != nil {
+		slog.Error("failed to prepare TLS certificate", "err", err)
+		return 1
+	}
+	if created {
+		slog.Info("generated self-signed certificate (pin it on agents with -ca)", "cert", *certFile)
+	}
+
+	checkTemplates()
+	r := newRouter()
+	r.LoadHTMLGlob(filepath.Join(cfg.TemplatesDir, "*"))
+	r.Static("/static", cfg.StaticDir)
+	fleet.RegisterRoutes(r, *token, *viewerToken)
+
+	slog.Info("fleet dashboard available", "url", "https://localhost"+dashboardPort(*listen)+"/fleet")
+	if err := r.RunTLS(*listen, *certFile, *keyFile); err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	return 0
+}
+
+// runAgent implements `sentinelx agent`: it audits this host on a schedule and
+// pushes every run to the collector.
+func runAgent(args []string) int {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	collectorURL := fs.String("collector", "https://localhost:8443", "collector base URL")
+	token := fs.String("token", cfg.Fleet.Token, "shared collector token")
+	caFile := fs.String("ca", "", "CA or self-signed collector certificate to trust")
+	insecure := fs.Bool("insecure", false, "skip TLS certificate verification (testing only; still https)")
+	interval := fs.Duration("interval", time.Hour, "time between scans")
+	profile := fs.String("level", cfg.DefaultProfile, "hardening profile: strict, moderate or basic")
+	host := fs.String("host", "", "host name reported to the collector (default: hostname)")
+	once := fs.Bool("once", false, "scan and push a single time, then exit")
+	fs.Parse(args)
+
+	agent, err := fleet.NewAgent(*collectorURL, *token, *caFile, *insecure)
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	if *host == "" {
+		*host, _ = os.Hostname()
+	}
+	osName := runtime.GOOS
+	if osName == "linux" {
+		osName = getLinuxDistro()
+	}
+
+	slog.Info("agent started", "host", *host, "collector", *collectorURL, "interval", *interval, "profile", *profile)
+	for {
+		// One correlation ID per scan, sent along so the collector logs it too
+		ctx := logging.WithID(context.Background(), logging.NewID())
+		if err := pushScan(ctx, agent, *host, osName, *profile); err != nil {
+			slog.ErrorContext(ctx, "scan push failed", "err", err)
+			if *once {
+				return 1
+			}
+		}
+		if *once {
+			return 0
+		}
+		time.Sleep(*interval)
+	}
+}
+
+func pushScan(ctx context.Context, agent *fleet.Agent, host, osName, profile string) error {
+	pol := loadCurrentPolicy()
+	if pol == nil {
+		return fmt.Errorf("failed to load policy")
+	}
+	pol.Rules = policy.FilterByProfile(pol.Rules, profile)
+
+	started := time.Now()
+	results := engine.RunAuditContext(ctx, platform.GetPlatform(), pol)
+
+	runID, err := agent.Push(ctx, fleet.Submission{
+		Host:      host,
+		OS:        osName,
+		Profile:   profile,
+		ScannedAt: started,
+		Results:   results,
+	})
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "scan pushed", "results", len(results), "run", runID)
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/platform"
+	"sih2025/internal/state"
+)
+
+// webActor names a dashboard/API client in the fix history.
+func webActor(c *gin.Context) string {
+	return "web:" + c.ClientIP()
+}
+
+// historyFilter reads GET /api/history's query: rule, tx, from, to (RFC 3339
+// or YYYY-MM-DD; a date "to" includes that day), page and per_page.
+func historyFilter(c *gin.Context) (state.HistoryFilter, int, int, error) {
+	f := state.HistoryFilter{RuleID: strings.TrimSpace(c.Query("rule")), TxID: strings.TrimSpace(c.Query("tx"))}
+	var err error
+	if s := c.Query("from"); s != "" {
+		if f.From, err = parseHistoryTime(s, false); err != nil {
+			return f, 0, 0, fmt.Errorf("from: %v", err)
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if f.To, err = parseHistoryTime(s, true); err != nil {
+			return f, 0, 0, fmt.Errorf("to: %v", err)
+		}
+	}
+
+	page, perPage, err := pageQuery(c)
+	if err != nil {
+		return f, 0, 0, err
+	}
+	f.Limit, f.Offset = perPage, (page-1)*perPage
+	return f, page, perPage, nil
+}
+
+// parseHistoryTime accepts a timestamp or a date; as an upper bound a date
+// means the end of that day.
+func parseHistoryTime(s string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be RFC 3339 or YYYY-MM-DD, got %q", s)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func registerHistoryRoutes(api *gin.RouterGroup) {
+	api.GET("/history", func(c *gin.Context) {
+		f, page, perPage, err := historyFilter(c)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		entries, total, err := state.ListHistory(f)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		if entries == nil {
+			entries = []state.HistoryEntry{}
+		}
+		c.JSON(200, gin.H{"entries": entries, "total": total, "page": page, "per_page": perPage})
+	})
+
+	// Revert one entry; later changes to the same rule, file or key need "force"
+	api.POST("/history/:id/revert", func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid history id"})
+			return
+		}
+		var req struct {
+			Force bool `json:"force"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			c.JSON(500, gin.H{"error": "Failed to load policy"})
+			return
+		}
+		res, err := engine.RevertEntry(c.Request.Context(), platform.GetPlatform(), pol, id, webActor(c), req.Force)
+		var conflict *engine.ConflictError
+		switch {
+		case errors.Is(err, engine.ErrEntryNotFound):
+			c.JSON(404, gin.H{"error": "History entry not found"})
+		case errors.As(err, &conflict):
+			c.JSON(409, gin.H{"error": err.Error(), "conflicts": conflict.Conflicts})
+		case err != nil:
+			c.JSON(400, gin.H{"error": err.Error()})
+		default:
+			c.JSON(200, gin.H{"status": "rolled_back", "result": res})
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"sih2025/internal/xccdf"
+)
+
+// runImportXCCDF implements `sentinelx import-xccdf`: convert a CIS/SCAP
+// benchmark into a policy file. Rules that can't be automated are imported as
+// manual rules with needs_review set.
+func runImportXCCDF(args []string) int {
+	fs := flag.NewFlagSet("import-xccdf", flag.ExitOnError)
+	xccdfPath := fs.String("xccdf", "", "XCCDF benchmark or SCAP data stream (required)")
+	ovalPaths := fs.String("oval", "", "comma-separated OVAL definition files (default: definitions in the -xccdf file)")
+	profile := fs.String("profile", "", "XCCDF profile to import (default: all rules)")
+	targetOS := fs.String("platform", "linux", "platform of the generated rules")
+	outPath := fs.String("out", "policies/imported.json", "policy file to write")
+	listProfiles := fs.Bool("list-profiles", false, "list the benchmark's profiles and exit")
+	fs.Parse(args)
+
+	if *xccdfPath == "" {
+		fmt.Fprintln(os.Stderr, "usage: sentinelx import-xccdf -xccdf <file> [-oval a.xml,b.xml] [-profile id] [-out policy.json]")
+		return 2
+	}
+	var ovals []string
+	for _, p := range strings.Split(*ovalPaths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			ovals = append(ovals, p)
+		}
+	}
+
+	opts := xccdf.Options{Profile: *profile, Platform: *targetOS}
+	if *listProfiles {
+		opts.Profile = ""
+	}
+	rep, err := xccdf.ImportFiles(*xccdfPath, ovals, opts)
+	if err != nil {
+		slog.Error("import failed", "err", err)
+		return 1
+	}
+	if *listProfiles {
+		for _, p := range rep.Profiles {
+			fmt.Println(p)
+		}
+		return 0
+	}
+
+	data, _ := json.MarshalIndent(rep.Policy, "", "  ")
+	if err := os.WriteFile(*outPath, data, 0644); err != nil {
+		slog.Error("failed to write output", "path", *outPath, "err", err)
+		return 1
+	}
+	fmt.Printf("[IMPORT] %s: %d rules -> %s\n", rep.Title, len(rep.Policy.Rules), *outPath)
+	fmt.Printf("   Automated checks: %d\n", rep.Automated)
+	fmt.Printf("   Needs review:     %d\n", rep.Review)
+	return 0
+}
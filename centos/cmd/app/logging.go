@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"sih2025/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+var httpLogger = logging.Logger("http")
+
+// newRouter is gin without its own access log: requestLogger takes its place.
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(requestLogger(), gin.Recovery())
+	return r
+}
+
+// requestLogger gives each request a correlation ID (the client's
+// X-Request-ID when it looks sane, else a new one), returns it in the
+// response and logs the request once it is done. Handlers pass
+// c.Request.Context() on so the engine logs under the same ID.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if !validRequestID(id) {
+			id = logging.NewID()
+		}
+		ctx := logging.WithID(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(ctx)
+		c.Header("X-Request-ID", id)
+
+		started := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case strings.HasPrefix(c.Request.URL.Path, "/static/"):
+			level = slog.LevelDebug
+		}
+		httpLogger.Log(ctx, level, "request", "method", c.Request.Method, "path", c.Request.URL.Path, "status", status,
+			"duration_ms", float64(time.Since(started).Microseconds())/1000, "client", c.ClientIP())
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// fatal logs an error and exits, for failures after logging is set up.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
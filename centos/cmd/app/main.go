@@ -1,22 +1,114 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
+	"sih2025/internal/config"
 	"sih2025/internal/engine"
+	"sih2025/internal/metrics"
+	"sih2025/internal/platform"
 	"sih2025/internal/policy"
 	"sih2025/internal/report"
+	"sih2025/internal/signing"
 	"sih2025/internal/state"
 
 	"github.com/gin-gonic/gin"
 	_ "modernc.org/sqlite"
 )
 
+// rootDir is set by --root when auditing an offline image instead of this host
+var rootDir string
+
+// subcommands run instead of the dashboard when named as the first argument
+var subcommands = map[string]func([]string) int{
+	"verify":       runVerify,
+	"collector":    runCollector,
+	"agent":        runAgent,
+	"remote":       runRemote,
+	"policy":       runPolicy,
+	"waiver":       runWaiver,
+	"import-xccdf": runImportXCCDF,
+	"export-fixes": runExportFixes,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			// Subcommands read the config file named by SENTINELX_CONFIG or
+			// the default locations; their own flags override it
+			setupConfig("", nil)
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
+	configPath := flag.String("config", "", "config file (default: SENTINELX_CONFIG or "+config.DefaultPaths()[0]+")")
+	listen := flag.String("listen", "", "dashboard listen address (default :8080)")
+	dbPath := flag.String("db", "", "SQLite state database (default hardening.db)")
+	profile := flag.String("profile", "", "default hardening profile: strict, moderate or basic")
+	reportDir := flag.String("report-dir", "", "directory for generated reports")
+	signReports := flag.Bool("sign", false, "sign exported reports with the tool's Ed25519 key")
+	keyDir := flag.String("key-dir", "", "directory holding the signing key pair (default keys)")
+	logLevel := flag.String("log-level", "", "log level: debug, info, warn or error (default info)")
+	logFormat := flag.String("log-format", "", "log format: text or json (default text)")
+	logFile := flag.String("log-file", "", "log to this file, rotated by size, instead of stderr")
+	flag.StringVar(&rootDir, "root", "", "audit an offline root filesystem mounted at this path (e.g. /mnt/image)")
+	platformName := flag.String("platform", "native", "platform backend: native or fake (in-memory, for demos and tests)")
+	flag.Parse()
+
+	// Flags win over the environment and the config file, but only when given
+	set := setFlags(flag.CommandLine)
+	setupConfig(*configPath, func(c *config.Config) {
+		if set["listen"] {
+			c.Listen = *listen
+		}
+		if set["db"] {
+			c.DBPath = *dbPath
+		}
+		if set["profile"] {
+			c.DefaultProfile = *profile
+		}
+		if set["report-dir"] {
+			c.ReportDir = *reportDir
+		}
+		if set["sign"] {
+			c.Signing.Enabled = *signReports
+		}
+		if set["key-dir"] {
+			c.Signing.KeyDir = *keyDir
+		}
+		if set["log-level"] {
+			c.Log.Level = *logLevel
+		}
+		if set["log-format"] {
+			c.Log.Format = *logFormat
+		}
+		if set["log-file"] {
+			c.Log.Output, c.Log.File = "file", *logFile
+		}
+	})
+	checkTemplates()
+
+	if *platformName != "native" {
+		if _, err := platform.UsePlatform(*platformName); err != nil {
+			fatal("failed to select platform", "err", err)
+		}
+	}
+	if rootDir != "" {
+		worker, err := platform.NewRootHardener(rootDir)
+		if err != nil {
+			fatal("failed to open root filesystem", "err", err)
+		}
+		platform.SetPlatform(worker)
+	}
+
 	distro := "Windows"
 	if runtime.GOOS == "linux" {
 		distro = getLinuxDistro()
@@ -25,40 +117,53 @@ func main() {
 	fmt.Println("==================================================")
 	fmt.Printf("   SIH 2025 HARDENING ORCHESTRATOR (v2.0)\n")
 	fmt.Printf("   DETECTED OS: %s\n", strings.ToUpper(distro))
+	if rootDir != "" {
+		fmt.Printf("   OFFLINE IMAGE: %s\n", rootDir)
+	}
 	fmt.Println("==================================================")
 
 	initDB()
+	if cfg.Signing.Enabled {
+		initSigning(cfg.Signing.KeyDir)
+	}
 	startServer()
 }
 
 func initDB() {
 	state.InitDB()
-	fmt.Println("[SUCCESS] State Manager Ready (Rollback Enabled)")
+	slog.Info("state manager ready (rollback enabled)", "db", state.DBPath)
+}
+
+func initSigning(keyDir string) {
+	if err := signing.Init(keyDir); err != nil {
+		fatal("failed to initialise signing key", "err", err)
+	}
+	slog.Info("report signing enabled", "key_id", signing.CurrentKeyID())
 }
 
 func startServer() {
-	gin.SetMode(gin.ReleaseMode)
-	r := gin.Default()
-	r.LoadHTMLGlob("ui/templates/*")
-	r.Static("/static", "./ui/static")
+	r := newRouter()
+	r.LoadHTMLGlob(filepath.Join(cfg.TemplatesDir, "*"))
+	r.Static("/static", cfg.StaticDir)
 
 	r.GET("/", func(c *gin.Context) { c.HTML(200, "index.html", nil) })
+	// Prometheus scrape endpoint, filled in by audits and fixes
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Versioned API; the routes below are its deprecated predecessors
+	registerAPIv1(r)
 
-	api := r.Group("/api")
+	api := r.Group("/api", deprecatedAPI())
 	{
 		// 1. STATUS (Updated to show Distro)
 		api.GET("/status", func(c *gin.Context) {
-			osName := runtime.GOOS
-			if osName == "linux" {
-				osName = getLinuxDistro()
-			}
-			c.JSON(200, gin.H{"status": "online", "os": osName})
+			c.JSON(200, gin.H{"status": "online", "os": osLabel()})
 		})
 
 		// 2. SCAN
 		api.GET("/scan", func(c *gin.Context) {
-			profile := c.DefaultQuery("level", "strict")
-			fmt.Printf("[API] Scanning with Profile: %s\n", profile)
+			profile := c.DefaultQuery("level", cfg.DefaultProfile)
+			slog.InfoContext(c.Request.Context(), "scan requested", "profile", profile)
 
 			pol := loadCurrentPolicy()
 			if pol == nil {
@@ -67,23 +172,12 @@ func startServer() {
 			}
 
 			// FILTER LOGIC
-			filteredRules := []policy.Rule{}
-			for _, rule := range pol.Rules {
-				if profile == "strict" {
-					filteredRules = append(filteredRules, rule)
-				} else if profile == "moderate" {
-					if rule.Severity == "Critical" || rule.Severity == "High" || rule.Severity == "Medium" {
-						filteredRules = append(filteredRules, rule)
-					}
-				} else if profile == "basic" {
-					if rule.Severity == "Critical" || rule.Severity == "High" {
-						filteredRules = append(filteredRules, rule)
-					}
-				}
-			}
-			pol.Rules = filteredRules
+			pol.Rules = policy.FilterByProfile(pol.Rules, profile)
 
-			results := engine.RunAudit(pol)
+			_, results, err := runScan(c.Request.Context(), pol, profile, webActor(c))
+			if err != nil {
+				slog.ErrorContext(c.Request.Context(), "failed to store scan", "err", err)
+			}
 			c.JSON(200, gin.H{"results": results})
 		})
 
@@ -100,11 +194,12 @@ func startServer() {
 			pol := loadCurrentPolicy()
 			for _, rule := range pol.Rules {
 				if rule.ID == req.ID {
-					if err := engine.ApplyFix(rule); err != nil {
-						c.JSON(500, gin.H{"error": err.Error()})
+					res, err := engine.ApplyFixBy(c.Request.Context(), platform.GetPlatform(), rule, webActor(c))
+					if err != nil {
+						c.JSON(500, gin.H{"error": err.Error(), "validation": res.Validation, "reverted": res.Reverted})
 						return
 					}
-					c.JSON(200, gin.H{"status": "fixed", "id": req.ID})
+					c.JSON(200, gin.H{"status": "fixed", "id": req.ID, "tx_id": res.TxID, "verification": res.Verification, "check_status": res.Check.Status, "actual": res.Check.Actual, "validation": res.Validation, "validated": res.Validated, "services": res.Services, "reboot_pending": res.RebootPending, "unchanged": res.Unchanged})
 					return
 				}
 			}
@@ -124,7 +219,7 @@ func startServer() {
 			pol := loadCurrentPolicy()
 			for _, rule := range pol.Rules {
 				if rule.ID == req.ID {
-					if err := engine.RevertFix(rule); err != nil {
+					if err := engine.RevertFixBy(c.Request.Context(), platform.GetPlatform(), rule, webActor(c)); err != nil {
 						c.JSON(500, gin.H{"error": err.Error()})
 						return
 					}
@@ -137,68 +232,159 @@ func startServer() {
 
 		// 5. EXPORT REPORT (With Dynamic Label)
 		api.GET("/export", func(c *gin.Context) {
-			profile := c.DefaultQuery("level", "strict")
+			profile := c.DefaultQuery("level", cfg.DefaultProfile)
 			pol := loadCurrentPolicy()
+			if pol == nil {
+				c.JSON(500, gin.H{"error": "Failed to load policy"})
+				return
+			}
+			pol.Rules = policy.FilterByProfile(pol.Rules, profile)
 
-			filteredRules := []policy.Rule{}
-			for _, rule := range pol.Rules {
-				if profile == "strict" {
-					filteredRules = append(filteredRules, rule)
-				} else if profile == "moderate" {
-					if rule.Severity == "Critical" || rule.Severity == "High" || rule.Severity == "Medium" {
-						filteredRules = append(filteredRules, rule)
-					}
-				} else if profile == "basic" {
-					if rule.Severity == "Critical" || rule.Severity == "High" {
-						filteredRules = append(filteredRules, rule)
+			started := time.Now()
+			_, results, err := runScan(c.Request.Context(), pol, profile, webActor(c))
+			if err != nil {
+				slog.ErrorContext(c.Request.Context(), "failed to store scan", "err", err)
+			}
+			label := targetLabel()
+
+			// format=xccdf / format=arf return SCAP results instead of the PDF;
+			// add sig=1 for their detached signature
+			if format := c.Query("format"); format == "xccdf" || format == "arf" {
+				info := scanInfo(label, osLabel(), profile, started)
+				generate := report.GenerateXCCDF
+				if format == "arf" {
+					generate = report.GenerateARF
+				}
+				filename, err := generate(results, pol, info)
+				if err != nil {
+					c.JSON(500, gin.H{"error": "Failed to generate " + strings.ToUpper(format) + " results"})
+					return
+				}
+				c.Header("Content-Type", "application/xml")
+				if c.Query("sig") == "1" {
+					if !signing.Enabled() {
+						c.JSON(404, gin.H{"error": "Report signing is disabled"})
+						return
 					}
+					filename += signing.SignatureExt
+					c.Header("Content-Type", "application/json")
 				}
+				c.Header("Content-Disposition", "attachment; filename="+filepath.Base(filename))
+				c.File(filename)
+				return
 			}
-			pol.Rules = filteredRules
-
-			results := engine.RunAudit(pol)
-
-			hostname, _ := os.Hostname()
-			osName := runtime.GOOS
-			if osName == "linux" {
-				osName = getLinuxDistro()
-			}
-			targetLabel := fmt.Sprintf("%s SERVER (%s)", strings.ToUpper(osName), hostname)
 
-			filename, err := report.GenerateReport(results, targetLabel)
+			filename, err := report.GenerateReport(results, label, engine.HistoryMachine(c.Request.Context(), platform.GetPlatform()))
 
 			if err != nil {
 				c.JSON(500, gin.H{"error": "Failed to generate PDF"})
 				return
 			}
-			c.Header("Content-Disposition", "attachment; filename="+filename)
-			c.Header("Content-Type", "application/pdf")
+
+			// format=json returns the result bundle, format=sig a detached signature
+			switch c.Query("format") {
+			case "json":
+				filename = strings.TrimSuffix(filename, ".pdf") + ".json"
+				c.Header("Content-Type", "application/json")
+			case "sig":
+				if !signing.Enabled() {
+					c.JSON(404, gin.H{"error": "Report signing is disabled"})
+					return
+				}
+				filename += signing.SignatureExt
+				c.Header("Content-Type", "application/json")
+			default:
+				c.Header("Content-Type", "application/pdf")
+			}
+			c.Header("Content-Disposition", "attachment; filename="+filepath.Base(filename))
 			c.File(filename)
 		})
 
 		// 6. MASTER RESET
 		api.POST("/reset", func(c *gin.Context) {
+			// Optional: only go back to before a transaction or a point in time
+			var req struct {
+				BeforeTx string    `json:"before_tx"`
+				Before   time.Time `json:"before"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+				c.JSON(400, gin.H{"error": "Invalid request"})
+				return
+			}
+
 			pol := loadCurrentPolicy()
-			summary, _ := engine.RevertAll(pol)
+			res, err := engine.RevertAllBy(c.Request.Context(), platform.GetPlatform(), pol, engine.RevertScope{BeforeTx: req.BeforeTx, Before: req.Before}, webActor(c))
+			if err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
 			c.JSON(200, gin.H{
 				"status":  "reset_complete",
-				"message": summary,
+				"message": res.Summary(),
+				"result":  res,
 			})
 		})
+
+		// 7. WAIVERS (risk acceptance)
+		registerWaiverRoutes(api)
+
+		// 8. REMEDIATION SCRIPTS (change-controlled / air-gapped hosts)
+		registerRemediationRoutes(api)
+
+		// 9. FIX HISTORY (browse, revert single entries)
+		registerHistoryRoutes(api)
+
+		// 10. EFFECTIVE CONFIGURATION
+		registerConfigRoutes(api)
+	}
+
+	slog.Info("dashboard available", "url", "http://localhost"+dashboardPort(cfg.Listen))
+	if err := r.Run(cfg.Listen); err != nil {
+		fatal("server stopped", "err", err)
+	}
+}
+
+// scanInfo describes an export's audit run. An offline image has no network
+// identity, so only its own host name is reported.
+func scanInfo(label, osName, profile string, started time.Time) report.ScanInfo {
+	info := report.ScanInfo{Target: label, OS: osName, Profile: profile, Started: started, Finished: time.Now()}
+	if rootDir == "" {
+		info.AddLocalFacts()
+		return info
+	}
+	data, _ := os.ReadFile(filepath.Join(rootDir, "/etc/hostname"))
+	info.Hostname = strings.TrimSpace(string(data))
+	if info.Hostname == "" {
+		info.Hostname = rootDir
 	}
+	return info
+}
+
+// osLabel is this machine's OS as shown to users: the Linux distribution, or
+// the Go OS name elsewhere.
+func osLabel() string {
+	if runtime.GOOS == "linux" {
+		return getLinuxDistro()
+	}
+	return runtime.GOOS
+}
 
-	fmt.Println("\n[UI] Dashboard available at http://localhost:8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal(err)
+// targetLabel names the audited system in reports.
+func targetLabel() string {
+	osName := strings.ToUpper(osLabel())
+	if rootDir != "" {
+		return fmt.Sprintf("%s IMAGE (%s)", osName, rootDir)
 	}
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s SERVER (%s)", osName, hostname)
 }
 
 func loadCurrentPolicy() *policy.Policy {
 
 	if runtime.GOOS == "windows" {
-		pol, err := policy.LoadPolicy("policies/annexure_a.json")
+		pol, err := policy.LoadPolicy(cfg.Policies.Windows)
 		if err != nil {
-			log.Printf("[ERROR] Failed to load policy: %v", err)
+			slog.Error("failed to load policy", "err", err)
 			return nil
 		}
 		return pol
@@ -207,28 +393,28 @@ func loadCurrentPolicy() *policy.Policy {
 	distro := getLinuxDistro()
 	if distro == "CentOS" {
 
-		if _, err := os.Stat("policies/annexure_b.json"); err == nil {
-			pol, err := policy.LoadPolicy("policies/annexure_b.json")
+		if _, err := os.Stat(cfg.Policies.Linux); err == nil {
+			pol, err := policy.LoadPolicy(cfg.Policies.Linux)
 			if err != nil {
-				log.Printf("[ERROR] Failed to load policy: %v", err)
+				slog.Error("failed to load policy", "err", err)
 				return nil
 			}
 			return pol
 		}
 
-		fmt.Println("[WARN] CentOS detected but 'annexure_b.json' missing. Using default.")
+		slog.Warn("CentOS detected but policy missing, using default", "policy", cfg.Policies.Linux)
 	}
 
-	pol, err := policy.LoadPolicy("policies/annexure_b.json")
+	pol, err := policy.LoadPolicy(cfg.Policies.Linux)
 	if err != nil {
-		log.Printf("[ERROR] Failed to load policy: %v", err)
+		slog.Error("failed to load policy", "err", err)
 		return nil
 	}
 	return pol
 }
 
 func getLinuxDistro() string {
-	data, err := os.ReadFile("/etc/os-release")
+	data, err := os.ReadFile(filepath.Join(rootDir, "/etc/os-release"))
 	if err != nil {
 		return "Linux"
 	}
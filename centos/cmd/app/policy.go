@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/sandbox"
+	"sih2025/internal/state"
+)
+
+// runPolicy implements `sentinelx policy <subcommand>`.
+func runPolicy(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: sentinelx policy verify [flags]")
+		return 2
+	}
+	switch args[0] {
+	case "verify":
+		if sandbox.IsChild() {
+			return runPolicyVerifySandboxed()
+		}
+		return runPolicyVerify(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown policy subcommand %q\n", args[0])
+	return 2
+}
+
+// runPolicyVerify checks every rule's fix and rollback inside a throwaway
+// overlay of this host. The real system is never modified.
+func runPolicyVerify(args []string) int {
+	fs := flag.NewFlagSet("policy verify", flag.ExitOnError)
+	policyPath := fs.String("policy", cfg.Policies.Linux, "policy file to verify")
+	profile := fs.String("level", cfg.DefaultProfile, "hardening profile: strict, moderate or basic")
+	only := fs.String("rule", "", "comma-separated rule IDs to verify (default: all)")
+	outPath := fs.String("out", "", "write the full lifecycle results as JSON to this file")
+	quiet := fs.Bool("quiet", false, "hide progress output from the sandbox")
+	fs.Parse(args)
+
+	pol, err := policy.LoadPolicy(*policyPath)
+	if err != nil {
+		slog.Error("failed to load policy", "err", err)
+		return 2
+	}
+	pol.Rules = policy.FilterByProfile(pol.Rules, *profile)
+	if *only != "" {
+		wanted := make(map[string]bool)
+		for _, id := range strings.Split(*only, ",") {
+			wanted[strings.TrimSpace(id)] = true
+		}
+		var rules []policy.Rule
+		for _, r := range pol.Rules {
+			if wanted[r.ID] {
+				rules = append(rules, r)
+			}
+		}
+		pol.Rules = rules
+	}
+
+	// The sandbox has no access to the policy file once it pivots, so the
+	// filtered policy is handed over on stdin
+	input, _ := json.Marshal(pol)
+	progress := os.Stderr
+	if *quiet {
+		progress, _ = os.Open(os.DevNull)
+	}
+
+	fmt.Printf("[VERIFY] Exercising %d rules in a sandboxed copy of this host...\n", len(pol.Rules))
+	output, err := sandbox.Run([]string{"policy", "verify"}, input, progress)
+	if err != nil {
+		slog.Error(err.Error())
+		return 2
+	}
+	var results []engine.LifecycleResult
+	if err := json.Unmarshal(output, &results); err != nil {
+		slog.Error("bad result from sandbox", "err", err)
+		return 2
+	}
+
+	if *outPath != "" {
+		data, _ := json.MarshalIndent(results, "", "  ")
+		if err := os.WriteFile(*outPath, data, 0644); err != nil {
+			slog.Error("failed to write output", "path", *outPath, "err", err)
+			return 2
+		}
+	}
+	return printLifecycleSummary(results)
+}
+
+// runPolicyVerifySandboxed is the re-executed child: enter the sandbox, read
+// the policy from stdin and write the lifecycle results to the result fd.
+func runPolicyVerifySandboxed() int {
+	if err := sandbox.Enter(); err != nil {
+		slog.Error("sandbox setup failed", "err", err)
+		return 2
+	}
+
+	var pol policy.Policy
+	if err := json.NewDecoder(os.Stdin).Decode(&pol); err != nil {
+		slog.Error("failed to read policy", "err", err)
+		return 2
+	}
+	// Fixes log to the rollback table; keep that inside the sandbox too
+	if err := state.Open(":memory:"); err != nil {
+		slog.Error(err.Error())
+		return 2
+	}
+
+	results, err := engine.VerifyLifecycle(platform.GetPlatform(), &pol, sandbox.SkipReason)
+	if err != nil {
+		slog.Error(err.Error())
+		return 2
+	}
+	out := sandbox.Result()
+	defer out.Close()
+	if err := json.NewEncoder(out).Encode(results); err != nil {
+		slog.Error(err.Error())
+		return 2
+	}
+	return 0
+}
+
+func printLifecycleSummary(results []engine.LifecycleResult) int {
+	broken := 0
+	fmt.Println()
+	for _, r := range results {
+		if r.Broken() {
+			broken++
+		}
+		fmt.Printf("   %-20s %-20s %s\n", r.Verdict, r.ID, r.Name)
+		if r.Detail != "" && r.Verdict != engine.LifecycleOK {
+			// First line only; -out has the full output
+			detail := strings.SplitN(r.Detail, "\n", 2)[0]
+			if len(detail) > 100 {
+				detail = detail[:97] + "..."
+			}
+			fmt.Printf("   %-20s %-20s > %s\n", "", "", detail)
+		}
+	}
+	fmt.Printf("\n[VERIFY] %d/%d rules have a broken lifecycle\n", broken, len(results))
+	if broken > 0 {
+		return 1
+	}
+	return 0
+}
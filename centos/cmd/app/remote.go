@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"sih2025/internal/dag"
+	"sih2025/internal/engine"
+	"sih2025/internal/logging"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+)
+
+// remoteRun is the per-host outcome written by `remote -out`.
+type remoteRun struct {
+	Host       string                 `json:"host"`
+	Error      string                 `json:"error,omitempty"`
+	Fixed      []string               `json:"fixed,omitempty"`
+	Unverified []string               `json:"unverified,omitempty"`     // fixed, but the check still fails
+	Pending    []string               `json:"pending_reboot,omitempty"` // fixed, takes effect after the next reboot
+	Services   []engine.ServiceResult `json:"services,omitempty"`
+	Results    []engine.AuditResult   `json:"results,omitempty"`
+}
+
+// runRemote implements `sentinelx remote`: agentless auditing (and optional
+// remediation) of the Linux hosts listed in an inventory file, over SSH.
+func runRemote(args []string) int {
+	fs := flag.NewFlagSet("remote", flag.ExitOnError)
+	inventoryPath := fs.String("inventory", "inventory.json", "JSON inventory of SSH hosts")
+	policyPath := fs.String("policy", cfg.Policies.Linux, "Linux policy to apply")
+	profile := fs.String("level", cfg.DefaultProfile, "hardening profile: strict, moderate or basic")
+	fix := fs.Bool("fix", false, "remediate failing rules, then re-audit")
+	outPath := fs.String("out", "", "write all results as JSON to this file")
+	fs.Parse(args)
+
+	inv, err := platform.LoadInventory(*inventoryPath)
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+	pol, err := policy.LoadPolicy(*policyPath)
+	if err != nil {
+		slog.Error("failed to load policy", "err", err)
+		return 1
+	}
+	pol.Rules = policy.FilterByProfile(pol.Rules, *profile)
+
+	if *fix {
+		initDB()
+	}
+
+	var runs []remoteRun
+	failedHosts := 0
+	for _, host := range inv.Hosts {
+		run := auditRemoteHost(host, pol, *fix)
+		if run.Error != "" {
+			failedHosts++
+		}
+		runs = append(runs, run)
+	}
+
+	if *outPath != "" {
+		data, _ := json.MarshalIndent(runs, "", "  ")
+		if err := os.WriteFile(*outPath, data, 0644); err != nil {
+			slog.Error("failed to write output", "path", *outPath, "err", err)
+			return 1
+		}
+		fmt.Printf("[SUCCESS] Results written to %s\n", *outPath)
+	}
+	if failedHosts > 0 {
+		return 1
+	}
+	return 0
+}
+
+func auditRemoteHost(host platform.SSHHost, pol *policy.Policy, fix bool) remoteRun {
+	run := remoteRun{Host: host.Name}
+	// One correlation ID per host, over its audit, fixes and re-audit
+	ctx := logging.WithID(context.Background(), logging.NewID())
+	fmt.Printf("\n[REMOTE] Connecting to %s (%s@%s)...\n", host.Name, host.User, host.Address)
+
+	worker, err := platform.NewSSHHardener(host)
+	if err != nil {
+		slog.ErrorContext(ctx, "connection failed", "host", host.Name, "err", err)
+		run.Error = err.Error()
+		return run
+	}
+	defer worker.Close()
+
+	run.Results = engine.RunAuditContext(ctx, worker, pol)
+
+	if fix {
+		failing := make(map[string]bool)
+		for _, r := range run.Results {
+			if r.Status == "FAIL" {
+				failing[r.ID] = true
+			}
+		}
+
+		// Remediate in dependency order
+		layers, err := dag.SortRulesContext(ctx, pol.Rules)
+		if err != nil {
+			run.Error = err.Error()
+			return run
+		}
+		var batch []policy.Rule
+		for _, layer := range layers {
+			for _, rule := range layer {
+				if failing[rule.ID] {
+					batch = append(batch, rule)
+				}
+			}
+		}
+		// Services (sshd reload, sysctl --system, ...) run once, after every fix
+		fixes, services := engine.ApplyFixesContext(ctx, worker, batch)
+		for _, f := range fixes {
+			switch {
+			case f.Error != "":
+				// not applied
+			case f.Verification == engine.FixVerified:
+				run.Fixed = append(run.Fixed, f.RuleID)
+			case f.Verification == engine.FixPendingReboot:
+				run.Pending = append(run.Pending, f.RuleID)
+			default:
+				run.Unverified = append(run.Unverified, f.RuleID)
+			}
+		}
+		run.Services = services
+		run.Results = engine.RunAuditContext(ctx, worker, pol)
+	}
+
+	printRemoteSummary(host.Name, run.Results)
+	return run
+}
+
+func printRemoteSummary(host string, results []engine.AuditResult) {
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+
+	pass := 0
+	for _, r := range results {
+		if r.Status != "FAIL" {
+			pass++
+		}
+		fmt.Printf("   %-6s %-20s %s\n", r.Status, r.ID, r.Name)
+	}
+	fmt.Printf("[REMOTE] %s: %d/%d controls passing\n", host, pass, len(results))
+}
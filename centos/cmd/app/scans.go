@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/report"
+	"sih2025/internal/signing"
+	"sih2025/internal/state"
+)
+
+// runScan audits this machine against pol (already filtered to profile),
+// applies waivers and stores the run as a scan for the v1 API. The results
+// are returned even when storing them fails.
+func runScan(ctx context.Context, pol *policy.Policy, profile, actor string) (*state.Scan, []engine.AuditResult, error) {
+	started := time.Now()
+	results := engine.RunAuditContext(ctx, platform.GetPlatform(), pol)
+	results = engine.ApplyWaivers(results, thisHost(), profile)
+
+	scan := state.Scan{Host: thisHost(), Profile: profile, Actor: actor, StartedAt: started, FinishedAt: time.Now()}
+	rows := make([]state.ScanResult, 0, len(results))
+	for _, r := range results {
+		// Scored as in the PDF report
+		switch r.Status {
+		case "WAIVED":
+			scan.Waived++
+		case "NOT_APPLICABLE", "PENDING_REBOOT":
+			// Not scored
+		case "FAIL":
+			scan.Fail++
+		default:
+			scan.Pass++
+		}
+		rows = append(rows, state.ScanResult{
+			RuleID:   r.ID,
+			Name:     r.Name,
+			Severity: r.Severity,
+			Status:   r.Status,
+			Actual:   r.Actual,
+			Expected: r.Expected,
+			Waiver:   r.Waiver,
+		})
+	}
+	scan.Total = scan.Pass + scan.Fail
+
+	id, err := state.SaveScan(scan, rows)
+	if err != nil {
+		return nil, results, err
+	}
+	stored, err := state.GetScan(id)
+	return stored, results, err
+}
+
+// scanParam looks up the scan named by :id, which may be "latest". It writes
+// the error response and returns nil when there is no such scan.
+func scanParam(c *gin.Context, id string) *state.Scan {
+	var scan *state.Scan
+	var err error
+	if id == "latest" {
+		scan, err = state.LatestScan()
+	} else {
+		n, convErr := strconv.ParseInt(id, 10, 64)
+		if convErr != nil {
+			apiFail(c, 400, codeInvalidRequest, "Invalid scan id", nil)
+			return nil
+		}
+		scan, err = state.GetScan(n)
+	}
+	if err != nil {
+		apiFail(c, 500, codeInternal, err.Error(), nil)
+		return nil
+	}
+	if scan == nil {
+		apiFail(c, 404, codeNotFound, "Scan not found", nil)
+		return nil
+	}
+	return scan
+}
+
+// scanAuditResults loads every result of a stored scan, for the report
+// generators.
+func scanAuditResults(scanID int64) ([]engine.AuditResult, error) {
+	rows, _, err := state.ListScanResults(state.ScanResultFilter{ScanID: scanID})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]engine.AuditResult, 0, len(rows))
+	for _, r := range rows {
+		results = append(results, engine.AuditResult{
+			ID:       r.RuleID,
+			Name:     r.Name,
+			Severity: r.Severity,
+			Status:   r.Status,
+			Actual:   r.Actual,
+			Expected: r.Expected,
+			Waiver:   r.Waiver,
+		})
+	}
+	return results, nil
+}
+
+// Report formats of GET /api/v1/reports/:format
+var reportTypes = map[string]string{
+	"pdf":   "application/pdf",
+	"json":  "application/json", // the result bundle written alongside the PDF
+	"xccdf": "application/xml",
+	"arf":   "application/xml",
+}
+
+// generateScanReport writes the report of a stored scan in format and
+// returns its path.
+func generateScanReport(ctx context.Context, scan *state.Scan, format string) (string, error) {
+	results, err := scanAuditResults(scan.ID)
+	if err != nil {
+		return "", err
+	}
+	label := targetLabel()
+
+	if format == "xccdf" || format == "arf" {
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			return "", fmt.Errorf("failed to load policy")
+		}
+		pol.Rules = policy.FilterByProfile(pol.Rules, scan.Profile)
+		info := scanInfo(label, osLabel(), scan.Profile, scan.StartedAt)
+		info.Finished = scan.FinishedAt
+		if format == "arf" {
+			return report.GenerateARF(results, pol, info)
+		}
+		return report.GenerateXCCDF(results, pol, info)
+	}
+
+	filename, err := report.GenerateReport(results, label, engine.HistoryMachine(ctx, platform.GetPlatform()))
+	if err != nil {
+		return "", err
+	}
+	if format == "json" {
+		filename = strings.TrimSuffix(filename, ".pdf") + ".json"
+	}
+	return filename, nil
+}
+
+func registerScanRoutes(v1 *gin.RouterGroup) {
+	// Run a scan now; the results are stored under the new scan
+	v1.POST("/scans", func(c *gin.Context) {
+		var req struct {
+			Profile string `json:"profile"`
+		}
+		if err := bindOptionalJSON(c, &req); err != nil {
+			apiFail(c, 400, codeInvalidRequest, "Invalid request body", nil)
+			return
+		}
+		if req.Profile == "" {
+			req.Profile = cfg.DefaultProfile
+		}
+		if !validProfile(req.Profile) {
+			apiFail(c, 400, codeInvalidRequest, "profile must be strict, moderate or basic", nil)
+			return
+		}
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			apiFail(c, 500, codeInternal, "Failed to load policy", nil)
+			return
+		}
+		pol.Rules = policy.FilterByProfile(pol.Rules, req.Profile)
+
+		scan, _, err := runScan(c.Request.Context(), pol, req.Profile, webActor(c))
+		if err != nil {
+			apiFail(c, 500, codeInternal, "Failed to store scan: "+err.Error(), nil)
+			return
+		}
+		c.Header("Location", fmt.Sprintf("/api/v1/scans/%d", scan.ID))
+		respond(c, 201, scan)
+	})
+
+	v1.GET("/scans", func(c *gin.Context) {
+		page, perPage, err := pageQuery(c)
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, err.Error(), nil)
+			return
+		}
+		scans, total, err := state.ListScans(perPage, (page-1)*perPage)
+		if err != nil {
+			apiFail(c, 500, codeInternal, err.Error(), nil)
+			return
+		}
+		if scans == nil {
+			scans = []state.Scan{}
+		}
+		respondPage(c, scans, page, perPage, total)
+	})
+
+	v1.GET("/scans/:id", func(c *gin.Context) {
+		if scan := scanParam(c, c.Param("id")); scan != nil {
+			respond(c, 200, scan)
+		}
+	})
+
+	// Results of a scan: ?status=FAIL&severity=high
+	v1.GET("/scans/:id/results", func(c *gin.Context) {
+		page, perPage, err := pageQuery(c)
+		if err != nil {
+			apiFail(c, 400, codeInvalidRequest, err.Error(), nil)
+			return
+		}
+		scan := scanParam(c, c.Param("id"))
+		if scan == nil {
+			return
+		}
+		results, total, err := state.ListScanResults(state.ScanResultFilter{
+			ScanID:   scan.ID,
+			Status:   c.Query("status"),
+			Severity: c.Query("severity"),
+			Limit:    perPage,
+			Offset:   (page - 1) * perPage,
+		})
+		if err != nil {
+			apiFail(c, 500, codeInternal, err.Error(), nil)
+			return
+		}
+		if results == nil {
+			results = []state.ScanResult{}
+		}
+		respondPage(c, results, page, perPage, total)
+	})
+
+	// Report of a stored scan (?scan_id=, default the latest):
+	// pdf, json, xccdf or arf, and its detached signature
+	serveReport := func(signature bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			format := c.Param("format")
+			contentType, ok := reportTypes[format]
+			if !ok {
+				apiFail(c, 404, codeNotFound, "Unknown report format (pdf, json, xccdf or arf)", nil)
+				return
+			}
+			if signature && !signing.Enabled() {
+				apiFail(c, 404, codeNotFound, "Report signing is disabled", nil)
+				return
+			}
+			scan := scanParam(c, c.DefaultQuery("scan_id", "latest"))
+			if scan == nil {
+				return
+			}
+			filename, err := generateScanReport(c.Request.Context(), scan, format)
+			if err != nil {
+				apiFail(c, 500, codeInternal, "Failed to generate report: "+err.Error(), nil)
+				return
+			}
+			if signature {
+				filename += signing.SignatureExt
+				contentType = "application/json"
+			}
+			c.Header("Content-Type", contentType)
+			c.Header("Content-Disposition", "attachment; filename="+filepath.Base(filename))
+			c.File(filename)
+		}
+	}
+	v1.GET("/reports/:format", serveReport(false))
+	v1.GET("/reports/:format/signature", serveReport(true))
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/policy"
+	"sih2025/internal/report"
+	"sih2025/internal/scriptgen"
+	"sih2025/internal/signing"
+)
+
+// failingRules audits the profile's rules on this host and returns the ones
+// that fail (waived and not-applicable rules are left alone).
+func failingRules(pol *policy.Policy, profile string) []policy.Rule {
+	results := engine.RunAudit(pol)
+	results = engine.ApplyWaivers(results, thisHost(), profile)
+	failing := make(map[string]bool)
+	for _, r := range results {
+		if r.Status == "FAIL" || r.Status == "TIMEOUT" {
+			failing[r.ID] = true
+		}
+	}
+	var rules []policy.Rule
+	for _, r := range pol.Rules {
+		if failing[r.ID] {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// remediationZip builds the zip of remediation and rollback scripts for a
+// profile: its failing rules, or every rule when all is set.
+func remediationZip(pol *policy.Policy, profile, format string, all bool) ([]byte, string, int, error) {
+	pol.Rules = policy.FilterByProfile(pol.Rules, profile)
+	meta := scriptgen.Meta{Profile: profile, Generated: time.Now()}
+	rules := pol.Rules
+	if !all {
+		rules = failingRules(pol, profile)
+		meta.Host = thisHost()
+	}
+
+	files, err := scriptgen.Generate(rules, format, meta)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	dir := fmt.Sprintf("sentinelx-%s-%s-%s", format, profile, meta.Generated.Format("20060102-150405"))
+	var buf bytes.Buffer
+	if err := scriptgen.WriteZip(&buf, dir, files, meta.Generated); err != nil {
+		return nil, "", 0, err
+	}
+	return buf.Bytes(), dir + ".zip", len(rules), nil
+}
+
+// signRemediationZip keeps a zip served by the API under report.Dir with its
+// detached signature, when signing is enabled. Zips are built per request, so
+// the signature has to be fetched for the one downloaded, by its file name.
+func signRemediationZip(data []byte, name string) error {
+	if !signing.Enabled() {
+		return nil
+	}
+	path := filepath.Join(report.Dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	_, err := signing.SignFile(path)
+	return err
+}
+
+// remediationSignature returns the signature file of a zip signRemediationZip
+// kept, or "" when name isn't one.
+func remediationSignature(name string) string {
+	if name != filepath.Base(name) || !strings.HasPrefix(name, "sentinelx-") || !strings.HasSuffix(name, ".zip") {
+		return ""
+	}
+	path := filepath.Join(report.Dir, name+signing.SignatureExt)
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+func registerRemediationRoutes(api *gin.RouterGroup) {
+	// GET /api/remediation?level=strict&format=shell|ansible[&all=1]
+	api.GET("/remediation", func(c *gin.Context) {
+		profile := c.DefaultQuery("level", cfg.DefaultProfile)
+		pol := loadCurrentPolicy()
+		if pol == nil {
+			c.JSON(500, gin.H{"error": "Failed to load policy"})
+			return
+		}
+		data, name, count, err := remediationZip(pol, profile, c.DefaultQuery("format", scriptgen.FormatShell), c.Query("all") == "1")
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if err := signRemediationZip(data, name); err != nil {
+			c.JSON(500, gin.H{"error": "Failed to sign the zip: " + err.Error()})
+			return
+		}
+		slog.InfoContext(c.Request.Context(), "remediations exported", "rules", count, "file", name)
+		c.Header("Content-Disposition", "attachment; filename="+name)
+		c.Data(200, "application/zip", data)
+	})
+}
+
+// runExportFixes implements `sentinelx export-fixes`, for hosts where fixes
+// have to go through change control instead of the dashboard.
+func runExportFixes(args []string) int {
+	fs := flag.NewFlagSet("export-fixes", flag.ExitOnError)
+	policyPath := fs.String("policy", cfg.Policies.Linux, "policy file")
+	profile := fs.String("level", cfg.DefaultProfile, "hardening profile: strict, moderate or basic")
+	format := fs.String("format", scriptgen.FormatShell, "shell (POSIX sh scripts) or ansible (playbooks)")
+	all := fs.Bool("all", false, "export every rule in the profile instead of auditing this host for failures")
+	outPath := fs.String("out", "", "zip file to write (default: named after format, profile and time)")
+	sign := fs.Bool("sign", cfg.Signing.Enabled, "write a detached signature next to the zip (<out>.sig)")
+	keyDir := fs.String("key-dir", cfg.Signing.KeyDir, "directory holding the signing key pair")
+	fs.Parse(args)
+
+	pol, err := policy.LoadPolicy(*policyPath)
+	if err != nil {
+		slog.Error("failed to load policy", "err", err)
+		return 2
+	}
+	if !*all {
+		initDB() // waivers
+	}
+	if *sign {
+		initSigning(*keyDir)
+	}
+	data, name, count, err := remediationZip(pol, *profile, *format, *all)
+	if err != nil {
+		slog.Error(err.Error())
+		return 2
+	}
+	if *outPath == "" {
+		*outPath = name
+	}
+	if err := os.WriteFile(*outPath, data, 0644); err != nil {
+		slog.Error("failed to write output", "path", *outPath, "err", err)
+		return 1
+	}
+	fmt.Printf("[SUCCESS] %d rules exported to %s (remediation + rollback)\n", count, *outPath)
+	if signing.Enabled() {
+		sigPath, err := signing.SignFile(*outPath)
+		if err != nil {
+			slog.Error("failed to sign output", "path", *outPath, "err", err)
+			return 1
+		}
+		fmt.Printf("[SUCCESS] signature written to %s (key %s)\n", sigPath, signing.CurrentKeyID())
+	}
+	return 0
+}
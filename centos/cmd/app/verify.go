@@ -0,0 +1,51 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sih2025/internal/signing"
+)
+
+// runVerify implements `sentinelx verify <file>`. It works offline and only
+// needs the artifact, its .sig file and the tool's public key.
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	pubPath := fs.String("pubkey", filepath.Join("keys", signing.PublicKeyFile), "trusted Ed25519 public key (PEM)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: sentinelx verify [-pubkey key.pub] <file>")
+		fmt.Fprintln(os.Stderr, "exit status: 0 valid, 1 invalid, 3 intact but signer key not trusted")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	file := fs.Arg(0)
+
+	var trusted ed25519.PublicKey
+	if pub, err := signing.LoadPublicKey(*pubPath); err == nil {
+		trusted = pub
+	} else {
+		fmt.Printf("[WARN] No trusted public key at %s; checking integrity against the embedded key only.\n", *pubPath)
+	}
+
+	sig, err := signing.VerifyFile(file, trusted)
+	if err != nil {
+		fmt.Printf("[FAIL] %s: %v\n", file, err)
+		return 1
+	}
+
+	fmt.Printf("[OK] %s\n", file)
+	fmt.Printf("     SHA-256:   %s\n", sig.SHA256)
+	fmt.Printf("     Key ID:    %s\n", sig.KeyID)
+	fmt.Printf("     Signed at: %s\n", sig.SignedAt)
+	if trusted == nil {
+		return 3
+	}
+	return 0
+}
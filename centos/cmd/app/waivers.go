@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"sih2025/internal/state"
+)
+
+// waiverRequest is the body of POST /api/waivers.
+type waiverRequest struct {
+	RuleID        string `json:"rule_id"`
+	Scope         string `json:"scope"`
+	Target        string `json:"target"`
+	Justification string `json:"justification"`
+	Approver      string `json:"approver"`
+	ExpiresAt     string `json:"expires_at"` // RFC 3339 or YYYY-MM-DD
+}
+
+// toWaiver validates the request. A host-scoped waiver without a target
+// applies to this machine.
+func (req waiverRequest) toWaiver() (state.Waiver, error) {
+	w := state.Waiver{
+		RuleID:        strings.TrimSpace(req.RuleID),
+		Scope:         strings.ToLower(strings.TrimSpace(req.Scope)),
+		Target:        strings.TrimSpace(req.Target),
+		Justification: strings.TrimSpace(req.Justification),
+		Approver:      strings.TrimSpace(req.Approver),
+		CreatedAt:     time.Now(),
+	}
+	if w.Scope == "" {
+		w.Scope = state.WaiverScopeHost
+	}
+	if w.Scope == state.WaiverScopeHost && w.Target == "" {
+		w.Target, _ = os.Hostname()
+	}
+
+	switch {
+	case w.RuleID == "":
+		return w, fmt.Errorf("rule_id is required")
+	case w.Scope != state.WaiverScopeHost && w.Scope != state.WaiverScopeProfile:
+		return w, fmt.Errorf("scope must be %q or %q", state.WaiverScopeHost, state.WaiverScopeProfile)
+	case w.Target == "":
+		return w, fmt.Errorf("target is required for %s scope", w.Scope)
+	case w.Justification == "":
+		return w, fmt.Errorf("justification is required")
+	case w.Approver == "":
+		return w, fmt.Errorf("approver is required")
+	}
+
+	expires, err := parseExpiry(req.ExpiresAt)
+	if err != nil {
+		return w, err
+	}
+	if !expires.After(time.Now()) {
+		return w, fmt.Errorf("expires_at must be in the future")
+	}
+	w.ExpiresAt = expires
+	return w, nil
+}
+
+// parseExpiry accepts a timestamp or a date; a date expires at the end of that day.
+func parseExpiry(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	return time.Time{}, fmt.Errorf("expires_at must be RFC 3339 or YYYY-MM-DD, got %q", s)
+}
+
+// thisHost is the host name waivers are matched against for local scans.
+func thisHost() string {
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+func registerWaiverRoutes(api *gin.RouterGroup) {
+	api.GET("/waivers", func(c *gin.Context) {
+		list, err := state.ListWaivers()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		now := time.Now()
+		out := make([]gin.H, 0, len(list))
+		for _, w := range list {
+			out = append(out, gin.H{"waiver": w, "expired": w.Expired(now)})
+		}
+		c.JSON(200, gin.H{"waivers": out})
+	})
+
+	api.POST("/waivers", func(c *gin.Context) {
+		var req waiverRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": "Invalid request"})
+			return
+		}
+		w, err := req.toWaiver()
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		id, err := state.AddWaiver(w)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		w.ID = id
+		slog.InfoContext(c.Request.Context(), "waiver added", "rule", w.RuleID, "scope", w.Scope, "target", w.Target, "approver", w.Approver, "expires", w.ExpiresAt.Format(time.RFC3339))
+		c.JSON(201, gin.H{"status": "created", "waiver": w})
+	})
+
+	api.DELETE("/waivers/:id", func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "Invalid waiver id"})
+			return
+		}
+		found, err := state.DeleteWaiver(id)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		if !found {
+			c.JSON(404, gin.H{"error": "Waiver not found"})
+			return
+		}
+		c.JSON(200, gin.H{"status": "deleted", "id": id})
+	})
+}
+
+// runWaiver implements `sentinelx waiver add|list|rm`, for hosts without the
+// dashboard such as the fleet collector.
+func runWaiver(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: sentinelx waiver add|list|rm [flags]")
+		return 2
+	}
+	initDB()
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("waiver add", flag.ExitOnError)
+		var req waiverRequest
+		fs.StringVar(&req.RuleID, "rule", "", "rule ID to waive")
+		fs.StringVar(&req.Scope, "scope", state.WaiverScopeHost, "host or profile")
+		fs.StringVar(&req.Target, "target", "", "hostname or profile name (default: this host)")
+		fs.StringVar(&req.Justification, "justification", "", "why the control cannot be applied")
+		fs.StringVar(&req.Approver, "approver", "", "who accepted the risk")
+		fs.StringVar(&req.ExpiresAt, "expires", "", "expiry date (YYYY-MM-DD or RFC 3339)")
+		fs.Parse(args[1:])
+
+		w, err := req.toWaiver()
+		if err != nil {
+			slog.Error(err.Error())
+			return 2
+		}
+		id, err := state.AddWaiver(w)
+		if err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+		fmt.Printf("[SUCCESS] Waiver %d: %s for %s %s until %s\n", id, w.RuleID, w.Scope, w.Target, w.ExpiresAt.Format("2006-01-02 15:04"))
+		return 0
+
+	case "list":
+		list, err := state.ListWaivers()
+		if err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+		now := time.Now()
+		for _, w := range list {
+			mark := "active"
+			if w.Expired(now) {
+				mark = "EXPIRED"
+			}
+			fmt.Printf("%4d  %-20s %-8s %-20s %-16s %s  %-7s  %s\n", w.ID, w.RuleID, w.Scope, w.Target, w.Approver, w.ExpiresAt.Format("2006-01-02"), mark, w.Justification)
+		}
+		return 0
+
+	case "rm":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, "usage: sentinelx waiver rm <id>")
+			return 2
+		}
+		id, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			slog.Error("invalid waiver id", "id", args[1])
+			return 2
+		}
+		found, err := state.DeleteWaiver(id)
+		if err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+		if !found {
+			slog.Error("waiver not found", "id", id)
+			return 1
+		}
+		fmt.Printf("[SUCCESS] Waiver %d removed\n", id)
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "unknown waiver subcommand %q\n", args[0])
+	return 2
+}
@@ -0,0 +1,428 @@
+// Package confedit reads and edits key/value configuration files the way the
+// programs that use them do: which occurrence of a key is effective, sshd's
+// Match blocks and Include directives, drop-in directories, and keys that
+// are only present as comments.
+package confedit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// FS is the file access confedit needs; platform hardeners provide it.
+type FS interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, data []byte, mode os.FileMode) error
+	Glob(pattern string) ([]string, error)
+}
+
+// File is one file of a configuration.
+type File struct {
+	Path   string
+	Lines  []string
+	exists bool
+	dirty  bool
+}
+
+// Entry is one occurrence of a key.
+type Entry struct {
+	File      *File
+	Line      int
+	Key       string // normalized
+	Value     string
+	Commented bool   // "#PermitRootLogin yes"
+	Section   string // sshd: the Match criteria, "" for the global section
+	prefix    string
+}
+
+// Config is a parsed configuration: the main file plus everything it pulls in.
+type Config struct {
+	d       *dialect
+	fs      FS
+	main    *File
+	files   []*File
+	entries []*Entry // in the order the program reads them
+}
+
+// Formats lists the supported formats.
+func Formats() []string {
+	names := make([]string, 0, len(dialects))
+	for name := range dialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultFile is the file a format is read from (and new keys written to)
+// when a rule doesn't name one.
+func DefaultFile(format string) string {
+	if d, ok := dialects[format]; ok {
+		return d.mainFile
+	}
+	return ""
+}
+
+// Load reads a configuration. mainFile overrides the format's default; a
+// missing file is treated as empty and created when written.
+func Load(fs FS, format, mainFile string) (*Config, error) {
+	d, ok := dialects[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown config format %q (supported: %s)", format, strings.Join(Formats(), ", "))
+	}
+	if mainFile == "" {
+		mainFile = d.mainFile
+	}
+	c := &Config{d: d, fs: fs}
+
+	seen := make(map[string]bool)
+	byName := make(map[string]string)
+	var names []string
+	inDropIns := false
+	for _, pattern := range d.dropIns {
+		if ok, _ := path.Match(pattern, path.Clean(mainFile)); ok {
+			inDropIns = true
+		}
+	}
+	if inDropIns {
+		// Read in its place among the drop-ins, even before it exists
+		byName[path.Base(mainFile)] = path.Clean(mainFile)
+		names = append(names, path.Base(mainFile))
+	} else if _, err := c.read(mainFile, "", 0, seen); err != nil {
+		return nil, err
+	}
+
+	// Drop-ins: the first directory to have a name wins, then name order
+	for _, pattern := range d.dropIns {
+		matches, err := fs.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if base := path.Base(m); byName[base] == "" {
+				byName[base] = m
+				names = append(names, base)
+			}
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := c.read(byName[name], "", 0, seen); err != nil {
+			return nil, err
+		}
+	}
+	if d.tailFile != "" {
+		if _, err := c.read(d.tailFile, "", 0, seen); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range c.files {
+		if f.Path == path.Clean(mainFile) {
+			c.main = f
+		}
+	}
+	return c, nil
+}
+
+// read parses one file (once), following sshd Include directives in place.
+func (c *Config) read(p, section string, depth int, seen map[string]bool) (*File, error) {
+	p = path.Clean(p)
+	if seen[p] {
+		return nil, nil
+	}
+	seen[p] = true
+	if depth > 16 {
+		return nil, fmt.Errorf("%s: too many nested Include directives", p)
+	}
+
+	f := &File{Path: p}
+	data, err := c.fs.ReadFile(p)
+	switch {
+	case err == nil:
+		f.exists = true
+		text := strings.ReplaceAll(string(data), "\r\n", "\n")
+		f.Lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+		if text == "" {
+			f.Lines = nil
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return nil, err
+	}
+	c.files = append(c.files, f)
+
+	for i, line := range f.Lines {
+		trimmed := strings.TrimSpace(line)
+		commented := strings.HasPrefix(trimmed, "#")
+		if commented {
+			trimmed = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			line = trimmed
+		}
+		if trimmed == "" {
+			continue
+		}
+
+		if c.d == dialects[SSHD] && !commented {
+			keyword, args := splitKeyword(trimmed)
+			switch keyword {
+			case "match":
+				// A Match block runs to the next Match or the end of the file
+				section = matchSection(args)
+				continue
+			case "include":
+				if err := c.include(args, section, depth, seen); err != nil {
+					return nil, fmt.Errorf("%s:%d: %v", p, i+1, err)
+				}
+				continue
+			}
+		}
+
+		key, prefix, value, ok := c.d.parse(line)
+		if !ok {
+			continue
+		}
+		c.entries = append(c.entries, &Entry{File: f, Line: i, Key: key, Value: value, Commented: commented, Section: section, prefix: prefix})
+	}
+	return f, nil
+}
+
+// include reads the files of an sshd Include line; relative paths are
+// relative to /etc/ssh.
+func (c *Config) include(args, section string, depth int, seen map[string]bool) error {
+	for _, pattern := range strings.Fields(args) {
+		if !path.IsAbs(pattern) {
+			pattern = path.Join("/etc/ssh", pattern)
+		}
+		matches, err := c.fs.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			if _, err := c.read(m, section, depth+1, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitKeyword splits an sshd line into its lower-cased keyword and arguments.
+func splitKeyword(line string) (keyword, args string) {
+	line = strings.TrimSpace(line)
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return strings.ToLower(line), ""
+	}
+	return strings.ToLower(line[:i]), strings.TrimLeft(line[i:], " \t=")
+}
+
+// matchSection normalizes Match criteria; "Match all" ends a Match block.
+func matchSection(criteria string) string {
+	criteria = strings.Join(strings.Fields(criteria), " ")
+	if strings.EqualFold(criteria, "all") {
+		return ""
+	}
+	return criteria
+}
+
+// find returns the active and commented entries for key in section.
+func (c *Config) find(key, section string) (active, commented []*Entry) {
+	key, section = c.d.normKey(key), matchSection(section)
+	for _, e := range c.entries {
+		if e.Key != key || !strings.EqualFold(e.Section, section) {
+			continue
+		}
+		if e.Commented {
+			commented = append(commented, e)
+		} else {
+			active = append(active, e)
+		}
+	}
+	return active, commented
+}
+
+// Get returns the effective value of key ("" section: outside Match blocks).
+func (c *Config) Get(key, section string) (value string, where *Entry, ok bool) {
+	active, _ := c.find(key, section)
+	if len(active) == 0 {
+		return "", nil, false
+	}
+	e := active[len(active)-1]
+	if c.d.firstWins {
+		e = active[0]
+	}
+	return e.Value, e, true
+}
+
+// Set makes value the effective value of key: every active occurrence in an
+// editable file is rewritten in place; otherwise the entry goes after a
+// commented-out occurrence, or at the end of the section (for sshd's global
+// section, before the first Match block). Entries in vendor files (/usr,
+// /lib, /run) are overridden, not edited.
+func (c *Config) Set(key, section, value string) error {
+	active, commented := c.find(key, section)
+	overridden := false
+	for _, e := range active {
+		if editable(e.File.Path) {
+			c.rewrite(e, value)
+		} else {
+			overridden = true
+		}
+	}
+	if len(active) > 0 && !overridden {
+		return nil
+	}
+	if overridden && c.d.firstWins {
+		// Anything added after it would lose to the vendor entry
+		return fmt.Errorf("%s is set in %s, which takes precedence and is not edited", key, active[0].File.Path)
+	}
+
+	line := c.d.format(key, value)
+	for _, e := range commented {
+		if editable(e.File.Path) && !overridden {
+			c.insert(e.File, e.Line+1, line)
+			return nil
+		}
+	}
+	c.appendTo(matchSection(section), line)
+	return nil
+}
+
+// Unset comments out every active occurrence of key in editable files.
+func (c *Config) Unset(key, section string) {
+	active, _ := c.find(key, section)
+	for _, e := range active {
+		if editable(e.File.Path) {
+			e.File.Lines[e.Line] = "# " + strings.TrimSpace(e.File.Lines[e.Line])
+			e.File.dirty = true
+			e.Commented = true
+		}
+	}
+}
+
+func (c *Config) rewrite(e *Entry, value string) {
+	if c.d.keyFields == 0 || e.Value == value {
+		return
+	}
+	// "key=1" keeps its own separator; "key" alone needs one
+	prefix := e.prefix
+	sep := strings.TrimSpace(c.d.sep)
+	if value != "" && strings.TrimRight(prefix, " \t") == prefix && (sep == "" || !strings.HasSuffix(prefix, sep)) {
+		prefix += c.d.sep
+	}
+	e.File.Lines[e.Line] = prefix + value
+	e.File.dirty = true
+	e.Value = value
+}
+
+func (c *Config) insert(f *File, at int, line string) {
+	f.Lines = append(f.Lines[:at], append([]string{line}, f.Lines[at:]...)...)
+	f.dirty = true
+	for _, e := range c.entries {
+		if e.File == f && e.Line >= at {
+			e.Line++
+		}
+	}
+}
+
+// appendTo adds an entry at the end of a section of the main file.
+func (c *Config) appendTo(section, line string) {
+	f := c.main
+	if c.d != dialects[SSHD] {
+		c.insert(f, len(f.Lines), line)
+		return
+	}
+
+	// Find where the section ends in the main file
+	current, start, end := "", -1, -1
+	firstMatch := -1
+	for i, l := range f.Lines {
+		keyword, args := splitKeyword(l)
+		if keyword != "match" {
+			continue
+		}
+		if firstMatch < 0 {
+			firstMatch = i
+		}
+		if current != "" && strings.EqualFold(current, section) && end < 0 {
+			end = i
+		}
+		current = matchSection(args)
+		if section != "" && strings.EqualFold(current, section) && start < 0 {
+			start = i
+		}
+	}
+
+	switch {
+	case section == "":
+		if firstMatch < 0 {
+			c.insert(f, len(f.Lines), line)
+			return
+		}
+		// Before the first Match block (and the comments and blank lines
+		// that introduce it)
+		at := firstMatch
+		for at > 0 && (strings.TrimSpace(f.Lines[at-1]) == "" || strings.HasPrefix(strings.TrimSpace(f.Lines[at-1]), "#")) {
+			at--
+		}
+		c.insert(f, at, line)
+	case start >= 0:
+		if end < 0 {
+			end = len(f.Lines)
+		}
+		for end > start+1 && strings.TrimSpace(f.Lines[end-1]) == "" {
+			end--
+		}
+		c.insert(f, end, "    "+line)
+	default:
+		if n := len(f.Lines); n > 0 && strings.TrimSpace(f.Lines[n-1]) != "" {
+			c.insert(f, n, "")
+		}
+		c.insert(f, len(f.Lines), "Match "+section)
+		c.insert(f, len(f.Lines), "    "+line)
+	}
+}
+
+// Pending lists the files Save would write.
+func (c *Config) Pending() []string {
+	var paths []string
+	for _, f := range c.files {
+		if f.dirty {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
+
+// Save writes the changed files, creating missing ones with the format's mode.
+// It returns the paths written.
+func (c *Config) Save() ([]string, error) {
+	var written []string
+	for _, f := range c.files {
+		if !f.dirty {
+			continue
+		}
+		data := strings.Join(f.Lines, "\n") + "\n"
+		if err := c.fs.WriteFile(f.Path, []byte(data), c.d.newMode); err != nil {
+			return written, fmt.Errorf("write %s: %v", f.Path, err)
+		}
+		f.dirty, f.exists = false, true
+		written = append(written, f.Path)
+	}
+	return written, nil
+}
+
+// Files lists the files that were read, in order.
+func (c *Config) Files() []string {
+	var paths []string
+	for _, f := range c.files {
+		if f.exists {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,386 @@
+package confedit
+
+import (
+	"os"
+	"path"
+	"sort"
+	"testing"
+)
+
+// memFS is an in-memory FS keyed by absolute path.
+type memFS struct {
+	files map[string]string
+	modes map[string]os.FileMode
+}
+
+func newMemFS(files map[string]string) *memFS {
+	fs := &memFS{files: make(map[string]string), modes: make(map[string]os.FileMode)}
+	for p, data := range files {
+		fs.files[p] = data
+	}
+	return fs
+}
+
+func (m *memFS) ReadFile(p string) ([]byte, error) {
+	data, ok := m.files[p]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+	}
+	return []byte(data), nil
+}
+
+func (m *memFS) WriteFile(p string, data []byte, mode os.FileMode) error {
+	if _, ok := m.files[p]; !ok {
+		m.modes[p] = mode
+	}
+	m.files[p] = string(data)
+	return nil
+}
+
+func (m *memFS) Glob(pattern string) ([]string, error) {
+	var matches []string
+	for p := range m.files {
+		if ok, err := path.Match(pattern, p); err != nil {
+			return nil, err
+		} else if ok {
+			matches = append(matches, p)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		files   map[string]string
+		key     string
+		section string
+		want    string
+		ok      bool
+	}{
+		{
+			name:   "sshd first occurrence wins",
+			format: SSHD,
+			files:  map[string]string{"/etc/ssh/sshd_config": "PermitRootLogin yes\nPermitRootLogin no\n"},
+			key:    "PermitRootLogin", want: "yes", ok: true,
+		},
+		{
+			name:   "sshd keywords ignore case and take =",
+			format: SSHD,
+			files:  map[string]string{"/etc/ssh/sshd_config": "permitrootlogin=no\n"},
+			key:    "PermitRootLogin", want: "no", ok: true,
+		},
+		{
+			name:   "sshd commented entry is not effective",
+			format: SSHD,
+			files:  map[string]string{"/etc/ssh/sshd_config": "#PermitRootLogin no\n"},
+			key:    "PermitRootLogin",
+		},
+		{
+			name:   "sshd Include is read in place",
+			format: SSHD,
+			files: map[string]string{
+				"/etc/ssh/sshd_config":             "Include /etc/ssh/sshd_config.d/*.conf\nPermitRootLogin yes\n",
+				"/etc/ssh/sshd_config.d/50-a.conf": "PermitRootLogin no\n",
+			},
+			key: "PermitRootLogin", want: "no", ok: true,
+		},
+		{
+			name:   "sshd relative Include is under /etc/ssh",
+			format: SSHD,
+			files: map[string]string{
+				"/etc/ssh/sshd_config":             "PermitRootLogin yes\nInclude sshd_config.d/*.conf\n",
+				"/etc/ssh/sshd_config.d/50-a.conf": "X11Forwarding no\n",
+			},
+			key: "X11Forwarding", want: "no", ok: true,
+		},
+		{
+			name:   "sshd global section skips Match blocks",
+			format: SSHD,
+			files:  map[string]string{"/etc/ssh/sshd_config": "Match User backup\n    PermitRootLogin yes\n"},
+			key:    "PermitRootLogin",
+		},
+		{
+			name:    "sshd Match section",
+			format:  SSHD,
+			files:   map[string]string{"/etc/ssh/sshd_config": "PermitRootLogin no\nMatch User backup\n    PermitRootLogin yes\n"},
+			key:     "PermitRootLogin",
+			section: "user  backup", want: "yes", ok: true,
+		},
+		{
+			name:   "sshd Match all ends the block",
+			format: SSHD,
+			files:  map[string]string{"/etc/ssh/sshd_config": "Match User backup\n    X11Forwarding yes\nMatch all\nPermitRootLogin no\n"},
+			key:    "PermitRootLogin", want: "no", ok: true,
+		},
+		{
+			name:   "sshd Match inherited by included files",
+			format: SSHD,
+			files: map[string]string{
+				"/etc/ssh/sshd_config":             "Match User backup\nInclude /etc/ssh/sshd_config.d/*.conf\n",
+				"/etc/ssh/sshd_config.d/50-a.conf": "PermitRootLogin yes\n",
+			},
+			key: "PermitRootLogin",
+		},
+		{
+			name:   "login.defs last occurrence wins",
+			format: LoginDefs,
+			files:  map[string]string{"/etc/login.defs": "PASS_MAX_DAYS\t99999\nPASS_MAX_DAYS 90\n"},
+			key:    "PASS_MAX_DAYS", want: "90", ok: true,
+		},
+		{
+			name:   "login.defs keys are case-sensitive",
+			format: LoginDefs,
+			files:  map[string]string{"/etc/login.defs": "pass_max_days 90\n"},
+			key:    "PASS_MAX_DAYS",
+		},
+		{
+			name:   "sysctl later drop-in name wins across directories",
+			format: Sysctl,
+			files: map[string]string{
+				"/etc/sysctl.d/10-a.conf":     "net.ipv4.ip_forward = 0\n",
+				"/usr/lib/sysctl.d/90-z.conf": "net.ipv4.ip_forward = 1\n",
+			},
+			key: "net.ipv4.ip_forward", want: "1", ok: true,
+		},
+		{
+			name:   "sysctl /etc hides a vendor file of the same name",
+			format: Sysctl,
+			files: map[string]string{
+				"/etc/sysctl.d/50-default.conf":     "kernel.sysrq = 0\n",
+				"/usr/lib/sysctl.d/50-default.conf": "kernel.sysrq = 16\n",
+			},
+			key: "kernel.sysrq", want: "0", ok: true,
+		},
+		{
+			name:   "sysctl.conf is read last",
+			format: Sysctl,
+			files: map[string]string{
+				"/etc/sysctl.d/99-sentinelx.conf": "net.ipv4.ip_forward = 0\n",
+				"/etc/sysctl.conf":                "net.ipv4.ip_forward=1\n",
+			},
+			key: "net.ipv4.ip_forward", want: "1", ok: true,
+		},
+		{
+			name:   "sysctl slash keys and ignore-error prefix",
+			format: Sysctl,
+			files:  map[string]string{"/etc/sysctl.conf": "-net/ipv4/ip_forward = 0\n"},
+			key:    "net.ipv4.ip_forward", want: "0", ok: true,
+		},
+		{
+			name:   "modprobe first occurrence wins",
+			format: Modprobe,
+			files: map[string]string{
+				"/etc/modprobe.d/a.conf": "install cramfs /bin/true\n",
+				"/etc/modprobe.d/b.conf": "install cramfs /bin/false\n",
+			},
+			key: "install  cramfs", want: "/bin/true", ok: true,
+		},
+		{
+			name:   "limits three-field key",
+			format: Limits,
+			files: map[string]string{
+				"/etc/security/limits.conf":        "*\thard\tcore\tunlimited\n",
+				"/etc/security/limits.d/10-a.conf": "* hard core 0\n",
+			},
+			key: "* hard core", want: "0", ok: true,
+		},
+		{
+			name:   "audit rule is the whole key",
+			format: Audit,
+			files:  map[string]string{"/etc/audit/rules.d/10-a.rules": "-w /etc/passwd  -p wa -k identity\n"},
+			key:    "-w /etc/passwd -p wa -k identity", want: "", ok: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Load(newMemFS(tt.files), tt.format, "")
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			got, _, ok := c.Get(tt.key, tt.section)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("Get(%q, %q) = %q, %v; want %q, %v", tt.key, tt.section, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		files   map[string]string
+		key     string
+		section string
+		value   string
+		want    map[string]string // file contents after Save
+		wantErr bool
+	}{
+		{
+			name:   "sshd rewrites in place",
+			format: SSHD,
+			files:  map[string]string{"/etc/ssh/sshd_config": "Port 22\nPermitRootLogin yes\n"},
+			key:    "PermitRootLogin", value: "no",
+			want: map[string]string{"/etc/ssh/sshd_config": "Port 22\nPermitRootLogin no\n"},
+		},
+		{
+			name:   "sshd goes under the commented default",
+			format: SSHD,
+			files:  map[string]string{"/etc/ssh/sshd_config": "#PermitRootLogin prohibit-password\nPort 22\n"},
+			key:    "PermitRootLogin", value: "no",
+			want: map[string]string{"/etc/ssh/sshd_config": "#PermitRootLogin prohibit-password\nPermitRootLogin no\nPort 22\n"},
+		},
+		{
+			name:   "sshd global key goes before the first Match block",
+			format: SSHD,
+			files:  map[string]string{"/etc/ssh/sshd_config": "Port 22\n\n# backups\nMatch User backup\n    X11Forwarding no\n"},
+			key:    "PermitRootLogin", value: "no",
+			want: map[string]string{"/etc/ssh/sshd_config": "Port 22\nPermitRootLogin no\n\n# backups\nMatch User backup\n    X11Forwarding no\n"},
+		},
+		{
+			name:    "sshd key goes at the end of its Match block",
+			format:  SSHD,
+			files:   map[string]string{"/etc/ssh/sshd_config": "Match User backup\n    X11Forwarding no\n\nMatch User other\n    X11Forwarding yes\n"},
+			key:     "PermitRootLogin",
+			section: "User backup", value: "no",
+			want: map[string]string{"/etc/ssh/sshd_config": "Match User backup\n    X11Forwarding no\n    PermitRootLogin no\n\nMatch User other\n    X11Forwarding yes\n"},
+		},
+		{
+			name:    "sshd adds a missing Match block",
+			format:  SSHD,
+			files:   map[string]string{"/etc/ssh/sshd_config": "Port 22\n"},
+			key:     "PermitRootLogin",
+			section: "User backup", value: "no",
+			want: map[string]string{"/etc/ssh/sshd_config": "Port 22\n\nMatch User backup\n    PermitRootLogin no\n"},
+		},
+		{
+			name:   "sshd rewrites every occurrence, included files too",
+			format: SSHD,
+			files: map[string]string{
+				"/etc/ssh/sshd_config":             "Include /etc/ssh/sshd_config.d/*.conf\nPermitRootLogin yes\n",
+				"/etc/ssh/sshd_config.d/50-a.conf": "PermitRootLogin yes\n",
+			},
+			key: "PermitRootLogin", value: "no",
+			want: map[string]string{
+				"/etc/ssh/sshd_config":             "Include /etc/ssh/sshd_config.d/*.conf\nPermitRootLogin no\n",
+				"/etc/ssh/sshd_config.d/50-a.conf": "PermitRootLogin no\n",
+			},
+		},
+		{
+			name:   "sysctl keeps the line's own separator",
+			format: Sysctl,
+			files:  map[string]string{"/etc/sysctl.conf": "kernel.sysrq=16\n"},
+			key:    "kernel.sysrq", value: "0",
+			want: map[string]string{"/etc/sysctl.conf": "kernel.sysrq=0\n"},
+		},
+		{
+			name:   "sysctl overrides a vendor default from its own file",
+			format: Sysctl,
+			files:  map[string]string{"/usr/lib/sysctl.d/50-default.conf": "kernel.sysrq = 16\n"},
+			key:    "kernel.sysrq", value: "0",
+			want: map[string]string{
+				"/usr/lib/sysctl.d/50-default.conf": "kernel.sysrq = 16\n",
+				"/etc/sysctl.d/99-sentinelx.conf":   "kernel.sysrq = 0\n",
+			},
+		},
+		{
+			name:   "modprobe can't override a first-wins vendor entry",
+			format: Modprobe,
+			files:  map[string]string{"/usr/lib/modprobe.d/10-a.conf": "install cramfs /bin/false\n"},
+			key:    "install cramfs", value: "/bin/true",
+			wantErr: true,
+		},
+		{
+			name:   "login.defs appends with a tab",
+			format: LoginDefs,
+			files:  map[string]string{"/etc/login.defs": "UMASK 022\n"},
+			key:    "PASS_MAX_DAYS", value: "90",
+			want: map[string]string{"/etc/login.defs": "UMASK 022\nPASS_MAX_DAYS\t90\n"},
+		},
+		{
+			name:   "audit adds the rule once",
+			format: Audit,
+			files:  map[string]string{"/etc/audit/rules.d/60-sentinelx.rules": "-w /etc/passwd -p wa -k identity\n"},
+			key:    "-w /etc/passwd -p wa -k identity",
+			want:   map[string]string{"/etc/audit/rules.d/60-sentinelx.rules": "-w /etc/passwd -p wa -k identity\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newMemFS(tt.files)
+			c, err := Load(fs, tt.format, "")
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			err = c.Set(tt.key, tt.section, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Set succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			if _, err := c.Save(); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			for p, want := range tt.want {
+				if got := fs.files[p]; got != want {
+					t.Errorf("%s = %q, want %q", p, got, want)
+				}
+			}
+
+			// The new value is what a fresh read sees
+			reloaded, err := Load(fs, tt.format, "")
+			if err != nil {
+				t.Fatalf("reload: %v", err)
+			}
+			if got, _, ok := reloaded.Get(tt.key, tt.section); !ok || got != tt.value {
+				t.Errorf("after Set, Get = %q, %v; want %q", got, ok, tt.value)
+			}
+		})
+	}
+}
+
+func TestUnset(t *testing.T) {
+	fs := newMemFS(map[string]string{"/etc/ssh/sshd_config": "PermitRootLogin yes\nMatch User backup\n    PermitRootLogin yes\n"})
+	c, err := Load(fs, SSHD, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Unset("PermitRootLogin", "")
+	if _, err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	want := "# PermitRootLogin yes\nMatch User backup\n    PermitRootLogin yes\n"
+	if got := fs.files["/etc/ssh/sshd_config"]; got != want {
+		t.Errorf("sshd_config = %q, want %q", got, want)
+	}
+}
+
+func TestSaveCreatesWithFormatMode(t *testing.T) {
+	fs := newMemFS(nil)
+	c, err := Load(fs, SSHD, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Set("PermitRootLogin", "", "no"); err != nil {
+		t.Fatal(err)
+	}
+	written, err := c.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 1 || written[0] != "/etc/ssh/sshd_config" {
+		t.Fatalf("written = %v", written)
+	}
+	if mode := fs.modes["/etc/ssh/sshd_config"]; mode != 0600 {
+		t.Errorf("mode = %o, want 600", mode)
+	}
+}
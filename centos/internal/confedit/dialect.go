@@ -0,0 +1,125 @@
+package confedit
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Formats
+const (
+	SSHD      = "sshd"       // sshd_config, with Match blocks and Include
+	LoginDefs = "login_defs" // /etc/login.defs
+	Sysctl    = "sysctl"     // sysctl.conf and sysctl.d
+	Limits    = "limits"     // limits.conf and limits.d, key "<domain> <type> <item>"
+	Modprobe  = "modprobe"   // modprobe.d, key "<command> <module>" (e.g. "install cramfs")
+	Audit     = "audit"      // audit rules.d, the whole rule is the key
+)
+
+// dialect describes one file format.
+type dialect struct {
+	// mainFile is read first (and written when nothing else fits)
+	mainFile string
+	// dropIns are read after mainFile: a file name in an earlier directory
+	// hides the same name in later ones, the rest go in name order
+	dropIns []string
+	// tailFile is read last (sysctl.conf comes after sysctl.d)
+	tailFile string
+
+	// firstWins is set when the first occurrence of a key is the effective
+	// one; otherwise the last one is
+	firstWins bool
+	// line matches an entry: group 1 is everything before the value, group
+	// 2 the key (its first field for multi-field keys), group 3 the value
+	line      *regexp.Regexp
+	keyFields int    // fields that make up the key (0: the whole line)
+	sep       string // between key and value in new lines
+	foldCase  bool   // keys are case-insensitive
+	newMode   os.FileMode
+}
+
+var dialects = map[string]*dialect{
+	SSHD: {
+		mainFile:  "/etc/ssh/sshd_config",
+		firstWins: true,
+		line:      regexp.MustCompile(`^(\s*(\S+?)(?:\s*=\s*|\s+))(.*?)\s*$`),
+		keyFields: 1, sep: " ", foldCase: true, newMode: 0600,
+	},
+	LoginDefs: {
+		mainFile:  "/etc/login.defs",
+		line:      regexp.MustCompile(`^(\s*(\S+)\s+)(.*?)\s*$`),
+		keyFields: 1, sep: "\t", newMode: 0644,
+	},
+	Sysctl: {
+		mainFile:  "/etc/sysctl.d/99-sentinelx.conf",
+		dropIns:   []string{"/etc/sysctl.d/*.conf", "/run/sysctl.d/*.conf", "/usr/local/lib/sysctl.d/*.conf", "/usr/lib/sysctl.d/*.conf", "/lib/sysctl.d/*.conf"},
+		tailFile:  "/etc/sysctl.conf",
+		line:      regexp.MustCompile(`^(\s*-?\s*([^=\s]+)\s*=\s*)(.*?)\s*$`),
+		keyFields: 1, sep: " = ", newMode: 0644,
+	},
+	Limits: {
+		mainFile:  "/etc/security/limits.conf",
+		dropIns:   []string{"/etc/security/limits.d/*.conf"},
+		line:      regexp.MustCompile(`^(\s*((?:\S+\s+){2}\S+)\s+)(\S+)\s*$`),
+		keyFields: 3, sep: " ", newMode: 0644,
+	},
+	Modprobe: {
+		mainFile:  "/etc/modprobe.d/60-sentinelx.conf",
+		dropIns:   []string{"/etc/modprobe.d/*.conf", "/run/modprobe.d/*.conf", "/usr/local/lib/modprobe.d/*.conf", "/usr/lib/modprobe.d/*.conf", "/lib/modprobe.d/*.conf"},
+		firstWins: true,
+		line:      regexp.MustCompile(`^(\s*(\S+\s+\S+)(?:\s+|$))(.*?)\s*$`),
+		keyFields: 2, sep: " ", newMode: 0644,
+	},
+	Audit: {
+		mainFile: "/etc/audit/rules.d/60-sentinelx.rules",
+		dropIns:  []string{"/etc/audit/rules.d/*.rules"},
+		line:     regexp.MustCompile(`^(\s*(-.*?))()\s*$`),
+		sep:      "", newMode: 0640,
+	},
+}
+
+// parse splits a (non-comment) line into its normalized key, the text before
+// the value and the value.
+func (d *dialect) parse(line string) (key, prefix, value string, ok bool) {
+	m := d.line.FindStringSubmatch(line)
+	if m == nil {
+		return "", "", "", false
+	}
+	return d.normKey(m[2]), m[1], m[3], true
+}
+
+// normKey is the form keys are compared in.
+func (d *dialect) normKey(key string) string {
+	key = strings.Join(strings.Fields(key), " ")
+	if d == dialects[Sysctl] {
+		key = strings.ReplaceAll(strings.TrimPrefix(key, "-"), "/", ".")
+	}
+	if d.foldCase {
+		key = strings.ToLower(key)
+	}
+	return key
+}
+
+// format writes a new entry.
+func (d *dialect) format(key, value string) string {
+	if d.keyFields == 0 {
+		return strings.Join(strings.Fields(key), " ")
+	}
+	if value == "" {
+		return key
+	}
+	return key + d.sep + value
+}
+
+// editable reports whether files at p may be changed: vendor defaults under
+// /usr, /lib and /run are overridden, never edited.
+func editable(p string) bool {
+	p = path.Clean(p)
+	for _, dir := range []string{"/usr/", "/lib/", "/run/"} {
+		if strings.HasPrefix(p, dir) {
+			return false
+		}
+	}
+	return true
+}
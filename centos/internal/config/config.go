@@ -0,0 +1,341 @@
+// Package config holds the orchestrator's settings: built-in defaults,
+// overridden by a YAML or TOML file, then SENTINELX_* environment variables,
+// then command-line flags.
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"sih2025/internal/logging"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Redacted replaces secrets in the output of /api/config.
+const Redacted = "[REDACTED]"
+
+// Config is the effective configuration. Relative paths are resolved against
+// BaseDir once loading is done.
+type Config struct {
+	Listen         string   `yaml:"listen" toml:"listen" json:"listen"`
+	BaseDir        string   `yaml:"base_dir" toml:"base_dir" json:"base_dir"`
+	DBPath         string   `yaml:"db_path" toml:"db_path" json:"db_path"`
+	TemplatesDir   string   `yaml:"templates_dir" toml:"templates_dir" json:"templates_dir"`
+	StaticDir      string   `yaml:"static_dir" toml:"static_dir" json:"static_dir"`
+	ReportDir      string   `yaml:"report_dir" toml:"report_dir" json:"report_dir"`
+	DefaultProfile string   `yaml:"default_profile" toml:"default_profile" json:"default_profile"`
+	Policies       Policies `yaml:"policies" toml:"policies" json:"policies"`
+	Timeouts       Timeouts `yaml:"timeouts" toml:"timeouts" json:"timeouts"`
+	Signing        Signing  `yaml:"signing" toml:"signing" json:"signing"`
+	Fleet          Fleet    `yaml:"fleet" toml:"fleet" json:"fleet"`
+	Log            Log      `yaml:"log" toml:"log" json:"log"`
+
+	// Source is the file the settings were read from, if any
+	Source string `yaml:"-" toml:"-" json:"source,omitempty"`
+}
+
+// Policies are the policy files used per platform.
+type Policies struct {
+	Linux   string `yaml:"linux" toml:"linux" json:"linux"`
+	Windows string `yaml:"windows" toml:"windows" json:"windows"`
+}
+
+// Timeouts bound single checks and SSH connections.
+type Timeouts struct {
+	Check Duration `yaml:"check" toml:"check" json:"check"`
+	SSH   Duration `yaml:"ssh" toml:"ssh" json:"ssh"`
+}
+
+// Signing controls signed report exports.
+type Signing struct {
+	Enabled bool   `yaml:"enabled" toml:"enabled" json:"enabled"`
+	KeyDir  string `yaml:"key_dir" toml:"key_dir" json:"key_dir"`
+}
+
+// Fleet holds the shared token agents present to the collector, and the one
+// for reading the fleet dashboard and its API (the agent token when unset).
+type Fleet struct {
+	Token       string `yaml:"token" toml:"token" json:"token"`                      // secret
+	ViewerToken string `yaml:"viewer_token" toml:"viewer_token" json:"viewer_token"` // secret
+}
+
+// Log controls the process log; see internal/logging.
+type Log struct {
+	Level     string `yaml:"level" toml:"level" json:"level"`    // debug, info, warn or error
+	Format    string `yaml:"format" toml:"format" json:"format"` // text or json
+	Output    string `yaml:"output" toml:"output" json:"output"` // stderr, stdout, file or journald
+	File      string `yaml:"file" toml:"file" json:"file"`
+	MaxSizeMB int    `yaml:"max_size_mb" toml:"max_size_mb" json:"max_size_mb"`
+	MaxFiles  int    `yaml:"max_files" toml:"max_files" json:"max_files"`
+}
+
+// Duration reads "5s", "2m" and the like from YAML, TOML and env vars.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(strings.TrimSpace(string(text)))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Default returns the built-in settings, which match the repository layout.
+func Default() *Config {
+	return &Config{
+		Listen:         ":8080",
+		DBPath:         "hardening.db",
+		TemplatesDir:   "ui/templates",
+		StaticDir:      "ui/static",
+		ReportDir:      ".",
+		DefaultProfile: "strict",
+		Policies: Policies{
+			Linux:   "policies/annexure_b.json",
+			Windows: "policies/annexure_a.json",
+		},
+		Timeouts: Timeouts{
+			Check: Duration(5 * time.Second),
+			SSH:   Duration(15 * time.Second),
+		},
+		Signing: Signing{KeyDir: "keys"},
+		Log: Log{
+			Level:     "info",
+			Format:    "text",
+			Output:    "stderr",
+			File:      "sentinelx.log",
+			MaxSizeMB: 10,
+			MaxFiles:  5,
+		},
+	}
+}
+
+// DefaultPaths are where the config file is looked for when none is named.
+func DefaultPaths() []string {
+	dir := "/etc/sentinelx"
+	if runtime.GOOS == "windows" {
+		dir = filepath.Join(os.Getenv("ProgramData"), "SentinelX")
+	}
+	return []string{filepath.Join(dir, "config.yaml"), filepath.Join(dir, "config.yml"), filepath.Join(dir, "config.toml")}
+}
+
+// Load reads the defaults, then the config file at path (or the first of
+// DefaultPaths that exists, when path is empty), then the environment.
+// Relative paths are not resolved yet, so flags can still override them.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		path = os.Getenv("SENTINELX_CONFIG")
+	}
+	if path == "" {
+		for _, p := range DefaultPaths() {
+			if _, err := os.Stat(p); err == nil {
+				path = p
+				break
+			}
+		}
+	}
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readFile decodes a YAML (.yaml, .yml) or TOML (.toml) file over cfg.
+// Unknown keys are errors, so typos don't go unnoticed.
+func (cfg *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalWithOptions(data, cfg, yaml.DisallowUnknownField())
+	case ".toml":
+		err = toml.NewDecoder(bytes.NewReader(data)).DisallowUnknownFields().Decode(cfg)
+	default:
+		return fmt.Errorf("config: %s: unknown format, use .yaml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %v", path, err)
+	}
+	cfg.Source = path
+	return nil
+}
+
+// envVars maps each SENTINELX_* variable to the setting it overrides.
+func (cfg *Config) envVars() map[string]interface{} {
+	return map[string]interface{}{
+		"SENTINELX_LISTEN":             &cfg.Listen,
+		"SENTINELX_BASE_DIR":           &cfg.BaseDir,
+		"SENTINELX_DB":                 &cfg.DBPath,
+		"SENTINELX_TEMPLATES_DIR":      &cfg.TemplatesDir,
+		"SENTINELX_STATIC_DIR":         &cfg.StaticDir,
+		"SENTINELX_REPORT_DIR":         &cfg.ReportDir,
+		"SENTINELX_PROFILE":            &cfg.DefaultProfile,
+		"SENTINELX_POLICY_LINUX":       &cfg.Policies.Linux,
+		"SENTINELX_POLICY_WINDOWS":     &cfg.Policies.Windows,
+		"SENTINELX_CHECK_TIMEOUT":      &cfg.Timeouts.Check,
+		"SENTINELX_SSH_TIMEOUT":        &cfg.Timeouts.SSH,
+		"SENTINELX_SIGN":               &cfg.Signing.Enabled,
+		"SENTINELX_KEY_DIR":            &cfg.Signing.KeyDir,
+		"SENTINELX_FLEET_TOKEN":        &cfg.Fleet.Token,
+		"SENTINELX_FLEET_VIEWER_TOKEN": &cfg.Fleet.ViewerToken,
+		"SENTINELX_LOG_LEVEL":          &cfg.Log.Level,
+		"SENTINELX_LOG_FORMAT":         &cfg.Log.Format,
+		"SENTINELX_LOG_OUTPUT":         &cfg.Log.Output,
+		"SENTINELX_LOG_FILE":           &cfg.Log.File,
+		"SENTINELX_LOG_MAX_SIZE":       &cfg.Log.MaxSizeMB,
+		"SENTINELX_LOG_MAX_FILES":      &cfg.Log.MaxFiles,
+	}
+}
+
+func (cfg *Config) applyEnv() error {
+	for name, field := range cfg.envVars() {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		var err error
+		switch f := field.(type) {
+		case *string:
+			*f = value
+		case *bool:
+			*f, err = strconv.ParseBool(value)
+		case *int:
+			*f, err = strconv.Atoi(value)
+		case *Duration:
+			err = f.UnmarshalText([]byte(value))
+		}
+		if err != nil {
+			return fmt.Errorf("config: %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Resolve makes relative paths absolute against BaseDir. Without a base
+// directory, that is the executable's directory when the UI templates are
+// next to it (an installed copy), else the working directory.
+func (cfg *Config) Resolve() error {
+	if cfg.BaseDir == "" {
+		cfg.BaseDir = defaultBaseDir(cfg.TemplatesDir)
+	}
+	base, err := filepath.Abs(cfg.BaseDir)
+	if err != nil {
+		return fmt.Errorf("config: base_dir: %v", err)
+	}
+	cfg.BaseDir = base
+	for _, p := range []*string{&cfg.DBPath, &cfg.TemplatesDir, &cfg.StaticDir, &cfg.ReportDir, &cfg.Policies.Linux, &cfg.Policies.Windows, &cfg.Signing.KeyDir, &cfg.Log.File} {
+		if *p != "" && *p != ":memory:" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(base, *p)
+		}
+	}
+	return nil
+}
+
+func defaultBaseDir(templatesDir string) string {
+	if exe, err := os.Executable(); err == nil {
+		dir := filepath.Dir(exe)
+		if _, err := os.Stat(filepath.Join(dir, templatesDir)); err == nil {
+			return dir
+		}
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+// Validate reports the first invalid setting.
+func (cfg *Config) Validate() error {
+	_, port, err := net.SplitHostPort(cfg.Listen) // an empty host means all interfaces
+	if err != nil {
+		return fmt.Errorf("config: listen: %v", err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("config: listen: invalid port %q", port)
+	}
+
+	switch cfg.DefaultProfile {
+	case "strict", "moderate", "basic":
+	default:
+		return fmt.Errorf("config: default_profile must be strict, moderate or basic, got %q", cfg.DefaultProfile)
+	}
+	if cfg.Timeouts.Check <= 0 {
+		return fmt.Errorf("config: timeouts.check must be positive")
+	}
+	if cfg.Timeouts.SSH <= 0 {
+		return fmt.Errorf("config: timeouts.ssh must be positive")
+	}
+	paths := []struct{ name, value string }{
+		{"db_path", cfg.DBPath}, {"templates_dir", cfg.TemplatesDir}, {"static_dir", cfg.StaticDir}, {"report_dir", cfg.ReportDir},
+		{"policies.linux", cfg.Policies.Linux}, {"policies.windows", cfg.Policies.Windows}, {"signing.key_dir", cfg.Signing.KeyDir},
+	}
+	for _, p := range paths {
+		if strings.TrimSpace(p.value) == "" {
+			return fmt.Errorf("config: %s must not be empty", p.name)
+		}
+	}
+	if _, err := logging.ParseLevel(cfg.Log.Level); err != nil {
+		return fmt.Errorf("config: log.level: %v", err)
+	}
+	switch cfg.Log.Format {
+	case "text", "json":
+	default:
+		return fmt.Errorf("config: log.format must be text or json, got %q", cfg.Log.Format)
+	}
+	switch cfg.Log.Output {
+	case "stderr", "stdout", "journald":
+	case "file":
+		if strings.TrimSpace(cfg.Log.File) == "" || cfg.Log.MaxSizeMB <= 0 || cfg.Log.MaxFiles < 0 {
+			return fmt.Errorf("config: log output file needs log.file, a positive log.max_size_mb and log.max_files >= 0")
+		}
+	default:
+		return fmt.Errorf("config: log.output must be stderr, stdout, file or journald, got %q", cfg.Log.Output)
+	}
+	if info, err := os.Stat(cfg.ReportDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("config: report_dir %s is not a directory", cfg.ReportDir)
+	}
+	return nil
+}
+
+// LogOptions are the logging settings for logging.Setup.
+func (cfg *Config) LogOptions() logging.Options {
+	return logging.Options{
+		Level:     cfg.Log.Level,
+		Format:    cfg.Log.Format,
+		Output:    cfg.Log.Output,
+		File:      cfg.Log.File,
+		MaxSizeMB: cfg.Log.MaxSizeMB,
+		MaxFiles:  cfg.Log.MaxFiles,
+	}
+}
+
+// Public is a copy safe to show over the API, secrets redacted.
+func (cfg *Config) Public() Config {
+	out := *cfg
+	if out.Fleet.Token != "" {
+		out.Fleet.Token = Redacted
+	}
+	if out.Fleet.ViewerToken != "" {
+		out.Fleet.ViewerToken = Redacted
+	}
+	return out
+}
@@ -1,18 +1,27 @@
 package dag
 
 import (
+	"context"
 	"fmt"
+	"sih2025/internal/logging"
 	"sih2025/internal/policy"
 )
 
+var logger = logging.Logger("dag")
+
 func SortRules(rules []policy.Rule) ([][]policy.Rule, error) {
+	return SortRulesContext(context.Background(), rules)
+}
+
+// SortRulesContext is SortRules logging its warnings under ctx's correlation ID
+func SortRulesContext(ctx context.Context, rules []policy.Rule) ([][]policy.Rule, error) {
 
 	uniqueRules := []policy.Rule{}
 	seenIDs := make(map[string]bool)
 
 	for _, r := range rules {
 		if _, exists := seenIDs[r.ID]; exists {
-			fmt.Printf("[SCHEDULER WARN] Duplicate Rule ID found and skipped: %s\n", r.ID)
+			logger.WarnContext(ctx, "duplicate rule ID skipped", "rule", r.ID)
 			continue
 		}
 		seenIDs[r.ID] = true
@@ -38,7 +47,7 @@ func SortRules(rules []policy.Rule) ([][]policy.Rule, error) {
 			if _, exists := ruleMap[depID]; !exists {
 				// Warn but don't crash? For strict DAG, this is an error.
 				// For this demo, let's ignore broken deps to keep running.
-				fmt.Printf("[SCHEDULER WARN] Rule %s depends on missing rule %s. Dependency ignored.\n", r.ID, depID)
+				logger.WarnContext(ctx, "dependency on missing rule ignored", "rule", r.ID, "depends_on", depID)
 				continue
 			}
 			graph[depID] = append(graph[depID], r.ID)
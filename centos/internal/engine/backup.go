@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"sih2025/internal/platform"
+	"sih2025/internal/state"
+)
+
+// fileCopy is a file's content from before a fix changed it, kept in memory
+// so a failed validation can put it back.
+type fileCopy struct {
+	path    string
+	data    []byte
+	existed bool
+	backup  string // where BackupFile put the copy, "" if it made none
+}
+
+// backupFiles copies files a rule is about to change and records where the
+// copies went. A file that can't be backed up stops the change.
+func backupFiles(ctx context.Context, worker platform.HardenerInterface, ruleID string, paths ...string) ([]fileCopy, error) {
+	var copies []fileCopy
+	for _, path := range paths {
+		data, err := worker.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			copies = append(copies, fileCopy{path: path})
+			continue // new file, nothing to keep
+		} else if err != nil {
+			return copies, fmt.Errorf("backup failed: %v", err)
+		}
+		copies = append(copies, fileCopy{path: path, data: data, existed: true})
+
+		backup, err := worker.BackupFile(path)
+		if err != nil {
+			return copies, fmt.Errorf("backup failed: %v", err)
+		}
+		if backup == "" {
+			continue
+		}
+		copies[len(copies)-1].backup = backup
+		logger.InfoContext(ctx, "file backed up", "rule", ruleID, "path", path, "backup", backup)
+		if state.DB == nil {
+			continue
+		}
+		if err := state.RecordBackup(ruleID, path, backup); err != nil {
+			logger.ErrorContext(ctx, "state update failed", "rule", ruleID, "err", err)
+		}
+	}
+	return copies, nil
+}
+
+// restoreFiles puts files back the way backupFiles found them: existing ones
+// get their old content (atomically, metadata kept), new ones are removed.
+func restoreFiles(worker platform.HardenerInterface, copies []fileCopy) error {
+	var firstErr error
+	for i := len(copies) - 1; i >= 0; i-- {
+		c := copies[i]
+		var err error
+		if c.existed {
+			err = worker.WriteFile(c.path, c.data, 0644)
+		} else {
+			err = worker.RemoveFile(c.path)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("restore %s: %v", c.path, err)
+		}
+	}
+	return firstErr
+}
+
+// fileBackups maps each file a fix wrote to its backup, "" for files it
+// created. A file that existed but has no backup is left out: without one
+// its old content is unknown.
+func fileBackups(copies []fileCopy) map[string]string {
+	backups := make(map[string]string)
+	for _, c := range copies {
+		if !c.existed || c.backup != "" {
+			backups[c.path] = c.backup
+		}
+	}
+	return backups
+}
+
+// restoreBackups puts back the files of one history entry from their
+// backups and removes the ones it created. The current files are backed up
+// first, like before any other change.
+func restoreBackups(ctx context.Context, worker platform.HardenerInterface, ruleID string, backups map[string]string) error {
+	paths := make([]string, 0, len(backups))
+	for path := range backups {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if _, err := backupFiles(ctx, worker, ruleID, paths...); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		var err error
+		if backup := backups[path]; backup != "" {
+			err = worker.RestoreFile(path, backup)
+		} else {
+			err = worker.RemoveFile(path)
+		}
+		if err != nil {
+			return fmt.Errorf("restore %s: %v", path, err)
+		}
+		logger.InfoContext(ctx, "file restored", "rule", ruleID, "path", path, "backup", backups[path])
+	}
+	return nil
+}
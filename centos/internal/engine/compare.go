@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+)
+
+// compareCheck runs a check that has a typed comparison: the command's output
+// is only used to extract the value, so a failing grep simply means the
+// setting is missing.
+func compareCheck(worker platform.HardenerInterface, c policy.CheckAction) (bool, string, error) {
+	_, output, err := worker.RunCommand(c.Cmd, c.Args, "")
+	if errors.Is(err, platform.ErrNotApplicable) {
+		return false, "", err
+	}
+	value, ok, err := policy.ExtractValue(c.ExpectPattern, output)
+	if err != nil {
+		return false, "", err
+	}
+	if !ok {
+		return false, "Not Set", nil
+	}
+	passed, err := c.Compare.Evaluate(value)
+	return passed, value, err
+}
+
+// expectedText is the check's condition as shown in reports.
+func expectedText(c policy.CheckAction) string {
+	if c.ConfigKey != "" {
+		switch {
+		case c.Compare != nil:
+			return c.ConfigKey + " " + c.Compare.String()
+		case c.Expected == nil:
+			return c.ConfigKey + " present"
+		}
+		return fmt.Sprintf("%s %v", c.ConfigKey, c.Expected)
+	}
+	if c.Compare != nil {
+		return c.Compare.String()
+	}
+	return c.ExpectPattern
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"sih2025/internal/confedit"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+)
+
+// checkConfigKey reads the effective value of a key from a structured config
+// file. Without an expected value (or comparison) the key only has to be set.
+func checkConfigKey(worker platform.HardenerInterface, c policy.CheckAction) (bool, string, error) {
+	cfg, err := confedit.Load(worker, c.ConfigFormat, c.FilePath)
+	if err != nil {
+		return false, "", err
+	}
+	value, _, ok := cfg.Get(c.ConfigKey, c.ConfigSection)
+	if !ok {
+		return false, "Not Set", nil
+	}
+	actual := value
+	if actual == "" {
+		actual = "Present"
+	}
+
+	switch {
+	case c.Compare != nil:
+		passed, err := c.Compare.Evaluate(value)
+		return passed, actual, err
+	case c.Expected == nil:
+		return true, actual, nil
+	}
+	return sameConfigValue(value, fmt.Sprintf("%v", c.Expected)), actual, nil
+}
+
+// sameConfigValue compares values ignoring case and runs of whitespace, as
+// "yes"/"Yes" and "32768	60999"/"32768 60999" mean the same to these programs.
+func sameConfigValue(a, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+// configValue is an action's value as written to the file; ok is false when
+// the key is to be commented out.
+func configValue(a policy.Action) (string, bool) {
+	if a.Value == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%v", a.Value), true
+}
+
+// applyConfigKey sets (or comments out) a key and writes the changed files,
+// backing each one up first. It returns the files' previous contents.
+func applyConfigKey(ctx context.Context, worker platform.HardenerInterface, ruleID string, a policy.Action) ([]fileCopy, error) {
+	cfg, err := confedit.Load(worker, a.ConfigFormat, a.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := configValue(a); ok {
+		if err := cfg.Set(a.ConfigKey, a.ConfigSection, value); err != nil {
+			return nil, err
+		}
+	} else {
+		cfg.Unset(a.ConfigKey, a.ConfigSection)
+	}
+	copies, err := backupFiles(ctx, worker, ruleID, cfg.Pending()...)
+	if err != nil {
+		return nil, err
+	}
+	written, err := cfg.Save()
+	if len(written) > 0 {
+		logger.InfoContext(ctx, "config key updated", "rule", ruleID, "key", a.ConfigKey, "files", strings.Join(written, ", "))
+	}
+	return copies, err
+}
+
+// configKeyHistory returns the current and new value of a config_key fix for
+// the rollback log.
+func configKeyHistory(worker platform.HardenerInterface, a policy.Action) (prev, next string) {
+	prev, next = "Not Set", "Removed"
+	if cfg, err := confedit.Load(worker, a.ConfigFormat, a.FilePath); err == nil {
+		if value, _, ok := cfg.Get(a.ConfigKey, a.ConfigSection); ok {
+			prev = strings.TrimSpace(a.ConfigKey + " " + value)
+		}
+	}
+	if value, ok := configValue(a); ok {
+		next = strings.TrimSpace(a.ConfigKey + " " + value)
+	}
+	return prev, next
+}
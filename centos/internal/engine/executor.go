@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -10,11 +11,17 @@ import (
     "os/exec"
 
 	"sih2025/internal/dag"
+	"sih2025/internal/logging"
 	"sih2025/internal/platform"
 	"sih2025/internal/policy"
 	"sih2025/internal/state"
 )
 
+// CheckTimeout bounds a single rule check.
+var CheckTimeout = 5 * time.Second
+
+var logger = logging.Logger("engine")
+
 type AuditResult struct {
 	ID       string `json:"id"`
 	Name     string `json:"name"`
@@ -22,6 +29,9 @@ type AuditResult struct {
 	Status   string `json:"status"`
 	Actual   string `json:"actual"`
 	Expected string `json:"expected"`
+
+	// Waiver is the risk acceptance covering this result, if any
+	Waiver *state.Waiver `json:"waiver,omitempty"`
 }
 
 
@@ -77,19 +87,32 @@ func getRawSystemValue(worker platform.HardenerInterface, cmd string, args []str
 	return output
 }
 
-// RunAudit executes rules
+// RunAudit executes rules on the local host
 func RunAudit(pol *policy.Policy) []AuditResult {
+	return RunAuditWith(platform.GetPlatform(), pol)
+}
+
+// RunAuditWith executes rules through the given platform (e.g. a remote SSH host)
+func RunAuditWith(worker platform.HardenerInterface, pol *policy.Policy) []AuditResult {
+	return RunAuditContext(context.Background(), worker, pol)
+}
+
+// RunAuditContext is RunAuditWith logging under ctx's correlation ID, or a
+// new one when ctx has none. Cancelling ctx times out the remaining checks.
+func RunAuditContext(ctx context.Context, worker platform.HardenerInterface, pol *policy.Policy) []AuditResult {
 	var results []AuditResult
 	var mutex sync.Mutex
 
-	worker := platform.GetPlatform()
-	secManager := NewSecEditManager()
+	ctx = logging.EnsureID(ctx)
+	started := time.Now()
+	secManager := NewSecEditManager(worker)
 
-	layers, err := dag.SortRules(pol.Rules)
+	layers, err := dag.SortRulesContext(ctx, pol.Rules)
 	if err != nil {
-		fmt.Printf("[CRITICAL ERROR] Dependency Cycle: %v\n", err)
+		logger.ErrorContext(ctx, "dependency cycle", "err", err)
 		return nil
 	}
+	logger.InfoContext(ctx, "audit started", "target", worker.GetOSName(), "rules", len(pol.Rules))
 
 	for _, layer := range layers {
 		var wg sync.WaitGroup
@@ -97,85 +120,228 @@ func RunAudit(pol *policy.Policy) []AuditResult {
 			wg.Add(1)
 			go func(r policy.Rule) {
 				defer wg.Done()
-				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-				defer cancel()
-
-				resultChan := make(chan struct {
-					status string
-					actual string
-				}, 1)
-
-				go func() {
-					passed := false
-					var err error
-					actualVal := ""
-
-					switch r.Type {
-					case "command", "file_check", "file_edit":
-						// Standard Check
-						passed, actualVal, err = worker.RunCommand(r.Check.Cmd, r.Check.Args, r.Check.ExpectPattern)
-						
-						// IF FAIL: Use Smart Helper to get the REAL value instead of "fail"
-						if !passed {
-							rawVal := getRawSystemValue(worker, r.Check.Cmd, r.Check.Args)
-							if rawVal != "Missing" && rawVal != "fail" {
-								actualVal = rawVal
-							}
-						}
-
-						actualVal = strings.TrimSpace(actualVal)
-						if len(actualVal) > 60 { actualVal = actualVal[:57] + "..." }
-						
-						if passed && actualVal == "" { actualVal = "Verified Secure" }
-
-					case "registry":
-						passed, err = worker.CheckRegistry(r.Check.RegKey, r.Check.RegValue, r.Check.Expected)
-						if passed { actualVal = fmt.Sprintf("%v", r.Check.Expected) } else { actualVal = "Registry Mismatch" }
-
-					case "secedit":
-						expectedStr, _ := r.Check.Expected.(string)
-						passed, err = secManager.CheckUserRight(r.Check.RegKey, expectedStr)
-						if passed { actualVal = "Right Assigned" } else { actualVal = "Right Missing" }
-					}
-
-					status := "FAIL"
-					if err == nil && passed {
-						status = "PASS"
-					}
-					resultChan <- struct{ status, actual string }{status, actualVal}
-				}()
-
-				var finalRes struct{ status, actual string }
-				select {
-				case res := <-resultChan:
-					finalRes = res
-				case <-ctx.Done():
-					finalRes = struct{ status, actual string }{"TIMEOUT", "Check timed out"}
-				}
+				res := checkRule(ctx, worker, secManager, r)
 
 				mutex.Lock()
-				results = append(results, AuditResult{
-					ID:       r.ID,
-					Name:     r.Name,
-					Severity: r.Severity,
-					Status:   finalRes.status,
-					Actual:   finalRes.actual,
-					Expected: r.Check.ExpectPattern,
-				})
+				results = append(results, res)
 				mutex.Unlock()
 			}(rule)
 		}
 		wg.Wait()
 	}
+	applyPendingReboots(ctx, worker, results)
+	recordScan(worker, pol, results, time.Since(started))
+
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	logger.InfoContext(ctx, "audit finished", "results", len(results), "pass", counts["PASS"], "fail", counts["FAIL"],
+		"timeout", counts["TIMEOUT"], "duration_ms", time.Since(started).Milliseconds())
 	return results
 }
 
-// ApplyFix performs Remediation
-func ApplyFix(rule policy.Rule) error {
-	worker := platform.GetPlatform()
-	secManager := NewSecEditManager()
+// CheckRule audits a single rule through the given platform
+func CheckRule(worker platform.HardenerInterface, rule policy.Rule) AuditResult {
+	return checkRule(context.Background(), worker, NewSecEditManager(worker), rule)
+}
+
+func checkRule(ctx context.Context, worker platform.HardenerInterface, secManager *SecEditManager, r policy.Rule) AuditResult {
+	timeout, cancel := context.WithTimeout(ctx, CheckTimeout)
+	defer cancel()
+	started := time.Now()
+
+	resultChan := make(chan struct {
+		status string
+		actual string
+	}, 1)
+
+	go func() {
+		passed := false
+		var err error
+		actualVal := ""
+
+		switch r.Type {
+		case "command", "file_check", "file_edit":
+			if r.Check.Compare != nil {
+				// Typed comparison on the extracted value
+				passed, actualVal, err = compareCheck(worker, r.Check)
+			} else {
+				// Standard Check
+				passed, actualVal, err = worker.RunCommand(r.Check.Cmd, r.Check.Args, r.Check.ExpectPattern)
+			}
+
+			// IF FAIL: Use Smart Helper to get the REAL value instead of "fail"
+			if !passed && r.Check.Compare == nil {
+				rawVal := getRawSystemValue(worker, r.Check.Cmd, r.Check.Args)
+				if rawVal != "Missing" && rawVal != "fail" {
+					actualVal = rawVal
+				}
+			}
+
+			actualVal = strings.TrimSpace(actualVal)
+			if len(actualVal) > 60 { actualVal = actualVal[:57] + "..." }
+			
+			if passed && actualVal == "" { actualVal = "Verified Secure" }
+
+		case "registry":
+			passed, actualVal, err = checkRegistry(worker, r.Check)
+			if len(actualVal) > 60 { actualVal = actualVal[:57] + "..." }
+
+		case "config_key":
+			passed, actualVal, err = checkConfigKey(worker, r.Check)
+			if len(actualVal) > 60 { actualVal = actualVal[:57] + "..." }
+
+		case "secedit":
+			passed, actualVal, err = secManager.Check(r.Check.RegKey, r.Check.Expected)
+
+		case "manual":
+			// No automated check (e.g. imported from a benchmark); stays FAIL until waived
+			actualVal = "Manual review required"
+		}
+
+		status := "FAIL"
+		if err == nil && passed {
+			status = "PASS"
+		}
+		if errors.Is(err, platform.ErrNotApplicable) {
+			status = "NOT_APPLICABLE"
+			actualVal = "Requires a running system"
+		}
+		resultChan <- struct{ status, actual string }{status, actualVal}
+	}()
+
+	var finalRes struct{ status, actual string }
+	select {
+	case res := <-resultChan:
+		finalRes = res
+	case <-timeout.Done():
+		finalRes = struct{ status, actual string }{"TIMEOUT", "Check timed out"}
+		logger.WarnContext(ctx, "check timed out", "rule", r.ID)
+		checkTimeouts.Inc(r.Type)
+	}
+	checkDuration.Observe(time.Since(started).Seconds(), r.Type)
+	logger.DebugContext(ctx, "rule checked", "rule", r.ID, "status", finalRes.status, "actual", finalRes.actual)
+
+	return AuditResult{
+		ID:       r.ID,
+		Name:     r.Name,
+		Severity: r.Severity,
+		Status:   finalRes.status,
+		Actual:   finalRes.actual,
+		Expected: expectedText(r.Check),
+	}
+}
+
+// ApplyFix performs Remediation on the local host
+func ApplyFix(rule policy.Rule) (*FixResult, error) {
+	return ApplyFixWith(platform.GetPlatform(), rule)
+}
+
+// ApplyFixWith performs Remediation through the given platform, then the
+// service reloads it declares, then re-runs the check to verify it. When the
+// remediation has a validate command and it fails, the change is undone and
+// an error returned; the result carries the validation output either way.
+func ApplyFixWith(worker platform.HardenerInterface, rule policy.Rule) (*FixResult, error) {
+	return ApplyFixBy(context.Background(), worker, rule, LocalActor())
+}
+
+// ApplyFixBy is ApplyFixWith on behalf of actor, as recorded in the history,
+// logging under ctx's correlation ID (a new one when ctx has none)
+func ApplyFixBy(ctx context.Context, worker platform.HardenerInterface, rule policy.Rule, actor string) (*FixResult, error) {
+	ctx = logging.EnsureID(ctx)
+	started := time.Now()
+	tx := state.NewTransaction(actor)
+	tx.Machine = HistoryMachine(ctx, worker)
+	result, err := applyFix(ctx, worker, rule, tx)
+	fixDuration.Observe(time.Since(started).Seconds())
+	changed := err == nil && !result.Unchanged
+	if err != nil {
+		recordFixError(ctx, result, err)
+		logger.WarnContext(ctx, "fix failed", "rule", rule.ID, "tx", result.TxID, "err", err)
+	} else if changed {
+		result.RebootPending = markPendingReboot(ctx, worker, rule)
+	}
+	if changed && len(rule.Remediation.Services) > 0 {
+		var queue ServiceQueue
+		queue.Add(rule.ID, rule.Remediation.Services)
+		result.Services = queue.Run(ctx, worker)
+	}
+	if changed {
+		verifyFix(ctx, worker, rule, result)
+	}
+	recordFixOutcome(result, err)
+	return result, err
+}
+
+// ApplyFixes applies a batch of fixes on the local host
+func ApplyFixes(rules []policy.Rule) ([]*FixResult, []ServiceResult) {
+	return ApplyFixesWith(platform.GetPlatform(), rules)
+}
+
+// ApplyFixesWith applies the rules' remediations in the given order, as one
+// transaction, then runs the service actions of the ones that succeeded, each
+// once, then verifies those fixes.
+func ApplyFixesWith(worker platform.HardenerInterface, rules []policy.Rule) ([]*FixResult, []ServiceResult) {
+	return ApplyFixesContext(context.Background(), worker, rules)
+}
+
+// ApplyFixesContext is ApplyFixesWith logging under ctx's correlation ID, or
+// a new one when ctx has none
+func ApplyFixesContext(ctx context.Context, worker platform.HardenerInterface, rules []policy.Rule) ([]*FixResult, []ServiceResult) {
+	var queue ServiceQueue
+	ctx = logging.EnsureID(ctx)
+	tx := state.NewTransaction(LocalActor())
+	tx.Machine = HistoryMachine(ctx, worker)
+	results := make([]*FixResult, 0, len(rules))
+	for _, rule := range rules {
+		started := time.Now()
+		result, err := applyFix(ctx, worker, rule, tx)
+		fixDuration.Observe(time.Since(started).Seconds())
+		if err != nil {
+			recordFixOutcome(result, err)
+			recordFixError(ctx, result, err)
+			result.Error = err.Error()
+			logger.WarnContext(ctx, "fix failed", "rule", rule.ID, "tx", tx.ID, "err", err)
+		} else if !result.Unchanged {
+			result.RebootPending = markPendingReboot(ctx, worker, rule)
+			queue.Add(rule.ID, rule.Remediation.Services)
+		}
+		results = append(results, result)
+	}
+	services := queue.Run(ctx, worker)
+	for i, rule := range rules {
+		if results[i].Error == "" {
+			if !results[i].Unchanged {
+				verifyFix(ctx, worker, rule, results[i])
+			}
+			recordFixOutcome(results[i], nil)
+		}
+	}
+	return results, services
+}
 
-	fmt.Printf("[FIX] Automating Rule: %s\n", rule.ID)
+// applyFix makes the change as part of transaction tx, without the service
+// actions. A rule that already passes is left alone and not logged.
+func applyFix(ctx context.Context, worker platform.HardenerInterface, rule policy.Rule, tx state.Transaction) (*FixResult, error) {
+	result := &FixResult{RuleID: rule.ID, TxID: tx.ID}
+	secManager := NewSecEditManager(worker)
+
+	// Nothing to run means nothing changes, so nothing goes in the history
+	if rule.Remediation.Type == "manual" && (rule.Remediation.Cmd == "echo" || rule.Remediation.Cmd == "") {
+		return result, fmt.Errorf("manual action required")
+	}
+
+	before := checkRule(ctx, worker, secManager, rule)
+	result.PreStatus = before.Status
+	if before.Status == "PASS" {
+		logger.InfoContext(ctx, "rule already passes, fix skipped", "rule", rule.ID, "tx", tx.ID)
+		result.Unchanged = true
+		result.Verification, result.Check = FixVerified, &before
+		return result, nil
+	}
+
+	logger.InfoContext(ctx, "applying fix", "rule", rule.ID, "type", rule.Remediation.Type, "tx", tx.ID, "actor", tx.Actor)
 
 	// --- 1. CAPTURE PREVIOUS VALUE ---
 	prevValue := getRawSystemValue(worker, rule.Check.Cmd, rule.Check.Args)
@@ -218,73 +384,146 @@ func ApplyFix(rule policy.Rule) error {
 		}
 	}
 
+	// Registry fixes record the exact previous value (type included) for the rollback
+	snapshot := ""
+	if isRegistryAction(rule.Remediation.Type) {
+		var err error
+		prevValue, newValue, snapshot, err = captureRegistry(worker, rule.Remediation)
+		if err != nil { return result, fmt.Errorf("fix failed: reading previous value: %v", err) }
+	}
+	if rule.Remediation.Type == "config_key" {
+		prevValue, newValue = configKeyHistory(worker, rule.Remediation)
+	}
+
 	// --- 3. LOG TO DB ---
-	err := state.LogAction(rule.ID, rule.Name, prevValue, newValue)
-	if err != nil { fmt.Printf("DB Log Error: %v\n", err) }
+	var err error
+	result.HistoryID, err = state.LogFix(tx, rule.ID, rule.Name, prevValue, newValue, snapshot)
+	if err != nil { logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", err) }
 
 	// --- 4. APPLY ---
+	var files []fileCopy
 	switch rule.Remediation.Type {
-	case "registry":
-		err = worker.SetRegistry(rule.Remediation.RegKey, rule.Remediation.RegValue, rule.Remediation.Value)
+	case "registry", "registry_delete_value", "registry_delete_key":
+		err = applyRegistry(worker, rule.Remediation)
 	case "command":
 		_, _, err = worker.RunCommand(rule.Remediation.Cmd, rule.Remediation.Args, "")
 	case "file_edit", "file_append":
-		err = worker.EditConfigFile(rule.Remediation.FilePath, rule.Remediation.SearchRegex, rule.Remediation.ReplaceText)
+		if files, err = backupFiles(ctx, worker, rule.ID, rule.Remediation.FilePath); err == nil {
+			err = worker.EditConfigFile(rule.Remediation.FilePath, rule.Remediation.SearchRegex, rule.Remediation.ReplaceText)
+		}
+	case "config_key":
+		files, err = applyConfigKey(ctx, worker, rule.ID, rule.Remediation)
 	case "secedit":
-		valStr, _ := rule.Remediation.Value.(string)
-		err = secManager.SetUserRight(rule.Remediation.RegKey, valStr)
+		err = secManager.Set(rule.Remediation.RegKey, seceditValue(rule.Remediation.Value))
 	case "manual":
-		if rule.Remediation.Cmd != "echo" && rule.Remediation.Cmd != "" {
-			_, _, err = worker.RunCommand(rule.Remediation.Cmd, rule.Remediation.Args, "")
-		} else {
-			return fmt.Errorf("manual action required")
-		}
+		_, _, err = worker.RunCommand(rule.Remediation.Cmd, rule.Remediation.Args, "")
 	default:
-		return fmt.Errorf("unknown remediation type: %s", rule.Remediation.Type)
+		return result, fmt.Errorf("unknown remediation type: %s", rule.Remediation.Type)
 	}
 
-	if err != nil { return fmt.Errorf("fix failed: %v", err) }
-	return nil
+	// What was written, so reverting an older fix can spot later changes to the same place
+	if result.HistoryID != 0 {
+		if dbErr := state.RecordFixTargets(result.HistoryID, fixTargets(rule.Remediation, files)); dbErr != nil { logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", dbErr) }
+		if backups := fileBackups(files); len(backups) > 0 {
+			if dbErr := state.RecordFixBackups(result.HistoryID, backups); dbErr != nil { logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", dbErr) }
+		}
+	}
+
+	if err != nil { return result, fmt.Errorf("fix failed: %v", err) }
+
+	// --- 5. VALIDATE (undo the change if it broke the config) ---
+	if rule.Remediation.Validate == nil {
+		return result, nil
+	}
+	ran, ok, output := runValidation(worker, rule.Remediation)
+	result.Validation, result.Validated = output, ran && ok
+	if ok {
+		if err := state.RecordValidation(result.HistoryID, output, false); err != nil { logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", err) }
+		return result, nil
+	}
+
+	logger.WarnContext(ctx, "validation failed, restoring previous state", "rule", rule.ID, "output", output)
+	if err := undoFix(ctx, worker, rule, files, snapshot); err != nil {
+		if dbErr := state.RecordValidation(result.HistoryID, output, false); dbErr != nil { logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", dbErr) }
+		return result, fmt.Errorf("validation failed (%s) and restoring the previous state failed: %v", output, err)
+	}
+	result.Reverted = true
+	if err := state.RecordValidation(result.HistoryID, output, true); err != nil { logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", err) }
+	return result, fmt.Errorf("validation failed, previous state restored: %s", output)
 }
 
 // RevertFix (Keep existing)
 func RevertFix(rule policy.Rule) error {
-	worker := platform.GetPlatform()
-	secManager := NewSecEditManager()
-	
+	return RevertFixWith(platform.GetPlatform(), rule)
+}
+
+// RevertFixWith runs the rule's rollback through the given platform
+func RevertFixWith(worker platform.HardenerInterface, rule policy.Rule) error {
+	return RevertFixBy(context.Background(), worker, rule, LocalActor())
+}
+
+// RevertFixBy is RevertFixWith on behalf of actor, as recorded in the history,
+// logging under ctx's correlation ID (a new one when ctx has none)
+func RevertFixBy(ctx context.Context, worker platform.HardenerInterface, rule policy.Rule, actor string) error {
+	ctx = logging.EnsureID(ctx)
+	logger.InfoContext(ctx, "reverting fix", "rule", rule.ID, "actor", actor)
+	err := revertFix(ctx, worker, rule)
+	if err != nil {
+		logger.WarnContext(ctx, "revert failed", "rule", rule.ID, "err", err)
+		return err
+	}
+	markReverted(ctx, worker, HistoryMachine(ctx, worker), rule, actor)
+	var queue ServiceQueue
+	queue.Add(rule.ID, revertServices(rule))
+	queue.Run(ctx, worker)
+	return nil
+}
+
+// revertServices are the service actions a rollback needs: its own, or else
+// the remediation's, since it touches the same configuration.
+func revertServices(rule policy.Rule) []policy.ServiceAction {
+	if len(rule.Rollback.Services) > 0 {
+		return rule.Rollback.Services
+	}
+	return rule.Remediation.Services
+}
+
+// revertFix undoes the change, without the service actions
+func revertFix(ctx context.Context, worker platform.HardenerInterface, rule policy.Rule) error {
+	secManager := NewSecEditManager(worker)
+
+	// Registry fixes go back to the exact value they replaced, when it was captured
+	if isRegistryAction(rule.Remediation.Type) {
+		if restored, err := restoreRegistry(worker, HistoryMachine(ctx, worker), rule.ID); restored {
+			return err
+		}
+	}
+
 	var err error
 	switch rule.Rollback.Type {
-	case "registry":
-		err = worker.SetRegistry(rule.Rollback.RegKey, rule.Rollback.RegValue, rule.Rollback.Value)
+	case "registry", "registry_delete_value", "registry_delete_key":
+		err = applyRegistry(worker, rule.Rollback)
 	case "command":
 		_, _, err = worker.RunCommand(rule.Rollback.Cmd, rule.Rollback.Args, "")
 	case "file_edit":
-		err = worker.EditConfigFile(rule.Rollback.FilePath, rule.Rollback.SearchRegex, rule.Rollback.ReplaceText)
+		if _, err = backupFiles(ctx, worker, rule.ID, rule.Rollback.FilePath); err == nil {
+			err = worker.EditConfigFile(rule.Rollback.FilePath, rule.Rollback.SearchRegex, rule.Rollback.ReplaceText)
+		}
+	case "config_key":
+		_, err = applyConfigKey(ctx, worker, rule.ID, rule.Rollback)
 	case "secedit":
-		valStr, _ := rule.Rollback.Value.(string)
-		err = secManager.SetUserRight(rule.Rollback.RegKey, valStr)
+		err = secManager.Set(rule.Rollback.RegKey, seceditValue(rule.Rollback.Value))
+	case "manual":
+		return fmt.Errorf("manual rollback required")
 	default:
 		return fmt.Errorf("unknown rollback type: %s", rule.Rollback.Type)
 	}
-	return err
-}
-
-func RevertAll(pol *policy.Policy) (string, error) {
-	revertedCount := 0
-	errorCount := 0
 
-	fmt.Println("[RESET] Starting MASTER FORCE RESET...")
-
-	for _, rule := range pol.Rules {
-		fmt.Printf("   > Force Reverting: %s\n", rule.ID)
-		err := RevertFix(rule)
-		if err != nil {
-			fmt.Printf("     [WARN] Revert issue on %s: %v\n", rule.ID, err)
-			errorCount++
-		} else {
-			revertedCount++
+	// A rollback's validate command only reports; undoing it would re-apply the fix
+	if err == nil && rule.Rollback.Validate != nil {
+		if _, ok, output := runValidation(worker, rule.Rollback); !ok {
+			return fmt.Errorf("rollback applied but validation failed: %s", output)
 		}
 	}
-
-	return fmt.Sprintf("Reset Complete. Reverted %d rules. Errors: %d", revertedCount, errorCount), nil
-}
\ No newline at end of file
+	return err
+}
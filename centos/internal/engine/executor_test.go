@@ -0,0 +1,410 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/state"
+	"sih2025/internal/winreg"
+)
+
+// useFake installs a fresh FakeHardener as the platform and a throwaway
+// state database, for the length of the test.
+func useFake(t *testing.T, osName string) *platform.FakeHardener {
+	t.Helper()
+	if err := state.Open(":memory:"); err != nil {
+		t.Fatal(err)
+	}
+	f := platform.NewFakeHardener(osName)
+	platform.SetPlatform(f)
+	t.Cleanup(func() {
+		platform.SetPlatform(nil)
+		state.DB.Close()
+		state.DB = nil
+	})
+	return f
+}
+
+// auditStatus runs a whole audit of pol and returns the status of rule id.
+func auditStatus(t *testing.T, pol *policy.Policy, id string) string {
+	t.Helper()
+	for _, r := range RunAudit(pol) {
+		if r.ID == id {
+			return r.Status
+		}
+	}
+	t.Fatalf("rule %s missing from the audit", id)
+	return ""
+}
+
+// onlyEntry returns the single fix logged for a rule.
+func onlyEntry(t *testing.T, ruleID string) state.HistoryEntry {
+	t.Helper()
+	entries, _, err := state.ListHistory(state.HistoryFilter{RuleID: ruleID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%s has %d history entries, want 1", ruleID, len(entries))
+	}
+	return entries[0]
+}
+
+const sshdConfig = "/etc/ssh/sshd_config"
+
+var rootLoginRule = policy.Rule{
+	ID:   "SSH-001",
+	Name: "Disable SSH root login",
+	Type: "file_edit",
+	Check: policy.CheckAction{
+		Cmd: "grep", Args: []string{"-i", "^PermitRootLogin", sshdConfig}, ExpectPattern: "(?i)PermitRootLogin no",
+	},
+	Remediation: policy.Action{Type: "file_edit", FilePath: sshdConfig, SearchRegex: "^PermitRootLogin .*", ReplaceText: "PermitRootLogin no"},
+	Rollback:    policy.Action{Type: "file_edit", FilePath: sshdConfig, SearchRegex: "^PermitRootLogin .*", ReplaceText: "PermitRootLogin yes"},
+}
+
+func TestFileEditFixAndRevert(t *testing.T) {
+	f := useFake(t, "linux")
+	f.SetFile(sshdConfig, "Port 22\nPermitRootLogin yes\n", 0600)
+	pol := &policy.Policy{Rules: []policy.Rule{rootLoginRule}}
+
+	if got := auditStatus(t, pol, "SSH-001"); got != "FAIL" {
+		t.Fatalf("before the fix: %s, want FAIL", got)
+	}
+	res, err := ApplyFix(rootLoginRule)
+	if err != nil {
+		t.Fatalf("ApplyFix: %v", err)
+	}
+	if res.Verification != FixVerified || res.PreStatus != "FAIL" {
+		t.Errorf("verification %s, pre-status %s; want VERIFIED after FAIL", res.Verification, res.PreStatus)
+	}
+	if got, _ := f.FileContent(sshdConfig); got != "Port 22\nPermitRootLogin no\n" {
+		t.Errorf("after the fix the file is %q", got)
+	}
+	if f.Files[sshdConfig].Mode != 0600 {
+		t.Errorf("the fix changed the mode to %v", f.Files[sshdConfig].Mode)
+	}
+	backups, err := state.ListBackups("SSH-001")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("backups: %v, %v; want one", backups, err)
+	}
+	if got, _ := f.FileContent(backups[0].Backup); got != "Port 22\nPermitRootLogin yes\n" {
+		t.Errorf("backup holds %q", got)
+	}
+	if got := auditStatus(t, pol, "SSH-001"); got != "PASS" {
+		t.Fatalf("after the fix: %s, want PASS", got)
+	}
+
+	if err := RevertFix(rootLoginRule); err != nil {
+		t.Fatalf("RevertFix: %v", err)
+	}
+	if got := auditStatus(t, pol, "SSH-001"); got != "FAIL" {
+		t.Errorf("after the revert: %s, want FAIL", got)
+	}
+	if e := onlyEntry(t, "SSH-001"); e.Status != "reverted" {
+		t.Errorf("history entry is %s, want reverted", e.Status)
+	}
+}
+
+func TestApplyFixSkipsPassingRule(t *testing.T) {
+	f := useFake(t, "linux")
+	f.SetFile(sshdConfig, "PermitRootLogin no\n", 0600)
+
+	res, err := ApplyFix(rootLoginRule)
+	if err != nil {
+		t.Fatalf("ApplyFix: %v", err)
+	}
+	if !res.Unchanged || res.HistoryID != 0 {
+		t.Errorf("unchanged %v, history id %d; want a skipped fix with no entry", res.Unchanged, res.HistoryID)
+	}
+	if len(f.Files) != 1 {
+		t.Errorf("a skipped fix wrote files (or backups): %d in the fake, want 1", len(f.Files))
+	}
+}
+
+const lsaKey = `HKLM\SYSTEM\CurrentControlSet\Control\Lsa`
+
+var anonymousRule = policy.Rule{
+	ID:          "WIN-REG-001",
+	Name:        "Restrict anonymous enumeration",
+	Type:        "registry",
+	Check:       policy.CheckAction{RegKey: lsaKey, RegValue: "RestrictAnonymous", Expected: 1, RegType: "REG_DWORD"},
+	Remediation: policy.Action{Type: "registry", RegKey: lsaKey, RegValue: "RestrictAnonymous", Value: 1, RegType: "REG_DWORD"},
+	Rollback:    policy.Action{Type: "registry", RegKey: lsaKey, RegValue: "RestrictAnonymous", Value: 0, RegType: "REG_DWORD"},
+}
+
+func TestRegistryFixAndRevert(t *testing.T) {
+	tests := []struct {
+		name string
+		prev *winreg.Value // nil: the value doesn't exist
+	}{
+		{"existing value", &winreg.Value{Type: winreg.SZ, String: "0"}},
+		{"missing value", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := useFake(t, "windows")
+			if tt.prev != nil {
+				if err := f.Registry.SetValue(lsaKey, "RestrictAnonymous", *tt.prev); err != nil {
+					t.Fatal(err)
+				}
+			}
+			pol := &policy.Policy{Rules: []policy.Rule{anonymousRule}}
+
+			if got := auditStatus(t, pol, anonymousRule.ID); got != "FAIL" {
+				t.Fatalf("before the fix: %s, want FAIL", got)
+			}
+			if _, err := ApplyFix(anonymousRule); err != nil {
+				t.Fatalf("ApplyFix: %v", err)
+			}
+			got, err := f.Registry.GetValue(lsaKey, "RestrictAnonymous")
+			if err != nil || got.Type != winreg.DWORD || got.Integer != 1 {
+				t.Fatalf("after the fix: %+v, %v; want REG_DWORD 1", got, err)
+			}
+			if got := auditStatus(t, pol, anonymousRule.ID); got != "PASS" {
+				t.Fatalf("after the fix: %s, want PASS", got)
+			}
+
+			// The snapshot wins over the rollback's REG_DWORD 0: the exact
+			// previous value (type included) comes back
+			if err := RevertFix(anonymousRule); err != nil {
+				t.Fatalf("RevertFix: %v", err)
+			}
+			got, err = f.Registry.GetValue(lsaKey, "RestrictAnonymous")
+			if tt.prev == nil {
+				if !errors.Is(err, winreg.ErrNotExist) {
+					t.Errorf("after the revert: %+v, %v; want the value gone", got, err)
+				}
+			} else if err != nil || got.Type != tt.prev.Type || !got.Equal(*tt.prev) {
+				t.Errorf("after the revert: %+v, %v; want %+v", got, err, *tt.prev)
+			}
+			if e := onlyEntry(t, anonymousRule.ID); e.Status != "reverted" {
+				t.Errorf("history entry is %s, want reverted", e.Status)
+			}
+		})
+	}
+}
+
+// Two machines sharing the state database (a remote run next to the local
+// host) each get their own registry snapshot and verification back.
+func TestRegistrySnapshotPerMachine(t *testing.T) {
+	a := useFake(t, "windows")
+	a.MachineID = "machine-a"
+	if err := a.Registry.SetValue(lsaKey, "RestrictAnonymous", winreg.Value{Type: winreg.SZ, String: "0"}); err != nil {
+		t.Fatal(err)
+	}
+	b := platform.NewFakeHardener("windows")
+	b.MachineID = "machine-b"
+
+	if _, err := ApplyFixWith(a, anonymousRule); err != nil {
+		t.Fatalf("fix on a: %v", err)
+	}
+	fixB, err := ApplyFixWith(b, anonymousRule)
+	if err != nil {
+		t.Fatalf("fix on b: %v", err)
+	}
+	if err := state.RecordVerification(fixB.HistoryID, FixUnverified, ""); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := state.GetVerification("machine-a", anonymousRule.ID); !ok || v != FixVerified {
+		t.Errorf("verification on a: %q, %v; want %s", v, ok, FixVerified)
+	}
+	if v, ok := state.GetVerification("machine-c", anonymousRule.ID); ok {
+		t.Errorf("verification on a machine without fixes: %q", v)
+	}
+
+	// b had no value, so its revert deletes it rather than restoring a's
+	if err := RevertFixWith(b, anonymousRule); err != nil {
+		t.Fatalf("revert on b: %v", err)
+	}
+	if got, err := b.Registry.GetValue(lsaKey, "RestrictAnonymous"); !errors.Is(err, winreg.ErrNotExist) {
+		t.Errorf("b after the revert: %+v, %v; want the value gone", got, err)
+	}
+	if err := RevertFixWith(a, anonymousRule); err != nil {
+		t.Fatalf("revert on a: %v", err)
+	}
+	if got, err := a.Registry.GetValue(lsaKey, "RestrictAnonymous"); err != nil || got.Type != winreg.SZ || got.String != "0" {
+		t.Errorf("a after the revert: %+v, %v; want REG_SZ 0", got, err)
+	}
+}
+
+func TestSeceditFixAndRevert(t *testing.T) {
+	tests := []struct {
+		rule   policy.Rule
+		before func(f *platform.FakeHardener)
+		fixed  func(f *platform.FakeHardener) bool
+	}{
+		{
+			rule: policy.Rule{
+				ID: "WIN-UR-001", Name: "Deny network logon to guests", Type: "secedit",
+				Check:       policy.CheckAction{RegKey: "SeDenyNetworkLogonRight", Expected: "Guests"},
+				Remediation: policy.Action{Type: "secedit", RegKey: "SeDenyNetworkLogonRight", Value: "Guests"},
+				Rollback:    policy.Action{Type: "secedit", RegKey: "SeDenyNetworkLogonRight", Value: "No One"},
+			},
+			fixed: func(f *platform.FakeHardener) bool { return f.Rights["SeDenyNetworkLogonRight"] == "*S-1-5-32-546" },
+		},
+		{
+			rule: policy.Rule{
+				ID: "WIN-PW-001", Name: "Minimum password length", Type: "secedit",
+				Check:       policy.CheckAction{RegKey: "MinimumPasswordLength", Expected: 14},
+				Remediation: policy.Action{Type: "secedit", RegKey: "MinimumPasswordLength", Value: 14},
+				Rollback:    policy.Action{Type: "secedit", RegKey: "MinimumPasswordLength", Value: 8},
+			},
+			before: func(f *platform.FakeHardener) { f.Security["MinimumPasswordLength"] = "8" },
+			fixed:  func(f *platform.FakeHardener) bool { return f.Security["MinimumPasswordLength"] == "14" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule.ID, func(t *testing.T) {
+			f := useFake(t, "windows")
+			if tt.before != nil {
+				tt.before(f)
+			}
+			pol := &policy.Policy{Rules: []policy.Rule{tt.rule}}
+
+			if got := auditStatus(t, pol, tt.rule.ID); got != "FAIL" {
+				t.Fatalf("before the fix: %s, want FAIL", got)
+			}
+			res, err := ApplyFix(tt.rule)
+			if err != nil {
+				t.Fatalf("ApplyFix: %v", err)
+			}
+			if !tt.fixed(f) || res.Verification != FixVerified {
+				t.Fatalf("fix not applied: rights %v, security %v, verification %s", f.Rights, f.Security, res.Verification)
+			}
+			if got := auditStatus(t, pol, tt.rule.ID); got != "PASS" {
+				t.Fatalf("after the fix: %s, want PASS", got)
+			}
+
+			if err := RevertFix(tt.rule); err != nil {
+				t.Fatalf("RevertFix: %v", err)
+			}
+			if got := auditStatus(t, pol, tt.rule.ID); got != "FAIL" {
+				t.Errorf("after the revert: %s, want FAIL", got)
+			}
+			// secedit's temp templates don't outlive the run
+			for path := range f.Files {
+				t.Errorf("left behind %s", path)
+			}
+		})
+	}
+}
+
+// Two fixes to the same file: reverting the later entry puts back the file
+// as the earlier fix left it, and leaves that fix in effect.
+func TestRevertEntryRestoresItsBackup(t *testing.T) {
+	f := useFake(t, "linux")
+	f.SetFile(sshdConfig, "PermitRootLogin yes\nX11Forwarding yes\n", 0600)
+	x11 := policy.Rule{
+		ID: "SSH-002", Name: "Disable X11 forwarding", Type: "file_edit",
+		Check:       policy.CheckAction{Cmd: "grep", Args: []string{"^X11Forwarding", sshdConfig}, ExpectPattern: "X11Forwarding no"},
+		Remediation: policy.Action{Type: "file_edit", FilePath: sshdConfig, SearchRegex: "^X11Forwarding .*", ReplaceText: "X11Forwarding no"},
+		Rollback:    policy.Action{Type: "command", Cmd: "true"}, // unused: the entry's backup is restored
+	}
+	pol := &policy.Policy{Rules: []policy.Rule{rootLoginRule, x11}}
+
+	if _, err := ApplyFix(rootLoginRule); err != nil {
+		t.Fatal(err)
+	}
+	res, err := ApplyFix(x11)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The earlier fix conflicts: the later one wrote the same file
+	first := onlyEntry(t, rootLoginRule.ID)
+	var conflict *ConflictError
+	if _, err := RevertEntry(t.Context(), f, pol, first.ID, "test", false); !errors.As(err, &conflict) {
+		t.Fatalf("reverting the earlier entry: %v, want a conflict", err)
+	}
+
+	if _, err := RevertEntry(t.Context(), f, pol, res.HistoryID, "test", false); err != nil {
+		t.Fatalf("RevertEntry: %v", err)
+	}
+	if got, _ := f.FileContent(sshdConfig); got != "PermitRootLogin no\nX11Forwarding yes\n" {
+		t.Errorf("after the revert the file is %q", got)
+	}
+	if e := onlyEntry(t, x11.ID); e.Status != "reverted" || e.RevertedBy != "test" {
+		t.Errorf("reverted entry is %s by %q", e.Status, e.RevertedBy)
+	}
+	if e := onlyEntry(t, rootLoginRule.ID); e.Status != "applied" {
+		t.Errorf("earlier entry is %s, want applied", e.Status)
+	}
+}
+
+// policy verify on an already hardened system: ApplyFix would skip the
+// rule, so the lifecycle rolls it back first and still exercises the fix.
+func TestVerifyLifecycleCompliantRule(t *testing.T) {
+	broken := rootLoginRule
+	broken.Remediation.ReplaceText = "PermitRootLogin yes"
+	tests := []struct {
+		name    string
+		rule    policy.Rule
+		verdict string
+		final   string
+	}{
+		{"working fix", rootLoginRule, LifecycleOK, "PermitRootLogin no\n"},
+		{"fix that changes nothing", broken, LifecycleFixIneffective, "PermitRootLogin yes\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := useFake(t, "linux")
+			f.SetFile(sshdConfig, "PermitRootLogin no\n", 0600)
+
+			results, err := VerifyLifecycle(f, &policy.Policy{Rules: []policy.Rule{tt.rule}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := results[0]
+			if res.Verdict != tt.verdict || res.Broken() != (tt.verdict != LifecycleOK) {
+				t.Fatalf("verdict %s (%s), want %s", res.Verdict, res.Detail, tt.verdict)
+			}
+			if res.Before.Status != "PASS" || res.AfterRollback.Status != "FAIL" {
+				t.Errorf("before %s, after rollback %s; want PASS then FAIL", res.Before.Status, res.AfterRollback.Status)
+			}
+			if got, _ := f.FileContent(sshdConfig); got != tt.final {
+				t.Errorf("the file ends up %q, want %q", got, tt.final)
+			}
+		})
+	}
+}
+
+func TestRegistryChecks(t *testing.T) {
+	f := useFake(t, "windows")
+	f.Registry.SetValue(lsaKey, "RestrictAnonymous", winreg.Value{Type: winreg.DWORD, Integer: 1})
+	f.Registry.SetValue(lsaKey, "LmCompatibilityLevel", winreg.Value{Type: winreg.SZ, String: "5"})
+	f.Registry.SetValue(lsaKey, "Notification Packages", winreg.Value{Type: winreg.MultiSZ, Strings: []string{"scecli"}})
+	five := 5.0
+
+	tests := []struct {
+		name  string
+		check policy.CheckAction
+		want  string
+	}{
+		{"dword matches", policy.CheckAction{RegValue: "RestrictAnonymous", Expected: 1}, "PASS"},
+		{"dword differs", policy.CheckAction{RegValue: "RestrictAnonymous", Expected: 2}, "FAIL"},
+		{"string is not a dword", policy.CheckAction{RegValue: "LmCompatibilityLevel", Expected: 5}, "FAIL"},
+		{"typed string", policy.CheckAction{RegValue: "LmCompatibilityLevel", Expected: "5", RegType: "REG_SZ"}, "PASS"},
+		{"multi string", policy.CheckAction{RegValue: "Notification Packages", Expected: []interface{}{"scecli"}}, "PASS"},
+		{"missing value", policy.CheckAction{RegValue: "NoLMHash", Expected: 1}, "FAIL"},
+		{"absent value", policy.CheckAction{RegValue: "NoLMHash", RegType: "ABSENT"}, "PASS"},
+		{"present value", policy.CheckAction{RegValue: "RestrictAnonymous", RegType: "ABSENT"}, "FAIL"},
+		{"absent key", policy.CheckAction{RegKey: lsaKey + `\Missing`, RegType: "ABSENT"}, "PASS"},
+		{"present key", policy.CheckAction{RegType: "ABSENT"}, "FAIL"},
+		{"compare as text", policy.CheckAction{RegValue: "LmCompatibilityLevel", Compare: &policy.Comparison{Op: "ge", Value: &five}}, "PASS"},
+		{"compare on a missing value", policy.CheckAction{RegValue: "NoLMHash", Compare: &policy.Comparison{Op: "ge", Value: &five}}, "FAIL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.check.RegKey == "" {
+				tt.check.RegKey = lsaKey
+			}
+			res := CheckRule(f, policy.Rule{ID: "WIN-REG", Type: "registry", Check: tt.check})
+			if res.Status != tt.want {
+				t.Errorf("status %s (%s), want %s", res.Status, res.Actual, tt.want)
+			}
+		})
+	}
+}
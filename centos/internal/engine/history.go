@@ -0,0 +1,350 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strings"
+	"time"
+
+	"sih2025/internal/dag"
+	"sih2025/internal/logging"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/state"
+)
+
+// RevertScope limits a reset to the fixes made from a point in history on.
+// The zero value reverts every change SentinelX made.
+type RevertScope struct {
+	BeforeTx string    // revert transaction BeforeTx and everything after it
+	Before   time.Time // revert fixes made at or after this time
+}
+
+// ResetResult is what RevertAll did, by rule ID.
+type ResetResult struct {
+	Reverted    []string `json:"reverted"`
+	Failed      []string `json:"failed,omitempty"`
+	Kept        []string `json:"kept,omitempty"`          // also fixed before the cutoff, so left as they were then
+	NotInPolicy []string `json:"not_in_policy,omitempty"` // changed, but the current policy has no rollback for them
+	FailedFixes int      `json:"failed_fixes,omitempty"`  // fixes that errored part-way; revert those by hand
+
+	Services []ServiceResult `json:"services,omitempty"`
+}
+
+// Summary is the one-line outcome shown by the dashboard.
+func (r *ResetResult) Summary() string {
+	msg := fmt.Sprintf("Reset Complete. Reverted %d rules. Errors: %d", len(r.Reverted), len(r.Failed))
+	if len(r.Kept) > 0 {
+		msg += fmt.Sprintf(". Kept %d fixed before the cutoff", len(r.Kept))
+	}
+	if len(r.NotInPolicy) > 0 {
+		msg += fmt.Sprintf(". Not in policy: %s", strings.Join(r.NotInPolicy, ", "))
+	}
+	if r.FailedFixes > 0 {
+		msg += fmt.Sprintf(". %d failed fixes need manual review", r.FailedFixes)
+	}
+	return msg
+}
+
+// RevertAll undoes the changes SentinelX made on the local host, as recorded
+// in the fix history, from scope on. Rules never fixed (or already compliant
+// before) are not touched. Dependents are reverted before their dependencies.
+func RevertAll(pol *policy.Policy, scope RevertScope) (*ResetResult, error) {
+	return RevertAllBy(context.Background(), platform.GetPlatform(), pol, scope, LocalActor())
+}
+
+// RevertAllBy is RevertAll through the given platform, on behalf of actor,
+// logging under ctx's correlation ID (a new one when ctx has none)
+func RevertAllBy(ctx context.Context, worker platform.HardenerInterface, pol *policy.Policy, scope RevertScope, actor string) (*ResetResult, error) {
+	ctx = logging.EnsureID(ctx)
+	machine := HistoryMachine(ctx, worker)
+	fixes, err := state.AppliedFixes(machine)
+	if err != nil {
+		return nil, err
+	}
+	start := int64(0)
+	if scope.BeforeTx != "" {
+		if start, err = state.TransactionStart(scope.BeforeTx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Rules with a fix in scope, unless also fixed before it: that fix stays
+	inScope, before := make(map[string]bool), make(map[string]bool)
+	lastFix := make(map[string]int64)
+	for _, f := range fixes {
+		lastFix[f.RuleID] = f.ID
+		if f.ID >= start && !f.Timestamp.Before(scope.Before) {
+			inScope[f.RuleID] = true
+		} else {
+			before[f.RuleID] = true
+		}
+	}
+
+	result := &ResetResult{}
+	known := make(map[string]bool)
+	for _, r := range pol.Rules {
+		known[r.ID] = true
+	}
+	for id := range inScope {
+		switch {
+		case !known[id]:
+			result.NotInPolicy = append(result.NotInPolicy, id)
+		case before[id]:
+			result.Kept = append(result.Kept, id)
+		}
+	}
+	sort.Strings(result.NotInPolicy)
+	sort.Strings(result.Kept)
+	if result.FailedFixes, err = state.FailedFixes(machine); err != nil {
+		return nil, err
+	}
+
+	// Reverse dependency order, over the whole policy so indirect dependencies count
+	layers, err := dag.SortRulesContext(ctx, pol.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.InfoContext(ctx, "reset started", "actor", actor, "before_tx", scope.BeforeTx, "in_scope", len(inScope))
+	var queue ServiceQueue
+	for i := len(layers) - 1; i >= 0; i-- {
+		// Independent rules: most recently fixed first
+		layer := layers[i]
+		sort.SliceStable(layer, func(a, b int) bool { return lastFix[layer[a].ID] > lastFix[layer[b].ID] })
+		for _, rule := range layer {
+			if !inScope[rule.ID] || before[rule.ID] {
+				continue
+			}
+			logger.InfoContext(ctx, "reverting fix", "rule", rule.ID, "actor", actor)
+			if err := revertFix(ctx, worker, rule); err != nil {
+				logger.WarnContext(ctx, "revert failed", "rule", rule.ID, "err", err)
+				result.Failed = append(result.Failed, rule.ID)
+				continue
+			}
+			markReverted(ctx, worker, machine, rule, actor)
+			result.Reverted = append(result.Reverted, rule.ID)
+			queue.Add(rule.ID, revertServices(rule))
+		}
+	}
+	result.Services = queue.Run(ctx, worker)
+	logger.InfoContext(ctx, "reset finished", "reverted", len(result.Reverted), "failed", len(result.Failed), "kept", len(result.Kept))
+	return result, nil
+}
+
+// markReverted records a successful rollback on machine in the fix history,
+// so a later reset leaves the rule alone.
+func markReverted(ctx context.Context, worker platform.HardenerInterface, machine string, rule policy.Rule, actor string) {
+	clearPendingReboot(ctx, worker, rule)
+	if state.DB == nil {
+		return
+	}
+	if err := state.MarkReverted(machine, rule.ID, actor); err != nil {
+		logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", err)
+	}
+}
+
+// recordFixError marks a fix that failed in the history. One whose
+// validation failed was already undone, and is marked reverted instead.
+func recordFixError(ctx context.Context, result *FixResult, err error) {
+	if result.HistoryID == 0 || result.Reverted {
+		return
+	}
+	if dbErr := state.RecordFixError(result.HistoryID, err.Error()); dbErr != nil {
+		logger.ErrorContext(ctx, "state update failed", "rule", result.RuleID, "err", dbErr)
+	}
+}
+
+// HistoryMachine is the machine ID fixes made through worker are logged
+// under, so resets and reverts only pick up that machine's fixes (remote and
+// offline-image runs share the local database). A target without one, such
+// as an offline image, gets "".
+func HistoryMachine(ctx context.Context, worker platform.HardenerInterface) string {
+	machine, _, err := worker.BootID()
+	if err != nil {
+		if !errors.Is(err, platform.ErrNotApplicable) {
+			logger.WarnContext(ctx, "could not read machine ID", "err", err)
+		}
+		return ""
+	}
+	return machine
+}
+
+// FixedRules returns the IDs of the rules with a fix in effect on worker's
+// machine: logged, not reverted and not failed.
+func FixedRules(ctx context.Context, worker platform.HardenerInterface) (map[string]bool, error) {
+	fixes, err := state.AppliedFixes(HistoryMachine(ctx, worker))
+	if err != nil {
+		return nil, err
+	}
+	fixed := make(map[string]bool, len(fixes))
+	for _, f := range fixes {
+		fixed[f.RuleID] = true
+	}
+	return fixed, nil
+}
+
+// LocalActor names the user running this process in the fix history.
+func LocalActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
+
+// fixTargets lists what an action wrote: files, config keys, registry values
+// and secedit settings. A command's effects are unknown, so it has none.
+func fixTargets(a policy.Action, files []fileCopy) []string {
+	var targets []string
+	switch {
+	case isRegistryAction(a.Type):
+		target := "registry:" + a.RegKey
+		if a.Type != "registry_delete_key" && a.RegValue != "" {
+			target += `\` + a.RegValue
+		}
+		targets = append(targets, target)
+	case a.Type == "secedit":
+		targets = append(targets, "secedit:"+a.RegKey)
+	case a.Type == "config_key":
+		target := "config:" + a.ConfigFormat + ":" + a.ConfigKey
+		if a.ConfigSection != "" {
+			target += " [" + a.ConfigSection + "]"
+		}
+		targets = append(targets, target)
+	}
+	for _, f := range files {
+		targets = append(targets, "file:"+f.path)
+	}
+	return targets
+}
+
+// targetsOverlap reports whether two fix targets touch the same thing. A
+// registry key covers its values and subkeys.
+func targetsOverlap(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if strings.HasPrefix(a, "registry:") && strings.HasPrefix(b, "registry:") {
+		a, b = strings.ToLower(a), strings.ToLower(b)
+		return a == b || strings.HasPrefix(a, b+`\`) || strings.HasPrefix(b, a+`\`)
+	}
+	return false
+}
+
+// ConflictError lists later fixes still in effect that touched the same rule,
+// file or key as the entry being reverted.
+type ConflictError struct {
+	Entry     int64
+	Conflicts []state.HistoryEntry
+}
+
+func (e *ConflictError) Error() string {
+	ids := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		ids[i] = fmt.Sprintf("%d (%s)", c.ID, c.RuleID)
+	}
+	return fmt.Sprintf("entry %d conflicts with later changes: %s", e.Entry, strings.Join(ids, ", "))
+}
+
+// EntryRevert is what RevertEntry did.
+type EntryRevert struct {
+	Entry     state.HistoryEntry   `json:"entry"`
+	Conflicts []state.HistoryEntry `json:"conflicts,omitempty"` // overridden with force
+	Services  []ServiceResult      `json:"services,omitempty"`
+}
+
+// ErrEntryNotFound is returned by RevertEntry for an unknown history id.
+var ErrEntryNotFound = errors.New("history entry not found")
+
+// RevertEntry undoes the change one history entry made, and marks only that
+// entry reverted: registry fixes get back the values they replaced, file fixes
+// the files they wrote (from the backups taken just before). Fixes that kept
+// neither, such as commands, secedit settings and entries logged before
+// backups were recorded, go through the rule's rollback. Later fixes still
+// in effect that touched the same rule, file or key make it fail with a
+// *ConflictError, unless force is set.
+func RevertEntry(ctx context.Context, worker platform.HardenerInterface, pol *policy.Policy, id int64, actor string, force bool) (*EntryRevert, error) {
+	ctx = logging.EnsureID(ctx)
+	entry, err := state.GetHistoryEntry(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, ErrEntryNotFound
+	}
+	if entry.RevertedAt != nil {
+		return nil, fmt.Errorf("entry %d was already reverted", id)
+	}
+	machine := HistoryMachine(ctx, worker)
+	if !entry.OnMachine(machine) {
+		return nil, fmt.Errorf("entry %d was made on another machine", id)
+	}
+	var rule *policy.Rule
+	for i := range pol.Rules {
+		if pol.Rules[i].ID == entry.RuleID {
+			rule = &pol.Rules[i]
+			break
+		}
+	}
+	if rule == nil {
+		return nil, fmt.Errorf("rule %s is not in the current policy", entry.RuleID)
+	}
+
+	later, err := state.LaterFixes(id, machine)
+	if err != nil {
+		return nil, err
+	}
+	result := &EntryRevert{Entry: *entry}
+	for _, l := range later {
+		if l.RuleID == entry.RuleID || anyOverlap(l.Targets, entry.Targets) {
+			result.Conflicts = append(result.Conflicts, l)
+		}
+	}
+	if len(result.Conflicts) > 0 && !force {
+		return result, &ConflictError{Entry: id, Conflicts: result.Conflicts}
+	}
+
+	logger.InfoContext(ctx, "reverting history entry", "entry", id, "rule", entry.RuleID, "actor", actor, "forced", len(result.Conflicts) > 0)
+	if err := revertEntryChange(ctx, worker, *rule, entry); err != nil {
+		return result, err
+	}
+	clearPendingReboot(ctx, worker, *rule)
+	if err := state.MarkEntryReverted(id, actor); err != nil {
+		logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", err)
+	}
+	var queue ServiceQueue
+	queue.Add(rule.ID, revertServices(*rule))
+	result.Services = queue.Run(ctx, worker)
+	return result, nil
+}
+
+// revertEntryChange puts back what entry's fix replaced, falling back to the
+// rule's rollback when the entry kept no copy of it.
+func revertEntryChange(ctx context.Context, worker platform.HardenerInterface, rule policy.Rule, entry *state.HistoryEntry) error {
+	switch {
+	case entry.Snapshot != "":
+		return restoreSnapshot(worker, entry.Snapshot)
+	case len(entry.Backups) > 0:
+		return restoreBackups(ctx, worker, rule.ID, entry.Backups)
+	default:
+		return revertFix(ctx, worker, rule)
+	}
+}
+
+func anyOverlap(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if targetsOverlap(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
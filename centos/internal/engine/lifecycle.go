@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"fmt"
+
+	"sih2025/internal/dag"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+)
+
+// Lifecycle verdicts
+const (
+	LifecycleOK                  = "OK"
+	LifecycleFixIneffective      = "FIX_INEFFECTIVE"
+	LifecycleRollbackIneffective = "ROLLBACK_INEFFECTIVE"
+	LifecycleRollbackDrift       = "ROLLBACK_DRIFT"
+	LifecyclePendingReboot       = "PENDING_REBOOT"
+	LifecycleFixError            = "FIX_ERROR"
+	LifecycleRollbackError       = "ROLLBACK_ERROR"
+	LifecycleNoRollback          = "NO_ROLLBACK"
+	LifecycleSkipped             = "SKIPPED"
+)
+
+// LifecycleResult is the outcome of check -> fix -> check -> rollback -> check
+// for one rule, or of check -> rollback -> check -> fix -> check for a rule
+// that already passes, so its remediation is still exercised.
+type LifecycleResult struct {
+	ID            string      `json:"id"`
+	Name          string      `json:"name"`
+	Verdict       string      `json:"verdict"`
+	Detail        string      `json:"detail,omitempty"`
+	Before        AuditResult `json:"before"`
+	AfterFix      AuditResult `json:"after_fix"`
+	AfterRollback AuditResult `json:"after_rollback"`
+}
+
+// Broken reports whether the rule's remediation or rollback misbehaves.
+// Drift (rollback lands on a different failing value than the original) is a
+// warning, not a failure: rollbacks reset to a fixed default by design. A fix
+// that only takes effect after a reboot can't be judged in one boot.
+func (l LifecycleResult) Broken() bool {
+	switch l.Verdict {
+	case LifecycleOK, LifecycleSkipped, LifecycleRollbackDrift, LifecyclePendingReboot:
+		return false
+	}
+	return true
+}
+
+// VerifyLifecycle runs every rule's full lifecycle through the worker, in
+// dependency order. It really applies fixes and rollbacks, so the worker must
+// be a disposable system (see internal/sandbox). skip may return a reason to
+// leave a rule out, e.g. because it changes state the sandbox can't isolate.
+func VerifyLifecycle(worker platform.HardenerInterface, pol *policy.Policy, skip func(policy.Rule) string) ([]LifecycleResult, error) {
+	layers, err := dag.SortRules(pol.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("dependency cycle: %v", err)
+	}
+
+	var results []LifecycleResult
+	for _, layer := range layers {
+		for _, rule := range layer {
+			logger.Info("verifying rule lifecycle", "rule", rule.ID)
+			results = append(results, verifyRule(worker, rule, skip))
+		}
+	}
+	return results, nil
+}
+
+func verifyRule(worker platform.HardenerInterface, rule policy.Rule, skip func(policy.Rule) string) LifecycleResult {
+	res := LifecycleResult{ID: rule.ID, Name: rule.Name}
+
+	// --- 1. SKIP WHAT CAN'T BE EXERCISED ---
+	if rule.Type == "manual" || rule.Remediation.Type == "manual" || rule.Remediation.Type == "" {
+		res.Verdict = LifecycleSkipped
+		res.Detail = "manual remediation"
+		return res
+	}
+	if skip != nil {
+		if reason := skip(rule); reason != "" {
+			res.Verdict = LifecycleSkipped
+			res.Detail = reason
+			return res
+		}
+	}
+
+	// --- 2. CHECK -> FIX -> CHECK ---
+	res.Before = CheckRule(worker, rule)
+	if res.Before.Status == "NOT_APPLICABLE" {
+		res.Verdict = LifecycleSkipped
+		res.Detail = res.Before.Actual
+		return res
+	}
+	if res.Before.Status == "PASS" {
+		return verifyCompliantRule(worker, rule, res)
+	}
+
+	fix, err := ApplyFixWith(worker, rule)
+	if err != nil {
+		res.Verdict = LifecycleFixError
+		res.Detail = err.Error()
+		return res
+	}
+	res.AfterFix = *fix.Check
+	if fix.Verification == FixPendingReboot {
+		res.Verdict = LifecyclePendingReboot
+		res.Detail = fmt.Sprintf("check reports %s (%s) until the next reboot", res.AfterFix.Status, res.AfterFix.Actual)
+		return res
+	}
+	if fix.Verification != FixVerified {
+		res.Verdict = LifecycleFixIneffective
+		res.Detail = fmt.Sprintf("check still reports %s (%s) after remediation", res.AfterFix.Status, res.AfterFix.Actual)
+		return res
+	}
+
+	// --- 3. ROLLBACK -> CHECK ---
+	if rule.Rollback.Type == "" {
+		res.Verdict = LifecycleNoRollback
+		res.Detail = "rule has no rollback action"
+		return res
+	}
+	if err := RevertFixWith(worker, rule); err != nil {
+		res.Verdict = LifecycleRollbackError
+		res.Detail = err.Error()
+		return res
+	}
+	res.AfterRollback = CheckRule(worker, rule)
+
+	switch {
+	case res.AfterRollback.Status == "PASS":
+		res.Verdict = LifecycleRollbackIneffective
+		res.Detail = "check still passes after rollback"
+	case res.AfterRollback.Status != res.Before.Status:
+		res.Verdict = LifecycleRollbackDrift
+		res.Detail = fmt.Sprintf("status %s before fix, %s after rollback", res.Before.Status, res.AfterRollback.Status)
+	case res.AfterRollback.Actual != res.Before.Actual:
+		res.Verdict = LifecycleRollbackDrift
+		res.Detail = fmt.Sprintf("value %q before fix, %q after rollback", res.Before.Actual, res.AfterRollback.Actual)
+	default:
+		res.Verdict = LifecycleOK
+	}
+	return res
+}
+
+// verifyCompliantRule exercises a rule that already passes, which ApplyFix
+// would leave alone: the rollback first makes it fail, then the fix has to
+// make it pass again. The system ends up as compliant as it started.
+func verifyCompliantRule(worker platform.HardenerInterface, rule policy.Rule, res LifecycleResult) LifecycleResult {
+	// --- 1. ROLLBACK -> CHECK ---
+	if rule.Rollback.Type == "" {
+		res.Verdict = LifecycleNoRollback
+		res.Detail = "rule already passes and has no rollback action to undo it, so the remediation can't be exercised"
+		return res
+	}
+	if err := RevertFixWith(worker, rule); err != nil {
+		res.Verdict = LifecycleRollbackError
+		res.Detail = err.Error()
+		return res
+	}
+	res.AfterRollback = CheckRule(worker, rule)
+	if res.AfterRollback.Status == "PASS" && rule.RequiresReboot {
+		res.Verdict = LifecyclePendingReboot
+		res.Detail = "check passes until the next reboot, so the rollback (and the fix) can't be judged"
+		return res
+	}
+	if res.AfterRollback.Status == "PASS" {
+		res.Verdict = LifecycleRollbackIneffective
+		res.Detail = "check still passes after rollback"
+		return res
+	}
+
+	// --- 2. FIX -> CHECK ---
+	fix, err := ApplyFixWith(worker, rule)
+	if err != nil {
+		res.Verdict = LifecycleFixError
+		res.Detail = err.Error()
+		return res
+	}
+	res.AfterFix = *fix.Check
+	switch {
+	case fix.Verification == FixPendingReboot:
+		res.Verdict = LifecyclePendingReboot
+		res.Detail = fmt.Sprintf("check reports %s (%s) until the next reboot", res.AfterFix.Status, res.AfterFix.Actual)
+	case fix.Verification != FixVerified:
+		res.Verdict = LifecycleFixIneffective
+		res.Detail = fmt.Sprintf("check still reports %s (%s) after remediation", res.AfterFix.Status, res.AfterFix.Actual)
+	default:
+		res.Verdict = LifecycleOK
+	}
+	return res
+}
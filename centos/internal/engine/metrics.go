@@ -0,0 +1,187 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"sih2025/internal/metrics"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+)
+
+var (
+	scansTotal = metrics.NewCounter("sentinelx_scans_total",
+		"Audit runs completed.")
+	scanDuration = metrics.NewHistogram("sentinelx_scan_duration_seconds",
+		"Time taken by an audit run.",
+		[]float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300})
+	lastScan = metrics.NewGauge("sentinelx_last_scan_timestamp_seconds",
+		"Unix time the last audit run finished.")
+
+	checkDuration = metrics.NewHistogram("sentinelx_check_duration_seconds",
+		"Time taken by a single rule check, by rule type.",
+		[]float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}, "type")
+	checkTimeouts = metrics.NewCounter("sentinelx_check_timeouts_total",
+		"Rule checks that hit the check timeout, by rule type.", "type")
+
+	fixesTotal = metrics.NewCounter("sentinelx_fixes_total",
+		"Fixes applied, by outcome: verified, unverified, pending_reboot, unchanged (the rule already passed) or failed.", "outcome")
+	fixDuration = metrics.NewHistogram("sentinelx_fix_duration_seconds",
+		"Time taken to apply (and validate) a single fix.",
+		[]float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60})
+
+	ruleResults = metrics.NewGauge("sentinelx_rule_results",
+		"Rules per status in the last audit run, before waivers.", "status")
+	compliance = metrics.NewGauge("sentinelx_compliance_ratio",
+		"Passing share of the scored rules in the last audit run, before waivers: all but FAIL pass, NOT_APPLICABLE and PENDING_REBOOT are not scored.")
+	complianceBySeverity = metrics.NewGauge("sentinelx_compliance_ratio_by_severity",
+		"sentinelx_compliance_ratio per rule severity.", "severity")
+	complianceByCategory = metrics.NewGauge("sentinelx_compliance_ratio_by_category",
+		"sentinelx_compliance_ratio per rule category (the first tag when a rule has no category).", "category")
+
+	driftedRules = metrics.NewGauge("sentinelx_drifted_rules",
+		"Rules that passed in the previous audit run of the same machine and fail now.")
+	driftTotal = metrics.NewCounter("sentinelx_drift_total",
+		"Rules found drifted from PASS to FAIL, over all audit runs.")
+)
+
+// lastStatuses holds each machine's rule statuses from its previous audit
+// run, for drift.
+var lastStatuses = struct {
+	sync.Mutex
+	byMachine map[string]map[string]string
+}{byMachine: make(map[string]map[string]string)}
+
+// scored is whether a status counts towards compliance, as in the PDF report
+// and stored scans: everything but NOT_APPLICABLE, WAIVED and PENDING_REBOOT.
+// Of those, everything but FAIL passes (a TIMEOUT too).
+func scored(status string) bool {
+	return status != "NOT_APPLICABLE" && status != "WAIVED" && status != "PENDING_REBOOT"
+}
+
+// Score counts the passing and failing scored results. The PDF report and
+// the XCCDF export score with it, so they agree on a scan's compliance.
+func Score(results []AuditResult) (pass, fail int) {
+	for _, res := range results {
+		switch {
+		case !scored(res.Status):
+		case res.Status == "FAIL":
+			fail++
+		default:
+			pass++
+		}
+	}
+	return pass, fail
+}
+
+func ruleCategory(r policy.Rule) string {
+	if r.Category != "" {
+		return r.Category
+	}
+	if len(r.Tags) > 0 {
+		return r.Tags[0]
+	}
+	return "uncategorized"
+}
+
+// recordScan updates the scan metrics from an audit run's results.
+func recordScan(worker platform.HardenerInterface, pol *policy.Policy, results []AuditResult, took time.Duration) {
+	scansTotal.Inc()
+	scanDuration.Observe(took.Seconds())
+	lastScan.Set(float64(time.Now().Unix()))
+
+	rules := make(map[string]policy.Rule, len(pol.Rules))
+	for _, r := range pol.Rules {
+		rules[r.ID] = r
+	}
+
+	var overall tally
+	bySeverity := make(map[string]*tally)
+	byCategory := make(map[string]*tally)
+	statuses := make(map[string]int)
+	for _, res := range results {
+		statuses[res.Status]++
+		if !scored(res.Status) {
+			continue
+		}
+		for _, t := range []*tally{&overall, tallyFor(bySeverity, res.Severity), tallyFor(byCategory, ruleCategory(rules[res.ID]))} {
+			t.total++
+			if res.Status != "FAIL" {
+				t.pass++
+			}
+		}
+	}
+
+	ruleResults.Reset()
+	for status, n := range statuses {
+		ruleResults.Set(float64(n), status)
+	}
+	// A share of nothing is left out rather than reported as NaN
+	compliance.Reset()
+	if overall.total > 0 {
+		compliance.Set(float64(overall.pass) / float64(overall.total))
+	}
+	complianceBySeverity.Reset()
+	for sev, t := range bySeverity {
+		complianceBySeverity.Set(float64(t.pass)/float64(t.total), sev)
+	}
+	complianceByCategory.Reset()
+	for cat, t := range byCategory {
+		complianceByCategory.Set(float64(t.pass)/float64(t.total), cat)
+	}
+
+	recordDrift(worker, results)
+}
+
+// tally counts passing and scored rules.
+type tally struct{ pass, total int }
+
+func tallyFor(m map[string]*tally, key string) *tally {
+	t, ok := m[key]
+	if !ok {
+		t = &tally{}
+		m[key] = t
+	}
+	return t
+}
+
+// recordDrift counts rules that passed in the machine's previous audit run
+// and fail now. Machines are told apart by machine ID where there is one.
+func recordDrift(worker platform.HardenerInterface, results []AuditResult) {
+	machine, _, err := worker.BootID()
+	if err != nil {
+		machine = ""
+	}
+	current := make(map[string]string, len(results))
+	for _, r := range results {
+		current[r.ID] = r.Status
+	}
+
+	lastStatuses.Lock()
+	previous := lastStatuses.byMachine[machine]
+	lastStatuses.byMachine[machine] = current
+	lastStatuses.Unlock()
+
+	drifted := 0
+	for id, status := range current {
+		if status == "FAIL" && previous[id] == "PASS" {
+			drifted++
+		}
+	}
+	driftedRules.Set(float64(drifted))
+	driftTotal.Add(float64(drifted))
+}
+
+// recordFixOutcome counts a fix once its outcome is known: failed, or
+// verified / unverified / pending_reboot after the re-check, or unchanged.
+func recordFixOutcome(result *FixResult, err error) {
+	switch {
+	case err != nil:
+		fixesTotal.Inc("failed")
+	case result.Unchanged:
+		fixesTotal.Inc("unchanged")
+	default:
+		fixesTotal.Inc(strings.ToLower(result.Verification))
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"errors"
+
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/state"
+)
+
+// bootID identifies the machine and its current boot, or ok is false when
+// pending reboots can't be tracked (no database, no running system).
+func bootID(ctx context.Context, worker platform.HardenerInterface) (machine, boot string, ok bool) {
+	if state.DB == nil {
+		return "", "", false
+	}
+	machine, boot, err := worker.BootID()
+	if err != nil {
+		if !errors.Is(err, platform.ErrNotApplicable) {
+			logger.WarnContext(ctx, "could not read boot ID", "err", err)
+		}
+		return "", "", false
+	}
+	return machine, boot, true
+}
+
+// markPendingReboot records that a fix of a requires_reboot rule waits for
+// the next boot. It reports whether the rule is now pending.
+func markPendingReboot(ctx context.Context, worker platform.HardenerInterface, rule policy.Rule) bool {
+	if !rule.RequiresReboot {
+		return false
+	}
+	machine, boot, ok := bootID(ctx, worker)
+	if !ok {
+		return false
+	}
+	if err := state.MarkPendingReboot(machine, rule.ID, boot); err != nil {
+		logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", err)
+		return false
+	}
+	logger.InfoContext(ctx, "fix takes effect after the next reboot", "rule", rule.ID)
+	return true
+}
+
+// clearPendingReboot forgets a pending reboot once the rule is reverted: the
+// running system never saw the change.
+func clearPendingReboot(ctx context.Context, worker platform.HardenerInterface, rule policy.Rule) {
+	if !rule.RequiresReboot {
+		return
+	}
+	machine, _, ok := bootID(ctx, worker)
+	if !ok {
+		return
+	}
+	if _, err := state.ClearPendingReboot(machine, rule.ID); err != nil {
+		logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", err)
+	}
+}
+
+// applyPendingReboots marks results of rules fixed during the current boot as
+// PENDING_REBOOT. Entries from earlier boots are dropped as the audit reads them.
+func applyPendingReboots(ctx context.Context, worker platform.HardenerInterface, results []AuditResult) {
+	machine, boot, ok := bootID(ctx, worker)
+	if !ok {
+		return
+	}
+	pending, err := state.PendingReboots(machine, boot)
+	if err != nil {
+		logger.WarnContext(ctx, "could not load pending reboots", "err", err)
+		return
+	}
+	for i := range results {
+		if _, ok := pending[results[i].ID]; !ok {
+			continue
+		}
+		if results[i].Status == "PASS" || results[i].Status == "FAIL" {
+			results[i].Status = "PENDING_REBOOT"
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/state"
+	"sih2025/internal/winreg"
+)
+
+// isRegistryAction reports whether an action type is applied by applyRegistry.
+func isRegistryAction(actionType string) bool {
+	switch actionType {
+	case "registry", "registry_delete_value", "registry_delete_key":
+		return true
+	}
+	return false
+}
+
+func registryOf(worker platform.HardenerInterface) (winreg.Backend, error) {
+	if b := worker.RegistryBackend(); b != nil {
+		return b, nil
+	}
+	return nil, fmt.Errorf("no registry on %s", worker.GetOSName())
+}
+
+// checkRegistry compares a registry value with the rule's typed expected
+// value (or its Compare condition) and returns the current value, formatted
+// for the report.
+func checkRegistry(worker platform.HardenerInterface, c policy.CheckAction) (bool, string, error) {
+	b, err := registryOf(worker)
+	if err != nil {
+		return false, "", err
+	}
+	if c.Compare != nil {
+		got, err := b.GetValue(c.RegKey, c.RegValue)
+		if errors.Is(err, winreg.ErrNotExist) {
+			return false, "Not Set", nil
+		} else if err != nil {
+			return false, "", err
+		}
+		passed, err := c.Compare.Evaluate(got.Text())
+		return passed, got.Display(), err
+	}
+
+	want, err := winreg.ParseValue(c.Expected, winreg.ValueType(c.RegType))
+	if err != nil {
+		return false, "", err
+	}
+
+	if want.Type == winreg.Absent && c.RegValue == "" {
+		if _, err := b.ListValues(c.RegKey); errors.Is(err, winreg.ErrNotExist) {
+			return true, "Key Not Present", nil
+		} else if err != nil {
+			return false, "", err
+		}
+		return false, "Key Present", nil
+	}
+
+	got, err := b.GetValue(c.RegKey, c.RegValue)
+	if errors.Is(err, winreg.ErrNotExist) {
+		return want.Type == winreg.Absent, "Not Set", nil
+	} else if err != nil {
+		return false, "", err
+	}
+	return want.Equal(got), got.Display(), nil
+}
+
+// applyRegistry runs a registry remediation or rollback.
+func applyRegistry(worker platform.HardenerInterface, a policy.Action) error {
+	b, err := registryOf(worker)
+	if err != nil {
+		return err
+	}
+	switch a.Type {
+	case "registry_delete_value":
+		return b.DeleteValue(a.RegKey, a.RegValue)
+	case "registry_delete_key":
+		return b.DeleteKey(a.RegKey)
+	}
+	v, err := winreg.ParseValue(a.Value, winreg.ValueType(a.RegType))
+	if err != nil {
+		return err
+	}
+	if v.Type == winreg.Absent {
+		return b.DeleteValue(a.RegKey, a.RegValue)
+	}
+	return b.SetValue(a.RegKey, a.RegValue, v)
+}
+
+// captureRegistry snapshots what a registry remediation is about to change.
+// It returns the previous and new values for the history, and the snapshot
+// to store for the rollback (empty when the fix changes nothing, so a later
+// revert still restores the state from before the first fix).
+func captureRegistry(worker platform.HardenerInterface, a policy.Action) (prev, next, snapshot string, err error) {
+	b, err := registryOf(worker)
+	if err != nil {
+		return "", "", "", err
+	}
+	snap, err := winreg.Capture(b, a.RegKey, a.RegValue, a.Type == "registry_delete_key")
+	if err != nil {
+		return "", "", "", err
+	}
+
+	unchanged := false
+	switch {
+	case snap.WholeKey:
+		prev, next = "Key Present", "Key Deleted"
+		if snap.Tree == nil {
+			prev, unchanged = "Key Not Present", true
+		}
+	case a.Type == "registry_delete_value":
+		prev, next, unchanged = "Not Set", "Value Deleted", snap.Value == nil
+	default:
+		want, err := winreg.ParseValue(a.Value, winreg.ValueType(a.RegType))
+		if err != nil {
+			return "", "", "", err
+		}
+		prev, next = "Not Set", want.Display()
+		if want.Type == winreg.Absent {
+			next = "Value Deleted"
+		}
+		unchanged = snap.Value == nil && want.Type == winreg.Absent
+		if snap.Value != nil {
+			unchanged = want.Equal(*snap.Value) && want.Type == snap.Value.Type
+		}
+	}
+	if snap.Value != nil {
+		prev = snap.Value.Display()
+	}
+	if unchanged {
+		return prev, next, "", nil
+	}
+	data, err := json.Marshal(snap)
+	return prev, next, string(data), err
+}
+
+// restoreRegistry reverts a rule to the registry state captured before its
+// first fix on machine. ok is false when there is no snapshot to restore.
+func restoreRegistry(worker platform.HardenerInterface, machine, ruleID string) (ok bool, err error) {
+	data, found := state.GetSnapshot(machine, ruleID)
+	if !found {
+		return false, nil
+	}
+	if err := restoreSnapshot(worker, data); err != nil {
+		return true, err
+	}
+	return true, state.ClearSnapshots(machine, ruleID)
+}
+
+// restoreSnapshot puts back the registry state a captureRegistry snapshot holds.
+func restoreSnapshot(worker platform.HardenerInterface, data string) error {
+	var snap winreg.Snapshot
+	if err := json.Unmarshal([]byte(data), &snap); err != nil {
+		return fmt.Errorf("corrupt registry snapshot: %v", err)
+	}
+	b, err := registryOf(worker)
+	if err != nil {
+		return err
+	}
+	return winreg.Restore(b, &snap)
+}
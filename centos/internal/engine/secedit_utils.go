@@ -3,106 +3,143 @@ package engine
 import (
 	"fmt"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"sih2025/internal/platform"
+	inf "sih2025/internal/secedit"
 )
 
+// tempSeq keeps temp file names unique between managers in one process
+var tempSeq int64
+
+// SecEditManager drives secedit through the worker, so its temp files and
+// commands go to the same backend as the rest of the audit. The policy is
+// exported once and shared by every check made through the manager, so use
+// one manager per audit run.
 type SecEditManager struct {
+	Worker     platform.HardenerInterface
 	ExportPath string
 	ImportPath string
 	DbPath     string
+
+	mu       sync.Mutex
+	exported *inf.Template
+	err      error
 }
 
-func NewSecEditManager() *SecEditManager {
+func NewSecEditManager(worker platform.HardenerInterface) *SecEditManager {
+	base := fmt.Sprintf("sentinelx_%d_%d", os.Getpid(), atomic.AddInt64(&tempSeq, 1))
 	return &SecEditManager{
-		ExportPath: "temp_export.inf",
-		ImportPath: "temp_import.inf",
-		DbPath:     "temp_secedit.sdb", // We use a temp DB to avoid corrupting the real one
+		Worker:     worker,
+		ExportPath: base + "_export.inf",
+		ImportPath: base + "_import.inf",
+		DbPath:     base + ".sdb", // We use a temp DB to avoid corrupting the real one
 	}
 }
 
-// CheckUserRight verifies if a specific user right is set correctly.
-func (s *SecEditManager) CheckUserRight(rightName string, expectedUsers string) (bool, error) {
-	// 1. Export current security policy
-	cmd := exec.Command("secedit", "/export", "/cfg", s.ExportPath, "/areas", "USER_RIGHTS")
-	if err := cmd.Run(); err != nil {
-		return false, fmt.Errorf("secedit export failed: %v", err)
+// Export returns the parsed security policy, running secedit /export on the
+// first call only. A failed export is cached too, so the checks of one run
+// all report the same error instead of retrying it.
+func (s *SecEditManager) Export() (*inf.Template, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exported != nil || s.err != nil {
+		return s.exported, s.err
 	}
-	defer os.Remove(s.ExportPath)
 
-	// 2. Read and Parse
-	data, err := os.ReadFile(s.ExportPath)
+	ok, output, err := s.Worker.RunCommand("secedit", []string{"/export", "/cfg", s.ExportPath, "/areas", "SECURITYPOLICY", "USER_RIGHTS"}, "")
+	if err != nil || !ok {
+		s.err = fmt.Errorf("secedit export failed: %v %s", err, strings.TrimSpace(output))
+		return nil, s.err
+	}
+	defer s.Worker.RemoveFile(s.ExportPath)
+
+	data, err := s.Worker.ReadFile(s.ExportPath)
 	if err != nil {
-		return false, err
+		s.err = err
+		return nil, err
 	}
-	
-	content := string(data)
-	lines := strings.Split(content, "\n")
-	currentVal := ""
-	
-	for _, line := range lines {
-		// Clean the line (handle different encodings/whitespace)
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, rightName) && strings.Contains(line, "=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				currentVal = strings.TrimSpace(parts[1])
-				break
-			}
-		}
+	if s.exported, err = inf.Parse(data); err != nil {
+		s.err = fmt.Errorf("parsing secedit export: %v", err)
 	}
+	return s.exported, s.err
+}
 
-	// 3. Logic Check
-	// If expected is "No One" (empty), and we found nothing or empty string -> PASS
-	if (expectedUsers == "No One" || expectedUsers == "") && (currentVal == "" || currentVal == "failed") {
-		return true, nil
+// Check compares one setting with its expected value. User rights compare
+// as exact account sets (SIDs and names are equivalent, order is ignored);
+// System Access and Event Audit values compare as numbers when both are.
+// actual is the current value, formatted for the report.
+func (s *SecEditManager) Check(key string, expected interface{}) (passed bool, actual string, err error) {
+	tmpl, err := s.Export()
+	if err != nil {
+		return false, "", err
 	}
-	
-	// Check if our expected users are present
-	// We do a simple contains check for the Hackathon
-	if strings.Contains(currentVal, expectedUsers) {
-		return true, nil
+	want := seceditValue(expected)
+
+	if inf.IsPrivilege(key) {
+		current := tmpl.Right(key)
+		return inf.SameAccounts(current, inf.AccountSet(want)), inf.DisplayAccounts(current), nil
 	}
 
-	return false, nil
+	value, _, ok := tmpl.Setting(key)
+	if !ok {
+		return false, "Not Defined", nil
+	}
+	a, errA := strconv.ParseFloat(value, 64)
+	b, errB := strconv.ParseFloat(want, 64)
+	if errA == nil && errB == nil {
+		return a == b, value, nil
+	}
+	return strings.EqualFold(value, strings.TrimSpace(want)), value, nil
 }
 
-// SetUserRight automates the complex process of creating an INF and applying it
-func (s *SecEditManager) SetUserRight(rightName string, users string) error {
-	// 1. Handle "No One" case (Clear the right)
-	valToWrite := users
-	if users == "No One" {
-		valToWrite = "" 
+// CheckUserRight verifies that exactly the expected accounts hold a user right.
+func (s *SecEditManager) CheckUserRight(rightName string, expectedUsers string) (bool, error) {
+	passed, _, err := s.Check(rightName, expectedUsers)
+	return passed, err
+}
+
+// Set applies one setting with secedit /configure, in the section it
+// belongs to. For user rights value is an account list ("No One" clears
+// the right); well-known accounts are written as SIDs.
+func (s *SecEditManager) Set(key, value string) error {
+	section, area := inf.SectionFor(key), "SECURITYPOLICY"
+	if section == inf.SectionPrivilegeRights {
+		value, area = inf.TemplateAccounts(value), "USER_RIGHTS"
 	}
 
-	// 2. Create the INF content
-	// This format is strict Windows Security Template format
-	infContent := fmt.Sprintf(`
-[Unicode]
-Unicode=yes
-[Privilege Rights]
-%s = %s
-[Version]
-signature="$CHICAGO$"
-Revision=1
-`, rightName, valToWrite)
-
-	// 3. Write INF file
-	if err := os.WriteFile(s.ImportPath, []byte(infContent), 0644); err != nil {
+	if err := s.Worker.WriteFile(s.ImportPath, inf.Render(section, key, value), 0644); err != nil {
 		return fmt.Errorf("failed to write temp INF: %v", err)
 	}
-	defer os.Remove(s.ImportPath)
-	defer os.Remove(s.DbPath) // Clean up the temp database
+	defer s.Worker.RemoveFile(s.ImportPath)
+	defer s.Worker.RemoveFile(s.DbPath) // Clean up the temp database
 
-	// 4. Apply using secedit /configure
 	// /db is required, so we generate a temp one
-	cmd := exec.Command("secedit", "/configure", "/db", s.DbPath, "/cfg", s.ImportPath, "/areas", "USER_RIGHTS")
-	
-	// Capture output for debugging
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("secedit configure failed: %s", string(output))
-	}
+	ok, output, err := s.Worker.RunCommand("secedit", []string{"/configure", "/db", s.DbPath, "/cfg", s.ImportPath, "/areas", area}, "")
 
+	// The next check has to see the new value
+	s.mu.Lock()
+	s.exported, s.err = nil, nil
+	s.mu.Unlock()
+
+	if err != nil || !ok {
+		return fmt.Errorf("secedit configure failed: %s", output)
+	}
 	return nil
-}
\ No newline at end of file
+}
+
+// SetUserRight assigns a user right to exactly the given accounts.
+func (s *SecEditManager) SetUserRight(rightName string, users string) error {
+	return s.Set(rightName, users)
+}
+
+// seceditValue formats a policy value (string, number or null) for secedit.
+func seceditValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
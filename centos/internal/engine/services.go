@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/state"
+)
+
+// Service action outcomes
+const (
+	ServiceOK      = "OK"
+	ServiceFailed  = "FAILED"
+	ServiceSkipped = "SKIPPED"
+)
+
+// ServiceResult is one service action run after a batch of fixes, with the
+// rules that asked for it.
+type ServiceResult struct {
+	Name   string   `json:"name,omitempty"`
+	Action string   `json:"action"`
+	Rules  []string `json:"rules"`
+	Status string   `json:"status"`
+	Output string   `json:"output,omitempty"`
+}
+
+// ServiceQueue collects the service actions of a batch of fixes, so each one
+// runs once at the end. A restart covers a reload of the same service.
+type ServiceQueue struct {
+	mu      sync.Mutex
+	pending map[string]*ServiceResult // by service name, or action for the nameless ones
+}
+
+// Add queues the service actions of a rule whose change was applied.
+func (q *ServiceQueue) Add(ruleID string, actions []policy.ServiceAction) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pending == nil {
+		q.pending = make(map[string]*ServiceResult)
+	}
+	for _, a := range actions {
+		key := a.Action
+		if a.Name != "" {
+			key = "unit:" + a.Name
+		}
+		p, ok := q.pending[key]
+		if !ok {
+			p = &ServiceResult{Name: a.Name, Action: a.Action}
+			q.pending[key] = p
+		}
+		if policy.ServiceRank(a.Action) > policy.ServiceRank(p.Action) {
+			p.Action = a.Action // reload -> restart
+		}
+		if !containsString(p.Rules, ruleID) {
+			p.Rules = append(p.Rules, ruleID)
+		}
+	}
+}
+
+// Run runs the queued actions in policy.ServiceRank order, records them in state and empties
+// the queue. It logs under ctx's correlation ID.
+func (q *ServiceQueue) Run(ctx context.Context, worker platform.HardenerInterface) []ServiceResult {
+	q.mu.Lock()
+	var list []ServiceResult
+	for _, p := range q.pending {
+		list = append(list, *p)
+	}
+	q.pending = nil
+	q.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if ri, rj := policy.ServiceRank(list[i].Action), policy.ServiceRank(list[j].Action); ri != rj {
+			return ri < rj
+		}
+		return list[i].Name < list[j].Name
+	})
+
+	for i := range list {
+		s := &list[i]
+		s.Status, s.Output = runService(worker, s.Name, s.Action)
+		level := slog.LevelInfo
+		if s.Status == ServiceFailed {
+			level = slog.LevelWarn
+		}
+		logger.Log(ctx, level, "service action", "action", s.Action, "service", s.Name, "rules", strings.Join(s.Rules, ","), "status", s.Status, "output", s.Output)
+		if state.DB == nil {
+			continue
+		}
+		if err := state.LogServiceAction(s.Name, s.Action, strings.Join(s.Rules, ","), s.Status, s.Output); err != nil {
+			logger.ErrorContext(ctx, "state update failed", "err", err)
+		}
+	}
+	return list
+}
+
+// serviceCommand is the command for a service action on the worker's OS;
+// empty when the action means nothing there.
+func serviceCommand(osName, name, action string) (string, []string) {
+	if osName == "windows" {
+		if action == policy.ServiceReload || action == policy.ServiceRestart {
+			return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", "Restart-Service -Force -Name '" + strings.ReplaceAll(name, "'", "''") + "'"}
+		}
+		return "", nil
+	}
+	switch action {
+	case policy.ServiceDaemonReload:
+		return "systemctl", []string{"daemon-reload"}
+	case policy.ServiceSysctl:
+		return "sysctl", []string{"--system"}
+	}
+	return "systemctl", []string{action, name}
+}
+
+func runService(worker platform.HardenerInterface, name, action string) (status, output string) {
+	cmd, args := serviceCommand(worker.GetOSName(), name, action)
+	if cmd == "" {
+		return ServiceSkipped, "not applicable on " + worker.GetOSName()
+	}
+	// The Windows backend reports a failed command through success, not err
+	success, output, err := worker.RunCommand(cmd, args, "")
+	output = strings.TrimSpace(output)
+	switch {
+	case errors.Is(err, platform.ErrNotApplicable):
+		return ServiceSkipped, "requires a running system"
+	case err != nil || !success:
+		if output == "" && err != nil {
+			output = err.Error()
+		}
+		return ServiceFailed, output
+	}
+	return ServiceOK, output
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
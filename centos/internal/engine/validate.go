@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"sih2025/internal/confedit"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+)
+
+// FixResult is what ApplyFix did beyond making the change, and whether it took.
+type FixResult struct {
+	RuleID     string `json:"id"`
+	TxID       string `json:"tx_id,omitempty"`      // transaction the fix was logged under
+	HistoryID  int64  `json:"history_id,omitempty"` // its fix history (rollback_log) entry
+	PreStatus  string `json:"pre_status,omitempty"` // the rule's check status before the fix
+	Unchanged  bool   `json:"unchanged,omitempty"`  // the rule already passed, so nothing was changed or logged
+	Validation string `json:"validation,omitempty"` // output of the remediation's validate command
+	Validated  bool   `json:"validated"`            // validate ran and passed
+	Reverted   bool   `json:"reverted,omitempty"`   // validate failed and the change was undone
+
+	RebootPending bool `json:"reboot_pending,omitempty"` // the fix takes effect after the next reboot
+
+	// Verification is VERIFIED when the rule's check passes after the fix
+	// (and its service reloads), PENDING_REBOOT when it can't until the next
+	// reboot, else UNVERIFIED; Check is that check's result
+	Verification string       `json:"verification,omitempty"`
+	Check        *AuditResult `json:"check,omitempty"`
+
+	Error    string          `json:"error,omitempty"`    // set by ApplyFixes when the fix failed
+	Services []ServiceResult `json:"services,omitempty"` // set by ApplyFixWith; ApplyFixes returns them per batch
+}
+
+// validationFile is what "{file}" stands for in an action's validate args.
+func validationFile(a policy.Action) string {
+	if a.Type == "config_key" && a.FilePath == "" {
+		return confedit.DefaultFile(a.ConfigFormat)
+	}
+	return a.FilePath
+}
+
+// runValidation runs an action's validate command. ok is false only when it
+// ran and failed; without a running system it is skipped.
+func runValidation(worker platform.HardenerInterface, a policy.Action) (ran, ok bool, output string) {
+	v := a.Validate
+	args := make([]string, len(v.Args))
+	for i, arg := range v.Args {
+		args[i] = strings.ReplaceAll(arg, "{file}", validationFile(a))
+	}
+	// The Windows backend reports a failed command through success, not err
+	success, output, err := worker.RunCommand(v.Cmd, args, "")
+	if errors.Is(err, platform.ErrNotApplicable) {
+		return false, true, "skipped: requires a running system"
+	}
+	output = strings.TrimSpace(output)
+	if err != nil && output == "" {
+		output = err.Error()
+	}
+	return true, success && err == nil, output
+}
+
+// undoFix restores the state from before a fix whose validation failed: file
+// contents and registry snapshots taken during the fix, otherwise the rule's
+// rollback action.
+func undoFix(ctx context.Context, worker platform.HardenerInterface, rule policy.Rule, files []fileCopy, snapshot string) error {
+	switch {
+	case isRegistryAction(rule.Remediation.Type):
+		if snapshot == "" {
+			return nil // the fix changed nothing
+		}
+		return restoreSnapshot(worker, snapshot)
+	case rule.Remediation.Type == "file_edit" || rule.Remediation.Type == "file_append" || rule.Remediation.Type == "config_key":
+		return restoreFiles(worker, files)
+	case rule.Rollback.Type == "":
+		return fmt.Errorf("rule has no rollback action")
+	}
+	return revertFix(ctx, worker, rule)
+}
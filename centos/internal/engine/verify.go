@@ -0,0 +1,46 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/state"
+)
+
+// Post-fix verification outcomes
+const (
+	FixVerified      = "VERIFIED"       // the rule's check passes after the fix
+	FixUnverified    = "UNVERIFIED"     // the fix ran, but the check still doesn't pass
+	FixPendingReboot = "PENDING_REBOOT" // the check doesn't pass yet, and can't until the next reboot
+)
+
+// verifyFix re-runs the rule's check once its fix (and service reloads) are
+// done, since a remediation exiting 0 doesn't mean it changed anything. The
+// outcome is stored with the fix record. A failing check on a fix that is
+// waiting for a reboot says nothing about the fix yet, so it isn't UNVERIFIED.
+func verifyFix(ctx context.Context, worker platform.HardenerInterface, rule policy.Rule, result *FixResult) {
+	check := checkRule(ctx, worker, NewSecEditManager(worker), rule)
+	result.Check = &check
+	switch {
+	case check.Status == "PASS":
+		result.Verification = FixVerified
+	case result.RebootPending:
+		result.Verification = FixPendingReboot
+	default:
+		result.Verification = FixUnverified
+	}
+	level := slog.LevelInfo
+	if result.Verification == FixUnverified {
+		level = slog.LevelWarn
+	}
+	logger.Log(ctx, level, "fix verified", "rule", rule.ID, "verification", result.Verification, "status", check.Status, "actual", check.Actual)
+
+	if state.DB == nil {
+		return
+	}
+	if err := state.RecordVerification(result.HistoryID, result.Verification, check.Actual); err != nil {
+		logger.ErrorContext(ctx, "state update failed", "rule", rule.ID, "err", err)
+	}
+}
@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"time"
+
+	"sih2025/internal/state"
+)
+
+// ApplyWaivers marks failing results covered by an active waiver for host or
+// profile as WAIVED. Results whose waiver has expired stay FAIL but keep the
+// waiver attached, so reports can show that the acceptance lapsed.
+func ApplyWaivers(results []AuditResult, host, profile string) []AuditResult {
+	waivers, err := state.WaiversFor(host, profile)
+	if err != nil {
+		logger.Warn("could not load waivers", "err", err)
+		return results
+	}
+
+	now := time.Now()
+	for i := range results {
+		w, ok := waivers[results[i].ID]
+		if !ok || results[i].Status != "FAIL" {
+			continue
+		}
+		results[i].Waiver = &w
+		if !w.Expired(now) {
+			results[i].Status = "WAIVED"
+		}
+	}
+	return results
+}
@@ -0,0 +1,93 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"sih2025/internal/logging"
+)
+
+// Agent pushes scan results to a collector over HTTPS.
+type Agent struct {
+	CollectorURL string
+	Token        string
+	Client       *http.Client
+}
+
+// NewAgent builds an agent. caFile pins the collector's certificate (e.g. the
+// self-signed one it generated); insecure skips verification for local
+// testing, but the connection is still TLS.
+func NewAgent(collectorURL, token, caFile string, insecure bool) (*Agent, error) {
+	if !strings.HasPrefix(collectorURL, "https://") {
+		return nil, fmt.Errorf("collector URL must use https:// (got %s)", collectorURL)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &Agent{
+		CollectorURL: strings.TrimRight(collectorURL, "/"),
+		Token:        token,
+		Client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+// Push sends one scan run and returns the collector's run ID. ctx's
+// correlation ID goes along as X-Request-ID.
+func (a *Agent) Push(ctx context.Context, sub Submission) (int64, error) {
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.CollectorURL+"/fleet/api/runs", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id := logging.ID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("push failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusCreated {
+		return 0, fmt.Errorf("collector returned %s: %s", resp.Status, strings.TrimSpace(string(raw)))
+	}
+	var out struct {
+		RunID int64 `json:"run_id"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return 0, fmt.Errorf("unexpected collector response: %v", err)
+	}
+	return out.RunID, nil
+}
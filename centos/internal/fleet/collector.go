@@ -0,0 +1,206 @@
+package fleet
+
+import (
+	"crypto/subtle"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/logging"
+	"sih2025/internal/report"
+	"sih2025/internal/state"
+
+	"github.com/gin-gonic/gin"
+)
+
+var logger = logging.Logger("collector")
+
+// RegisterRoutes mounts the collector on r. Agents push to POST /fleet/api/runs
+// with "Authorization: Bearer <token>"; the read-only views are under /fleet
+// and need viewerToken (token when empty), as a bearer token or as the
+// password of HTTP basic auth so a browser can open the dashboard.
+func RegisterRoutes(r *gin.Engine, token, viewerToken string) {
+	if viewerToken == "" {
+		viewerToken = token
+	}
+	viewer := requireViewer(viewerToken)
+	r.GET("/fleet", viewer, func(c *gin.Context) { c.HTML(200, "fleet.html", nil) })
+
+	api := r.Group("/fleet/api")
+	{
+		// 1. INGEST (Agents only)
+		api.POST("/runs", requireToken(token), func(c *gin.Context) {
+			var sub Submission
+			if err := c.BindJSON(&sub); err != nil {
+				c.JSON(400, gin.H{"error": "Invalid submission"})
+				return
+			}
+			sub.Host = strings.TrimSpace(sub.Host)
+			if sub.Host == "" {
+				c.JSON(400, gin.H{"error": "Missing host"})
+				return
+			}
+			if sub.ScannedAt.IsZero() {
+				sub.ScannedAt = time.Now()
+			}
+
+			// Waivers are granted centrally: ignore any the agent claims and
+			// apply the collector's own
+			for i := range sub.Results {
+				if sub.Results[i].Status == "WAIVED" {
+					sub.Results[i].Status = "FAIL"
+					sub.Results[i].Waiver = nil
+				}
+			}
+			sub.Results = engine.ApplyWaivers(sub.Results, sub.Host, sub.Profile)
+
+			run, rows := sub.toState()
+			runID, err := state.SaveFleetRun(run, rows)
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			logger.InfoContext(c.Request.Context(), "run stored", "run", runID, "host", sub.Host, "pass", run.Pass, "total", run.Total)
+			c.JSON(201, gin.H{"status": "stored", "run_id": runID})
+		})
+
+		// 2. FLEET-WIDE SUMMARY
+		api.GET("/summary", viewer, func(c *gin.Context) {
+			hosts, err := state.ListFleetHosts()
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			failures, err := state.TopFleetFailures(20)
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+
+			pass, total, compliant := 0, 0, 0
+			for _, h := range hosts {
+				pass += h.Pass
+				total += h.Total
+				if h.Fail == 0 {
+					compliant++
+				}
+			}
+			overall := 0
+			if total > 0 {
+				overall = int((float64(pass) / float64(total) * 100) + 0.5)
+			}
+			c.JSON(200, gin.H{
+				"hosts":           len(hosts),
+				"compliant_hosts": compliant,
+				"compliance":      overall,
+				"top_failures":    failures,
+			})
+		})
+
+		// 3. HOST LIST (Latest run per host)
+		api.GET("/hosts", viewer, func(c *gin.Context) {
+			hosts, err := state.ListFleetHosts()
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			out := make([]gin.H, 0, len(hosts))
+			for _, h := range hosts {
+				out = append(out, gin.H{"latest": h, "compliance": Compliance(h)})
+			}
+			c.JSON(200, gin.H{"hosts": out})
+		})
+
+		// 4. HOST DRILL-DOWN (Run history)
+		api.GET("/hosts/:host/runs", viewer, func(c *gin.Context) {
+			limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+			runs, err := state.ListFleetRuns(c.Param("host"), limit)
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(200, gin.H{"runs": runs})
+		})
+
+		// 5. RUN DETAIL
+		api.GET("/runs/:id", viewer, func(c *gin.Context) {
+			runID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "Invalid run id"})
+				return
+			}
+			run, results, err := state.GetFleetRun(runID)
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			if run == nil {
+				c.JSON(404, gin.H{"error": "Run not found"})
+				return
+			}
+			c.JSON(200, gin.H{"run": run, "results": results})
+		})
+
+		// 6. AGGREGATED REPORT
+		api.GET("/report", viewer, func(c *gin.Context) {
+			hosts, err := state.ListFleetHosts()
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			failures, err := state.TopFleetFailures(25)
+			if err != nil {
+				c.JSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			filename, err := report.GenerateFleetReport(hosts, failures)
+			if err != nil {
+				c.JSON(500, gin.H{"error": "Failed to generate PDF"})
+				return
+			}
+			c.Header("Content-Disposition", "attachment; filename="+filepath.Base(filename))
+			c.Header("Content-Type", "application/pdf")
+			c.File(filename)
+		})
+	}
+}
+
+// requireViewer rejects requests that carry the viewer token neither as a
+// bearer token nor as a basic auth password (any user name), and asks
+// browsers to prompt for it.
+func requireViewer(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if _, password, ok := c.Request.BasicAuth(); ok {
+			got = password
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="SentinelX fleet"`)
+			c.AbortWithStatusJSON(401, gin.H{"error": "Invalid viewer token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireToken rejects requests that don't carry the shared agent token.
+func requireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(401, gin.H{"error": "Invalid agent token"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,56 @@
+package fleet
+
+import (
+	"time"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/state"
+)
+
+// Submission is the payload an agent pushes to the collector after a scan.
+type Submission struct {
+	Host      string               `json:"host"`
+	OS        string               `json:"os"`
+	Profile   string               `json:"profile"`
+	ScannedAt time.Time            `json:"scanned_at"`
+	Results   []engine.AuditResult `json:"results"`
+}
+
+// toState splits a submission into the run summary and rows stored by state.
+func (s Submission) toState() (state.FleetRun, []state.FleetResult) {
+	run := state.FleetRun{
+		Host:      s.Host,
+		OS:        s.OS,
+		Profile:   s.Profile,
+		ScannedAt: s.ScannedAt,
+	}
+	rows := make([]state.FleetResult, 0, len(s.Results))
+	for _, r := range s.Results {
+		switch r.Status {
+		case "NOT_APPLICABLE", "WAIVED", "PENDING_REBOOT":
+			// Not scored
+		case "FAIL":
+			run.Fail++
+		default:
+			run.Pass++
+		}
+		rows = append(rows, state.FleetResult{
+			RuleID:   r.ID,
+			Name:     r.Name,
+			Severity: r.Severity,
+			Status:   r.Status,
+			Actual:   r.Actual,
+			Expected: r.Expected,
+		})
+	}
+	run.Total = run.Pass + run.Fail
+	return run, rows
+}
+
+// Compliance returns the pass percentage of a run, matching the PDF report.
+func Compliance(run state.FleetRun) int {
+	if run.Total == 0 {
+		return 0
+	}
+	return int((float64(run.Pass) / float64(run.Total) * 100) + 0.5)
+}
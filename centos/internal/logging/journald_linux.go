@@ -0,0 +1,133 @@
+//go:build linux
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// journalSocket is where systemd-journald takes native protocol datagrams.
+const journalSocket = "/run/systemd/journal/socket"
+
+// journalHandler sends each record to journald as structured fields:
+// MESSAGE, PRIORITY, SYSLOG_IDENTIFIER plus one field per attribute
+// (correlation_id becomes CORRELATION_ID).
+type journalHandler struct {
+	conn   *net.UnixConn
+	opts   *slog.HandlerOptions
+	ident  string
+	attrs  []slog.Attr
+	prefix string // group names, joined with "_"
+}
+
+func newJournalHandler(opts *slog.HandlerOptions) (slog.Handler, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("journald not available: %v", err)
+	}
+	return &journalHandler{conn: conn, opts: opts, ident: filepath.Base(os.Args[0])}, nil
+}
+
+func (h *journalHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+func (h *journalHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", r.Message)
+	writeJournalField(&buf, "PRIORITY", journalPriority(r.Level))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", h.ident)
+	for _, a := range h.attrs {
+		writeJournalAttr(&buf, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeJournalAttr(&buf, h.prefix, a)
+		return true
+	})
+	_, err := h.conn.Write(buf.Bytes())
+	return err
+}
+
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := *h
+	out.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		out.attrs = append(out.attrs, slog.Attr{Key: h.prefix + a.Key, Value: a.Value})
+	}
+	return &out
+}
+
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	out := *h
+	out.prefix = h.prefix + name + "_"
+	return &out
+}
+
+func journalPriority(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "3"
+	case level >= slog.LevelWarn:
+		return "4"
+	case level >= slog.LevelInfo:
+		return "6"
+	}
+	return "7"
+}
+
+func writeJournalAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, ga := range v.Group() {
+			writeJournalAttr(buf, prefix+a.Key+"_", ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	value := v.String()
+	if v.Kind() == slog.KindTime {
+		value = v.Time().Format(time.RFC3339Nano)
+	}
+	writeJournalField(buf, journalFieldName(prefix+a.Key), value)
+}
+
+// journalFieldName upper-cases a key and replaces what journald doesn't allow
+// in field names. Names starting with "_" are reserved for journald itself.
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		name = "X" + name
+	}
+	return name
+}
+
+// writeJournalField appends KEY=value, or the length-prefixed binary form
+// when the value spans lines.
+func writeJournalField(buf *bytes.Buffer, key, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(key + "=" + value + "\n")
+		return
+	}
+	buf.WriteString(key + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+func newJournalHandler(opts *slog.HandlerOptions) (slog.Handler, error) {
+	return nil, fmt.Errorf("journald output is only available on Linux")
+}
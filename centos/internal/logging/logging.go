@@ -0,0 +1,163 @@
+// Package logging sets up the process-wide slog logger (level, text or JSON,
+// stderr, a rotating file or journald) and carries correlation IDs through
+// contexts, so one request or scan can be followed across components.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options choose where and how records are written.
+type Options struct {
+	Level     string // debug, info, warn or error
+	Format    string // text or json; journald keeps its own fields
+	Output    string // stderr, stdout, file or journald
+	File      string // log file, for Output "file"
+	MaxSizeMB int    // rotate the file past this size
+	MaxFiles  int    // rotated files kept
+}
+
+// Setup installs the default slog logger. The standard log package goes
+// through it as well. An open log file or journal socket stays open for the
+// life of the process.
+func Setup(o Options) error {
+	level, err := ParseLevel(o.Level)
+	if err != nil {
+		return err
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch o.Output {
+	case "journald":
+		if handler, err = newJournalHandler(handlerOpts); err != nil {
+			return err
+		}
+	case "", "stderr", "stdout", "file":
+		var w io.Writer = os.Stderr
+		if o.Output == "stdout" {
+			w = os.Stdout
+		}
+		if o.Output == "file" {
+			if w, err = OpenRotatingFile(o.File, int64(o.MaxSizeMB)<<20, o.MaxFiles); err != nil {
+				return err
+			}
+		}
+		switch o.Format {
+		case "", "text":
+			handler = slog.NewTextHandler(w, handlerOpts)
+		case "json":
+			handler = slog.NewJSONHandler(w, handlerOpts)
+		default:
+			return fmt.Errorf("unknown log format %q (want text or json)", o.Format)
+		}
+	default:
+		return fmt.Errorf("unknown log output %q (want stderr, stdout, file or journald)", o.Output)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	return nil
+}
+
+// ParseLevel reads debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+	}
+	return level, nil
+}
+
+// --- CORRELATION IDS ---
+
+type idKey struct{}
+
+// NewID returns a random correlation ID.
+func NewID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithID returns ctx carrying the correlation ID id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// ID returns the correlation ID carried by ctx, if any.
+func ID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(idKey{}).(string)
+	return id
+}
+
+// EnsureID returns ctx with a correlation ID, adding a new one when ctx has
+// none (e.g. a scan started outside any request).
+func EnsureID(ctx context.Context) context.Context {
+	if ID(ctx) != "" {
+		return ctx
+	}
+	return WithID(ctx, NewID())
+}
+
+// contextHandler adds the context's correlation ID to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := ID(ctx); id != "" {
+		r.AddAttrs(slog.String("correlation_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// --- COMPONENT LOGGERS ---
+
+// Logger returns a logger tagging records with component. It writes through
+// whatever logger is the default at the time, so package-level loggers
+// created before Setup pick it up.
+func Logger(component string) *slog.Logger {
+	return slog.New(lazyHandler{attrs: []slog.Attr{slog.String("component", component)}})
+}
+
+type lazyHandler struct {
+	attrs []slog.Attr
+}
+
+func (h lazyHandler) current() slog.Handler {
+	return slog.Default().Handler().WithAttrs(h.attrs)
+}
+
+func (h lazyHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slog.Default().Handler().Enabled(ctx, level)
+}
+
+func (h lazyHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.current().Handle(ctx, r)
+}
+
+func (h lazyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return lazyHandler{attrs: append(h.attrs[:len(h.attrs):len(h.attrs)], attrs...)}
+}
+
+func (h lazyHandler) WithGroup(name string) slog.Handler {
+	return h.current().WithGroup(name)
+}
@@ -0,0 +1,66 @@
+package platform
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// evaluateOutput turns a finished command into the (success, output, error)
+// triple of RunCommand. exitCode is -1 when the command didn't start or
+// didn't exit normally. Shared by every Unix-like backend.
+func evaluateOutput(cmdStr string, outStr string, exitCode int, runErr error, expectPattern string) (bool, string, error) {
+	// 1. Audit Mode (Checking for a pattern)
+	if expectPattern != "" {
+		// Handle common grep exit code 1 (not found) gracefully
+		if runErr != nil && exitCode == 1 && strings.Contains(cmdStr, "grep") {
+			return false, "Pattern Not Found", nil
+		}
+
+		// Check for match
+		matched, _ := regexp.MatchString(expectPattern, outStr)
+		if matched || strings.Contains(outStr, expectPattern) {
+			// Return TRUE and the ACTUAL OUTPUT found
+			return true, outStr, nil
+		}
+		// Return FALSE and the OUTPUT (so we know what wrong value was found)
+		if outStr == "" {
+			outStr = "Empty Output"
+		}
+		return false, outStr, nil
+	}
+
+	// 2. Action Mode (Running a fix/command)
+	if runErr != nil {
+		return false, outStr, fmt.Errorf("execution failed: %v | output: %s", runErr, outStr)
+	}
+
+	return true, outStr, nil
+}
+
+// applyConfigEdit replaces every line matching searchRegex with replaceText,
+// or appends replaceText when nothing matches.
+func applyConfigEdit(text string, searchRegex string, replaceText string) (string, error) {
+	re, err := regexp.Compile("(?m)" + searchRegex)
+	if err != nil {
+		return "", fmt.Errorf("invalid search regex: %v", err)
+	}
+
+	if re.MatchString(text) {
+		return re.ReplaceAllString(text, replaceText), nil
+	}
+	if len(text) > 0 && !strings.HasSuffix(text, "\n") {
+		return text + "\n" + replaceText + "\n", nil
+	}
+	return text + replaceText + "\n", nil
+}
+
+// backupName is where a backup of path taken at t goes under dir: the same
+// path below dir, with a timestamp suffix ("/etc/ssh/sshd_config" ->
+// dir/etc/ssh/sshd_config.20261018-153000.000000).
+func backupName(dir string, path string, t time.Time) string {
+	path = strings.TrimPrefix(filepath.ToSlash(path), filepath.VolumeName(path))
+	return filepath.Join(dir, filepath.FromSlash(path)) + "." + t.Format("20060102-150405.000000")
+}
@@ -0,0 +1,44 @@
+package platform
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+)
+
+// Factory builds a platform backend.
+type Factory func() (HardenerInterface, error)
+
+var factories = map[string]Factory{
+	"native": func() (HardenerInterface, error) { return getPlatformInstance(), nil },
+	"fake":   func() (HardenerInterface, error) { return NewFakeHardener(runtime.GOOS), nil },
+}
+
+// RegisterFactory makes a backend selectable by name, e.g. from tests.
+func RegisterFactory(name string, f Factory) {
+	factories[name] = f
+}
+
+// NewPlatform builds the named backend without installing it.
+func NewPlatform(name string) (HardenerInterface, error) {
+	f, ok := factories[name]
+	if !ok {
+		names := make([]string, 0, len(factories))
+		for n := range factories {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown platform %q (available: %v)", name, names)
+	}
+	return f()
+}
+
+// UsePlatform builds the named backend and makes it the one GetPlatform returns.
+func UsePlatform(name string) (HardenerInterface, error) {
+	h, err := NewPlatform(name)
+	if err != nil {
+		return nil, err
+	}
+	SetPlatform(h)
+	return h, nil
+}
@@ -0,0 +1,420 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sih2025/internal/secedit"
+	"sih2025/internal/winreg"
+)
+
+// FakeFile is one entry of the FakeHardener's in-memory filesystem.
+type FakeFile struct {
+	Data  []byte
+	Mode  os.FileMode
+	Owner string
+	Group string
+}
+
+// FakeCommand is a scripted response for one command line.
+type FakeCommand struct {
+	Output   string
+	ExitCode int
+	// Effect runs after the command, e.g. to flip a file so a later check passes
+	Effect func(f *FakeHardener)
+}
+
+// FakeHardener is an in-memory platform for deterministic policy tests: files,
+// command responses, registry values and secedit user rights are all scripted,
+// so the Linux and Windows code paths of the engine run on any machine.
+type FakeHardener struct {
+	mu sync.Mutex
+
+	OS       string
+	Files    map[string]*FakeFile
+	Commands map[string]FakeCommand // keyed by FakeCommandLine(cmd, args)
+	Registry *winreg.Memory
+	Rights   map[string]string // secedit privilege rights, e.g. SeDenyNetworkLogonRight = *S-1-5-32-546
+	Security map[string]string // secedit [System Access] and [Event Audit] values, e.g. MinimumPasswordLength = 14
+
+	// MachineID and Boot are what BootID reports; change Boot to simulate a reboot
+	MachineID string
+	Boot      string
+
+	// Calls records every command line that was run, in order
+	Calls []string
+}
+
+// NewFakeHardener returns an empty fake that behaves like osName ("linux" or "windows").
+func NewFakeHardener(osName string) *FakeHardener {
+	return &FakeHardener{
+		OS:        osName,
+		Files:     make(map[string]*FakeFile),
+		Commands:  make(map[string]FakeCommand),
+		Registry:  winreg.NewMemory(),
+		Rights:    make(map[string]string),
+		Security:  make(map[string]string),
+		MachineID: "fake-machine",
+		Boot:      "fake-boot-1",
+	}
+}
+
+// FakeCommandLine is the key used to script a command.
+func FakeCommandLine(cmdStr string, args []string) string {
+	return strings.Join(append([]string{cmdStr}, args...), " ")
+}
+
+// SetFile creates or replaces a file.
+func (f *FakeHardener) SetFile(path string, content string, mode os.FileMode) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Files[path] = &FakeFile{Data: []byte(content), Mode: mode, Owner: "root", Group: "root"}
+}
+
+// FileContent returns a file's content and whether it exists.
+func (f *FakeHardener) FileContent(path string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, ok := f.Files[path]
+	if !ok {
+		return "", false
+	}
+	return string(file.Data), true
+}
+
+// OnCommand scripts the output and exit code of a command line.
+func (f *FakeHardener) OnCommand(cmdStr string, args []string, output string, exitCode int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Commands[FakeCommandLine(cmdStr, args)] = FakeCommand{Output: output, ExitCode: exitCode}
+}
+
+func (f *FakeHardener) GetOSName() string {
+	return f.OS
+}
+
+// RunCommand replays the scripted response. secedit and grep are emulated
+// against Rights and Files; anything else unscripted behaves like
+// "command not found".
+func (f *FakeHardener) RunCommand(cmdStr string, args []string, expectPattern string) (bool, string, error) {
+	line := FakeCommandLine(cmdStr, args)
+
+	f.mu.Lock()
+	f.Calls = append(f.Calls, line)
+	script, ok := f.Commands[line]
+	f.mu.Unlock()
+
+	if !ok && strings.EqualFold(cmdStr, "secedit") {
+		script = f.secedit(args)
+		ok = true
+	}
+	if !ok && cmdStr == "grep" {
+		script, ok = f.grep(args)
+	}
+	if !ok {
+		script = FakeCommand{Output: fmt.Sprintf("%s: command not scripted", cmdStr), ExitCode: 127}
+	}
+	if script.Effect != nil {
+		script.Effect(f)
+	}
+
+	var runErr error
+	if script.ExitCode != 0 {
+		runErr = fmt.Errorf("exit status %d", script.ExitCode)
+	}
+	outStr := strings.TrimSpace(script.Output)
+
+	// Same result semantics as the real backends
+	if f.OS == "windows" {
+		if runErr != nil {
+			return false, outStr, nil
+		}
+		return strings.Contains(outStr, expectPattern), outStr, nil
+	}
+	return evaluateOutput(cmdStr, outStr, script.ExitCode, runErr, expectPattern)
+}
+
+// Wrapper for Grep checks
+func (f *FakeHardener) CheckFileContent(cmd string, args []string, expectPattern string) (bool, error) {
+	success, _, err := f.RunCommand(cmd, args, expectPattern)
+	return success, err
+}
+
+func (f *FakeHardener) CheckFilePermission(path string, expectedMode string, expectedOwner string, expectedGroup string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, ok := f.Files[path]
+	if !ok {
+		return false, nil
+	}
+	if expectedMode != "" && fmt.Sprintf("%04o", file.Mode.Perm()) != expectedMode {
+		return false, nil
+	}
+	if expectedOwner != "" && file.Owner != expectedOwner {
+		return false, nil
+	}
+	if expectedGroup != "" && file.Group != expectedGroup {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (f *FakeHardener) SetFilePermission(path string, modeStr string) error {
+	modeInt, err := strconv.ParseUint(modeStr, 8, 32)
+	if err != nil {
+		return fmt.Errorf("invalid octal: %v", err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, ok := f.Files[path]
+	if !ok {
+		return &os.PathError{Op: "chmod", Path: path, Err: os.ErrNotExist}
+	}
+	file.Mode = os.FileMode(modeInt)
+	return nil
+}
+
+func (f *FakeHardener) EditConfigFile(path string, searchRegex string, replaceText string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, ok := f.Files[path]
+	if !ok {
+		file = &FakeFile{Mode: 0644, Owner: "root", Group: "root"}
+	}
+	newText, err := applyConfigEdit(string(file.Data), searchRegex, replaceText)
+	if err != nil {
+		return err
+	}
+	file.Data = []byte(newText)
+	f.Files[path] = file
+	return nil
+}
+
+func (f *FakeHardener) ReadFile(path string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, ok := f.Files[path]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return append([]byte(nil), file.Data...), nil
+}
+
+func (f *FakeHardener) WriteFile(path string, data []byte, mode os.FileMode) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if file, ok := f.Files[path]; ok {
+		file.Data = append([]byte(nil), data...)
+		return nil
+	}
+	f.Files[path] = &FakeFile{Data: append([]byte(nil), data...), Mode: mode, Owner: "root", Group: "root"}
+	return nil
+}
+
+func (f *FakeHardener) Glob(pattern string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var matches []string
+	for name := range f.Files {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func (f *FakeHardener) BootID() (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Boot == "" {
+		return "", "", ErrNotApplicable
+	}
+	return f.MachineID, f.Boot, nil
+}
+
+// FakeBackupDir is where FakeHardener keeps backups, inside Files.
+const FakeBackupDir = "/var/lib/sentinelx/backups"
+
+func (f *FakeHardener) BackupFile(p string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, ok := f.Files[p]
+	if !ok {
+		return "", nil
+	}
+	dst := filepath.ToSlash(backupName(FakeBackupDir, p, time.Now()))
+	backup := *file
+	backup.Data = append([]byte(nil), file.Data...)
+	f.Files[dst] = &backup
+	return dst, nil
+}
+
+func (f *FakeHardener) RestoreFile(path string, backup string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, ok := f.Files[backup]
+	if !ok {
+		return &os.PathError{Op: "restore", Path: backup, Err: os.ErrNotExist}
+	}
+	restored := *file
+	restored.Data = append([]byte(nil), file.Data...)
+	f.Files[path] = &restored
+	return nil
+}
+
+func (f *FakeHardener) RemoveFile(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.Files, path)
+	return nil
+}
+
+// --- REGISTRY ---
+
+func (f *FakeHardener) RegistryBackend() winreg.Backend {
+	return f.Registry
+}
+
+// --- SECEDIT EMULATION ---
+
+// secedit handles "/export /cfg <file>" and "/configure ... /cfg <file>".
+// Exports are UTF-16LE like the real tool's.
+func (f *FakeHardener) secedit(args []string) FakeCommand {
+	cfg := ""
+	for i, a := range args {
+		if strings.EqualFold(a, "/cfg") && i+1 < len(args) {
+			cfg = args[i+1]
+		}
+	}
+	if cfg == "" || len(args) == 0 {
+		return FakeCommand{Output: "The parameter is incorrect.", ExitCode: 87}
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "/export":
+		f.mu.Lock()
+		sections := map[string]map[string]string{
+			secedit.SectionSystemAccess:    {},
+			secedit.SectionEventAudit:      {},
+			secedit.SectionPrivilegeRights: f.Rights,
+		}
+		for name, value := range f.Security {
+			sections[secedit.SectionFor(name)][name] = value
+		}
+		var b strings.Builder
+		b.WriteString("[Unicode]\r\nUnicode=yes\r\n")
+		for _, section := range []string{secedit.SectionSystemAccess, secedit.SectionEventAudit, secedit.SectionPrivilegeRights} {
+			names := make([]string, 0, len(sections[section]))
+			for name := range sections[section] {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fmt.Fprintf(&b, "[%s]\r\n", section)
+			for _, name := range names {
+				fmt.Fprintf(&b, "%s = %s\r\n", name, sections[section][name])
+			}
+		}
+		b.WriteString("[Version]\r\nsignature=\"$CHICAGO$\"\r\nRevision=1\r\n")
+		f.Files[cfg] = &FakeFile{Data: secedit.EncodeUTF16(b.String()), Mode: 0644}
+		f.mu.Unlock()
+		return FakeCommand{Output: "The task has completed successfully."}
+
+	case "/configure":
+		data, err := f.ReadFile(cfg)
+		if err != nil {
+			return FakeCommand{Output: "The system cannot find the file specified.", ExitCode: 2}
+		}
+		tmpl, err := secedit.Parse(data)
+		if err != nil {
+			return FakeCommand{Output: "Invalid template: " + err.Error(), ExitCode: 1}
+		}
+		f.mu.Lock()
+		for section, values := range tmpl.Sections {
+			for name, value := range values {
+				switch {
+				case strings.EqualFold(section, secedit.SectionPrivilegeRights) && value == "":
+					delete(f.Rights, name)
+				case strings.EqualFold(section, secedit.SectionPrivilegeRights):
+					f.Rights[name] = value
+				case strings.EqualFold(section, secedit.SectionSystemAccess), strings.EqualFold(section, secedit.SectionEventAudit):
+					f.Security[name] = value
+				}
+			}
+		}
+		f.mu.Unlock()
+		return FakeCommand{Output: "The task has completed successfully."}
+	}
+	return FakeCommand{Output: "The parameter is incorrect.", ExitCode: 87}
+}
+
+// --- GREP EMULATION ---
+
+// grep handles "grep [-E|-P|-i|-q|-s...] PATTERN FILE..." over Files using Go
+// regexp syntax, which covers the ERE patterns the policies use.
+func (f *FakeHardener) grep(args []string) (FakeCommand, bool) {
+	var operands []string
+	ignoreCase, quiet := false, false
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") && len(operands) == 0 && len(a) > 1 {
+			if strings.ContainsAny(a, "ABCefvcl") {
+				return FakeCommand{}, false // unsupported flag; fall back to "not scripted"
+			}
+			ignoreCase = ignoreCase || strings.Contains(a, "i")
+			quiet = quiet || strings.Contains(a, "q")
+			continue
+		}
+		operands = append(operands, a)
+	}
+	if len(operands) < 2 {
+		return FakeCommand{}, false
+	}
+
+	pattern := operands[0]
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return FakeCommand{Output: "grep: invalid regular expression", ExitCode: 2}, true
+	}
+
+	var matches []string
+	missing := false
+	for _, path := range operands[1:] {
+		data, err := f.ReadFile(path)
+		if err != nil {
+			missing = true
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if re.MatchString(line) {
+				matches = append(matches, line)
+			}
+		}
+	}
+
+	switch {
+	case len(matches) > 0:
+		if quiet {
+			return FakeCommand{}, true
+		}
+		return FakeCommand{Output: strings.Join(matches, "\n")}, true
+	case missing:
+		return FakeCommand{Output: "grep: No such file or directory", ExitCode: 2}, true
+	}
+	return FakeCommand{ExitCode: 1}, true
+}
@@ -0,0 +1,48 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SSHHost is one entry of an inventory file.
+type SSHHost struct {
+	Name        string `json:"name"`
+	Address     string `json:"address"`
+	Port        int    `json:"port,omitempty"`
+	User        string `json:"user"`
+	KeyFile     string `json:"key_file,omitempty"`
+	PasswordEnv string `json:"password_env,omitempty"` // env var holding the password
+	Sudo        bool   `json:"sudo,omitempty"`         // prefix commands with "sudo -n"
+
+	KnownHosts            string `json:"known_hosts,omitempty"` // default ~/.ssh/known_hosts
+	InsecureIgnoreHostKey bool   `json:"insecure_ignore_host_key,omitempty"`
+}
+
+// Inventory lists the remote hosts to audit over SSH.
+type Inventory struct {
+	Hosts []SSHHost `json:"hosts"`
+}
+
+// LoadInventory reads a JSON inventory file.
+func LoadInventory(filePath string) (*Inventory, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open inventory file: %v", err)
+	}
+
+	var inv Inventory
+	if err := json.Unmarshal(data, &inv); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %v", err)
+	}
+	for i, h := range inv.Hosts {
+		if h.Address == "" || h.User == "" {
+			return nil, fmt.Errorf("inventory host #%d: address and user are required", i+1)
+		}
+		if h.Name == "" {
+			inv.Hosts[i].Name = h.Address
+		}
+	}
+	return &inv, nil
+}
@@ -0,0 +1,72 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeInventory(t *testing.T, data string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "inventory.json")
+	if err := os.WriteFile(p, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadInventory(t *testing.T) {
+	inv, err := LoadInventory(writeInventory(t, `{"hosts": [
+		{"name": "web01", "address": "10.0.0.5", "port": 2222, "user": "audit", "key_file": "/keys/id_ed25519", "sudo": true},
+		{"address": "db01.example.com", "user": "root", "password_env": "DB_PASS", "insecure_ignore_host_key": true}
+	]}`))
+	if err != nil {
+		t.Fatalf("LoadInventory: %v", err)
+	}
+	want := []SSHHost{
+		{Name: "web01", Address: "10.0.0.5", Port: 2222, User: "audit", KeyFile: "/keys/id_ed25519", Sudo: true},
+		{Name: "db01.example.com", Address: "db01.example.com", User: "root", PasswordEnv: "DB_PASS", InsecureIgnoreHostKey: true},
+	}
+	if !reflect.DeepEqual(inv.Hosts, want) {
+		t.Errorf("hosts\n got %+v\nwant %+v", inv.Hosts, want)
+	}
+	if inv.Hosts[0].addr() != "10.0.0.5:2222" || inv.Hosts[1].addr() != "db01.example.com:22" {
+		t.Errorf("addresses %s, %s", inv.Hosts[0].addr(), inv.Hosts[1].addr())
+	}
+}
+
+func TestLoadInventoryErrors(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"not json", `hosts: []`},
+		{"no address", `{"hosts": [{"name": "web01", "user": "audit"}]}`},
+		{"no user", `{"hosts": [{"address": "10.0.0.5"}]}`},
+		{"wrong type", `{"hosts": [{"address": "10.0.0.5", "user": "audit", "port": "22"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if inv, err := LoadInventory(writeInventory(t, tt.data)); err == nil {
+				t.Errorf("LoadInventory accepted it: %+v", inv)
+			}
+		})
+	}
+	if _, err := LoadInventory(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("a missing inventory file was accepted")
+	}
+}
+
+func TestClientConfig(t *testing.T) {
+	if _, err := (SSHHost{Name: "web01", Address: "10.0.0.5", User: "audit"}).clientConfig(); err == nil {
+		t.Error("a host without credentials was accepted")
+	}
+	host := SSHHost{Address: "10.0.0.5", User: "audit", PasswordEnv: "X", KnownHosts: filepath.Join(t.TempDir(), "missing")}
+	if _, err := host.clientConfig(); err == nil {
+		t.Error("a missing known_hosts file was accepted")
+	}
+	host.InsecureIgnoreHostKey = true
+	if c, err := host.clientConfig(); err != nil || c.User != "audit" || len(c.Auth) != 1 {
+		t.Errorf("clientConfig: %+v, %v", c, err)
+	}
+}
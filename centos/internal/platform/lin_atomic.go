@@ -0,0 +1,213 @@
+//go:build linux
+
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// BackupDir holds the copies of files taken before a remediation changes them.
+var BackupDir = "/var/lib/sentinelx/backups"
+
+// writeFileAtomic replaces path with data so that a crash leaves either the
+// old or the new file, never a truncated one: the data goes to a temp file in
+// the same directory, is fsynced and renamed over path. The new file takes
+// the owner, mode and extended attributes (SELinux label, ACLs, file
+// capabilities) of like, or gets mode when like is "".
+func writeFileAtomic(path string, data []byte, mode os.FileMode, like string) error {
+	dir, base := filepath.Split(path)
+	tmp, err := os.CreateTemp(dir, "."+base+".sentinelx-*")
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if like != "" {
+		err = copyMetadata(tmp, like)
+	} else {
+		err = tmp.Chmod(mode)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	committed = true
+
+	// Make the rename itself durable
+	if d, err := os.Open(filepath.Clean(dir)); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// replaceFile writes data over path (following symlinks to the real file),
+// keeping an existing file's metadata.
+func replaceFile(path string, data []byte, mode os.FileMode) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	like := ""
+	if _, err := os.Stat(path); err == nil {
+		like = path
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return writeFileAtomic(path, data, mode, like)
+}
+
+// copyMetadata gives f the owner, mode and extended attributes of src.
+// Ownership goes first: chown clears setuid bits and file capabilities.
+func copyMetadata(f *os.File, src string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		if err := f.Chown(int(st.Uid), int(st.Gid)); err != nil {
+			return err
+		}
+	}
+	if err := f.Chmod(info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)); err != nil {
+		return err
+	}
+
+	names, err := listXattrs(src)
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("list xattrs: %v", err)
+	}
+	for _, name := range names {
+		value, err := getXattr(src, name)
+		if errors.Is(err, unix.ENODATA) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("read xattr %s: %v", name, err)
+		}
+		if err := unix.Fsetxattr(int(f.Fd()), name, value, 0); err != nil && !errors.Is(err, unix.ENOTSUP) {
+			return fmt.Errorf("set xattr %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func listXattrs(path string) ([]string, error) {
+	size, err := unix.Listxattr(path, nil)
+	for err == nil && size > 0 {
+		buf := make([]byte, size)
+		var n int
+		n, err = unix.Listxattr(path, buf)
+		if errors.Is(err, unix.ERANGE) {
+			// Grew in between; ask again
+			size, err = unix.Listxattr(path, nil)
+			continue
+		}
+		if err != nil {
+			break
+		}
+		var names []string
+		for start := 0; start < n; {
+			end := start
+			for end < n && buf[end] != 0 {
+				end++
+			}
+			if end > start {
+				names = append(names, string(buf[start:end]))
+			}
+			start = end + 1
+		}
+		return names, nil
+	}
+	return nil, err
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	for {
+		size, err := unix.Getxattr(path, name, nil)
+		if err != nil || size == 0 {
+			return nil, err
+		}
+		buf := make([]byte, size)
+		n, err := unix.Getxattr(path, name, buf)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// backupFile copies path, with its metadata, to a timestamped name under dir.
+func backupFile(dir string, path string) (string, error) {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	return backupFileAs(dir, path, path)
+}
+
+// backupFileAs copies path to a backup under dir named after name, for
+// callers whose path on disk isn't the one the backup should be filed under.
+func backupFileAs(dir string, path string, name string) (string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("backup %s: not a regular file", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	dst := backupName(dir, name, time.Now())
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return "", fmt.Errorf("backup %s: %v", path, err)
+	}
+	if err := writeFileAtomic(dst, data, 0600, path); err != nil {
+		return "", fmt.Errorf("backup %s: %v", path, err)
+	}
+	return dst, nil
+}
+
+// restoreBackup writes a backupFile copy back over path, with the owner, mode
+// and extended attributes the backup kept.
+func restoreBackup(backup string, path string) error {
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data, 0, backup); err != nil {
+		return fmt.Errorf("restore %s: %v", path, err)
+	}
+	return nil
+}
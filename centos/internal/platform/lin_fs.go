@@ -7,10 +7,11 @@ import (
     "io/ioutil"
     "os"
     "os/exec"
-    "regexp"
+    "path/filepath"
     "strconv"
     "strings"
-    
+
+    "sih2025/internal/winreg"
 )
 
 type LinuxHardener struct{}
@@ -24,8 +25,7 @@ func (l *LinuxHardener) GetOSName() string {
 }
 
 // Stubs
-func (l *LinuxHardener) CheckRegistry(key, val string, exp interface{}) (bool, error) { return false, nil }
-func (l *LinuxHardener) SetRegistry(k, v string, val interface{}) error               { return nil }
+func (l *LinuxHardener) RegistryBackend() winreg.Backend { return nil }
 
 // --- UPDATED: RunCommand returns the OUTPUT string ---
 func (l *LinuxHardener) RunCommand(cmdStr string, args []string, expectPattern string) (bool, string, error) {
@@ -33,34 +33,11 @@ func (l *LinuxHardener) RunCommand(cmdStr string, args []string, expectPattern s
     output, err := cmd.CombinedOutput()
     outStr := strings.TrimSpace(string(output))
 
-    // 1. Audit Mode (Checking for a pattern)
-    if expectPattern != "" {
-        // Handle common grep exit code 1 (not found) gracefully
-        if err != nil {
-            if exitErr, ok := err.(*exec.ExitError); ok {
-                if exitErr.ExitCode() == 1 && strings.Contains(cmdStr, "grep") {
-                    return false, "Pattern Not Found", nil
-                }
-            }
-        }
-
-        // Check for match
-        matched, _ := regexp.MatchString(expectPattern, outStr)
-        if matched || strings.Contains(outStr, expectPattern) {
-            // Return TRUE and the ACTUAL OUTPUT found
-            return true, outStr, nil
-        }
-        // Return FALSE and the OUTPUT (so we know what wrong value was found)
-        if outStr == "" { outStr = "Empty Output" }
-        return false, outStr, nil
+    exitCode := -1
+    if exitErr, ok := err.(*exec.ExitError); ok {
+        exitCode = exitErr.ExitCode()
     }
-
-    // 2. Action Mode (Running a fix/command)
-    if err != nil {
-        return false, outStr, fmt.Errorf("execution failed: %v | output: %s", err, outStr)
-    }
-
-    return true, outStr, nil
+    return evaluateOutput(cmdStr, outStr, exitCode, err, expectPattern)
 }
 
 // Wrapper for Grep checks
@@ -91,10 +68,11 @@ func (l *LinuxHardener) SetFilePermission(path string, modeStr string) error {
     return os.Chmod(path, os.FileMode(modeInt))
 }
 
+// EditConfigFile rewrites the file atomically, keeping its owner, mode and
+// security context (see writeFileAtomic)
 func (l *LinuxHardener) EditConfigFile(path string, searchRegex string, replaceText string) error {
     content, err := ioutil.ReadFile(path)
     var text string
-    var originalMode os.FileMode = 0644
 
     if os.IsNotExist(err) {
         text = ""
@@ -102,21 +80,57 @@ func (l *LinuxHardener) EditConfigFile(path string, searchRegex string, replaceT
         return err
     } else {
         text = string(content)
-        info, _ := os.Stat(path)
-        originalMode = info.Mode()
     }
 
-    re := regexp.MustCompile("(?m)" + searchRegex)
-    newText := ""
+    newText, err := applyConfigEdit(text, searchRegex, replaceText)
+    if err != nil {
+        return err
+    }
+    return replaceFile(path, []byte(newText), 0644)
+}
+
+func (l *LinuxHardener) ReadFile(path string) ([]byte, error) {
+    return os.ReadFile(path)
+}
 
-    if re.MatchString(text) {
-        newText = re.ReplaceAllString(text, replaceText)
-    } else {
-        if len(text) > 0 && !strings.HasSuffix(text, "\n") {
-            newText = text + "\n" + replaceText + "\n"
-        } else {
-            newText = text + replaceText + "\n"
-        }
+// WriteFile replaces the file atomically; an existing file keeps its owner,
+// mode and xattrs, mode applies to new files
+func (l *LinuxHardener) WriteFile(path string, data []byte, mode os.FileMode) error {
+    return replaceFile(path, data, mode)
+}
+
+func (l *LinuxHardener) Glob(pattern string) ([]string, error) {
+    return filepath.Glob(pattern)
+}
+
+func (l *LinuxHardener) BackupFile(path string) (string, error) {
+    return backupFile(BackupDir, path)
+}
+
+func (l *LinuxHardener) RestoreFile(path string, backup string) error {
+    if target, err := filepath.EvalSymlinks(path); err == nil {
+        path = target
+    }
+    return restoreBackup(backup, path)
+}
+
+func (l *LinuxHardener) BootID() (string, string, error) {
+    boot, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+    if err != nil {
+        return "", "", err
+    }
+    machine, err := os.ReadFile("/etc/machine-id")
+    if err != nil || strings.TrimSpace(string(machine)) == "" {
+        host, _ := os.Hostname()
+        machine = []byte(host)
+    }
+    return strings.TrimSpace(string(machine)), strings.TrimSpace(string(boot)), nil
+}
+
+func (l *LinuxHardener) RemoveFile(path string) error {
+    err := os.Remove(path)
+    if os.IsNotExist(err) {
+        return nil
     }
-    return ioutil.WriteFile(path, []byte(newText), originalMode)
+    return err
 }
\ No newline at end of file
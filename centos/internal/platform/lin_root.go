@@ -0,0 +1,213 @@
+//go:build linux
+
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// liveKernelPattern matches commands that only make sense on a booted system:
+// kernel parameters, loaded modules, live mounts, sockets, processes,
+// firewall state and unit activity.
+var liveKernelPattern = regexp.MustCompile(`(^|[;&|(]\s*|\$\(\s*)(sysctl|lsmod|modprobe|findmnt|mount|ss|netstat|ps|iptables|ip6tables|nft|ufw|firewall-cmd|auditctl|uname)\b` +
+	`|\bsystemctl\s+(\S+\s+)*(is-active|is-failed|status|show)\b` +
+	`|(^|\s)/(proc|sys)/`)
+
+// RootHardener audits an offline root filesystem (a mounted disk image or
+// container rootfs). File operations are rebased under Root, commands run
+// chrooted, and checks that need a live kernel return ErrNotApplicable.
+type RootHardener struct {
+	LinuxHardener
+	Root string
+}
+
+// NewRootHardener validates root and returns a hardener for it.
+func NewRootHardener(root string) (HardenerInterface, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("root filesystem: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("root filesystem %s is not a directory", abs)
+	}
+	if _, err := os.Stat(filepath.Join(abs, "etc")); err != nil {
+		return nil, fmt.Errorf("root filesystem %s has no /etc; is the image mounted?", abs)
+	}
+	return &RootHardener{Root: abs}, nil
+}
+
+func (r *RootHardener) RunCommand(cmdStr string, args []string, expectPattern string) (bool, string, error) {
+	if needsLiveKernel(append([]string{cmdStr}, args...)) {
+		return false, "Requires a running system", ErrNotApplicable
+	}
+
+	cmd := exec.Command("chroot", append([]string{r.Root, cmdStr}, args...)...)
+	output, err := cmd.CombinedOutput()
+	outStr := strings.TrimSpace(string(output))
+
+	exitCode := -1
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	}
+	return evaluateOutput(cmdStr, outStr, exitCode, err, expectPattern)
+}
+
+// Wrapper for Grep checks
+func (r *RootHardener) CheckFileContent(cmd string, args []string, expectPattern string) (bool, error) {
+	success, _, err := r.RunCommand(cmd, args, expectPattern)
+	return success, err
+}
+
+func (r *RootHardener) CheckFilePermission(path string, expectedMode string, expectedOwner string, expectedGroup string) (bool, error) {
+	hostPath, err := r.rebase(path)
+	if err != nil {
+		return false, err
+	}
+	return r.LinuxHardener.CheckFilePermission(hostPath, expectedMode, expectedOwner, expectedGroup)
+}
+
+func (r *RootHardener) SetFilePermission(path string, modeStr string) error {
+	hostPath, err := r.rebase(path)
+	if err != nil {
+		return err
+	}
+	return r.LinuxHardener.SetFilePermission(hostPath, modeStr)
+}
+
+func (r *RootHardener) EditConfigFile(path string, searchRegex string, replaceText string) error {
+	hostPath, err := r.rebase(path)
+	if err != nil {
+		return err
+	}
+	return r.LinuxHardener.EditConfigFile(hostPath, searchRegex, replaceText)
+}
+
+func (r *RootHardener) ReadFile(path string) ([]byte, error) {
+	hostPath, err := r.rebase(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.LinuxHardener.ReadFile(hostPath)
+}
+
+func (r *RootHardener) WriteFile(path string, data []byte, mode os.FileMode) error {
+	hostPath, err := r.rebase(path)
+	if err != nil {
+		return err
+	}
+	return r.LinuxHardener.WriteFile(hostPath, data, mode)
+}
+
+func (r *RootHardener) RemoveFile(path string) error {
+	hostPath, err := r.rebase(path)
+	if err != nil {
+		return err
+	}
+	return r.LinuxHardener.RemoveFile(hostPath)
+}
+
+// BackupFile keeps the copy on the host, under BackupDir plus the image path
+func (r *RootHardener) BackupFile(path string) (string, error) {
+	hostPath, err := r.rebase(path)
+	if err != nil {
+		return "", err
+	}
+	// rebase already resolved symlinks inside the image
+	imagePath := "/" + strings.TrimPrefix(strings.TrimPrefix(hostPath, r.Root), "/")
+	return backupFileAs(BackupDir, hostPath, imagePath)
+}
+
+// RestoreFile reads the backup from the host and writes it into the image
+func (r *RootHardener) RestoreFile(path string, backup string) error {
+	hostPath, err := r.rebase(path)
+	if err != nil {
+		return err
+	}
+	return restoreBackup(backup, hostPath)
+}
+
+// BootID: an image on disk has no current boot
+func (r *RootHardener) BootID() (string, string, error) {
+	return "", "", ErrNotApplicable
+}
+
+// Glob matches inside the image. Only the last path element may contain
+// wildcards, which covers Include lines and .d directories.
+func (r *RootHardener) Glob(pattern string) ([]string, error) {
+	dir, base := filepath.Split(filepath.Clean("/" + pattern))
+	hostDir, err := r.rebase(dir)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(hostDir, base))
+	for i, m := range matches {
+		matches[i] = filepath.Join(dir, filepath.Base(m))
+	}
+	return matches, err
+}
+
+// rebase maps an absolute path inside the image to the host path. Symlinks
+// are resolved against Root, so an absolute link in the image can't point the
+// tool at the host's own /etc.
+func (r *RootHardener) rebase(path string) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(filepath.Clean("/"+path), "/"), "/")
+	resolved := "/"
+	hops := 0
+
+	for i := 0; i < len(parts); i++ {
+		part := parts[i]
+		if part == "" || part == "." {
+			continue
+		}
+		if part == ".." {
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, part)
+		info, err := os.Lstat(filepath.Join(r.Root, next))
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		hops++
+		if hops > 40 {
+			return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+		}
+		target, err := os.Readlink(filepath.Join(r.Root, next))
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(resolved, target)
+		}
+
+		// Restart the walk from the link target plus the remaining components
+		rest := strings.Split(strings.TrimPrefix(filepath.Clean(target), "/"), "/")
+		parts = append(rest, parts[i+1:]...)
+		resolved = "/"
+		i = -1
+	}
+	return filepath.Join(r.Root, resolved), nil
+}
+
+// needsLiveKernel checks the command and each argument, so the script passed
+// to "bash -c" is inspected too.
+func needsLiveKernel(words []string) bool {
+	for _, w := range words {
+		if liveKernelPattern.MatchString(w) {
+			return true
+		}
+	}
+	return false
+}
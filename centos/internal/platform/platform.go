@@ -1,5 +1,16 @@
 package platform
 
+import (
+    "errors"
+    "os"
+
+    "sih2025/internal/winreg"
+)
+
+// ErrNotApplicable is returned when a check or fix cannot run on the current
+// target, e.g. a sysctl query against an offline disk image.
+var ErrNotApplicable = errors.New("not applicable to this target")
+
 // HardenerInterface defines the methods required for any OS implementation
 type HardenerInterface interface {
     GetOSName() string
@@ -14,12 +25,27 @@ type HardenerInterface interface {
     CheckFilePermission(path string, expectedMode string, expectedOwner string, expectedGroup string) (bool, error)
     SetFilePermission(path string, modeStr string) error
 
-    // Registry (Windows)
-    CheckRegistry(key, val string, exp interface{}) (bool, error)
-    SetRegistry(k, v string, val interface{}) error
+    // Registry (Windows); nil on platforms without one
+    RegistryBackend() winreg.Backend
 
     // File Editing (Linux)
     EditConfigFile(path string, searchRegex string, replaceText string) error
+
+    // Raw file access on the target (used by secedit and backups)
+    ReadFile(path string) ([]byte, error)
+    WriteFile(path string, data []byte, mode os.FileMode) error
+    RemoveFile(path string) error
+    // Glob lists the files matching a shell pattern, sorted (Include and .d directories)
+    Glob(pattern string) ([]string, error)
+    // BackupFile keeps a timestamped copy of path (contents, owner, mode) and
+    // returns where it went; "" when path doesn't exist
+    BackupFile(path string) (string, error)
+    // RestoreFile puts a BackupFile copy back over path
+    RestoreFile(path string, backup string) error
+
+    // BootID identifies the machine and its current boot, so a reboot can be
+    // detected; ErrNotApplicable without a running system
+    BootID() (machine string, boot string, err error)
 }
 
 // Global instance variable
@@ -32,4 +58,9 @@ func GetPlatform() HardenerInterface {
         currentPlatform = getPlatformInstance()
     }
     return currentPlatform
+}
+
+// SetPlatform replaces the singleton, e.g. with a RootHardener for --root mode
+func SetPlatform(h HardenerInterface) {
+    currentPlatform = h
 }
\ No newline at end of file
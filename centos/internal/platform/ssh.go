@@ -0,0 +1,412 @@
+package platform
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"sih2025/internal/winreg"
+)
+
+// maxSSHSessions caps concurrent sessions per connection. RunAudit checks a
+// whole DAG layer in parallel and sshd's MaxSessions defaults to 10.
+const maxSSHSessions = 8
+
+// SSHTimeout bounds establishing an SSH connection.
+var SSHTimeout = 15 * time.Second
+
+// remoteBackupDir is BackupDir on the remote host.
+const remoteBackupDir = "/var/lib/sentinelx/backups"
+
+// SSHHardener audits and remediates a remote Linux host over SSH. Commands
+// run through the remote shell, so the same Linux policy applies unchanged.
+type SSHHardener struct {
+	Host     SSHHost
+	client   *ssh.Client
+	sessions chan struct{}
+}
+
+// NewSSHHardener dials the host and authenticates with its key or password.
+func NewSSHHardener(host SSHHost) (*SSHHardener, error) {
+	config, err := host.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := ssh.Dial("tcp", host.addr(), config)
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s: %v", host.addr(), err)
+	}
+	return &SSHHardener{
+		Host:     host,
+		client:   client,
+		sessions: make(chan struct{}, maxSSHSessions),
+	}, nil
+}
+
+// Close ends the SSH connection.
+func (s *SSHHardener) Close() error {
+	return s.client.Close()
+}
+
+func (s *SSHHardener) GetOSName() string {
+	return "linux"
+}
+
+// Stubs
+func (s *SSHHardener) RegistryBackend() winreg.Backend { return nil }
+
+// RunCommand executes the command on the remote host with the same
+// pattern-matching semantics as LinuxHardener.
+func (s *SSHHardener) RunCommand(cmdStr string, args []string, expectPattern string) (bool, string, error) {
+	output, exitCode, err := s.run(shellJoin(append([]string{cmdStr}, args...)), nil)
+	return evaluateOutput(cmdStr, strings.TrimSpace(string(output)), exitCode, err, expectPattern)
+}
+
+// Wrapper for Grep checks
+func (s *SSHHardener) CheckFileContent(cmd string, args []string, expectPattern string) (bool, error) {
+	success, _, err := s.RunCommand(cmd, args, expectPattern)
+	return success, err
+}
+
+// CheckFilePermission stats the remote file. A missing file fails the check;
+// stat (and a failing sudo -n) exit 1 for other errors too, so existence is
+// tested on its own as in readRemote.
+func (s *SSHHardener) CheckFilePermission(path string, expectedMode string, expectedOwner string, expectedGroup string) (bool, error) {
+	script := `[ -e "$1" ] || exit 3
+exec stat -c '%a %U %G' "$1"`
+	output, exitCode, err := s.run(shellJoin([]string{"sh", "-c", script, "sh", path}), nil)
+	if exitCode == 3 {
+		return false, nil // Missing file = Fail
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat %s: %v | output: %s", path, err, strings.TrimSpace(string(output)))
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 3 {
+		return false, fmt.Errorf("unexpected stat output: %q", output)
+	}
+	if expectedMode != "" {
+		mode, err := strconv.ParseUint(fields[0], 8, 32)
+		if err != nil || fmt.Sprintf("%04o", mode) != expectedMode {
+			return false, nil
+		}
+	}
+	if expectedOwner != "" && fields[1] != expectedOwner {
+		return false, nil
+	}
+	if expectedGroup != "" && fields[2] != expectedGroup {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *SSHHardener) SetFilePermission(path string, modeStr string) error {
+	if _, err := strconv.ParseUint(modeStr, 8, 32); err != nil {
+		return fmt.Errorf("invalid octal: %v", err)
+	}
+	output, _, err := s.run(shellJoin([]string{"chmod", modeStr, path}), nil)
+	if err != nil {
+		return fmt.Errorf("chmod failed: %v | output: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// EditConfigFile downloads the file, applies the edit locally and uploads the
+// result to a temp file next to the original, which is then renamed over it
+// so the remote file is never left half written.
+func (s *SSHHardener) EditConfigFile(path string, searchRegex string, replaceText string) error {
+	content, exists, err := s.readRemote(path)
+	if err != nil {
+		return err
+	}
+
+	newText, err := applyConfigEdit(string(content), searchRegex, replaceText)
+	if err != nil {
+		return err
+	}
+	return s.upload(path, []byte(newText), exists)
+}
+
+func (s *SSHHardener) ReadFile(path string) ([]byte, error) {
+	content, exists, err := s.readRemote(path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return content, nil
+}
+
+// readRemote reads a remote file, telling a missing file apart from one that
+// can't be read: cat (and a failing sudo -n) exit 1 for both, so existence is
+// tested on its own. Any failure to read an existing file is an error.
+func (s *SSHHardener) readRemote(path string) ([]byte, bool, error) {
+	script := `[ -e "$1" ] || exit 3
+exec cat "$1"`
+	content, exitCode, err := s.run(shellJoin([]string{"sh", "-c", script, "sh", path}), nil)
+	if exitCode == 3 {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read %s: %v | output: %s", path, err, strings.TrimSpace(string(content)))
+	}
+	return content, true, nil
+}
+
+// WriteFile uploads atomically; an existing file keeps its owner and mode
+func (s *SSHHardener) WriteFile(path string, data []byte, mode os.FileMode) error {
+	_, exitCode, _ := s.run(shellJoin([]string{"test", "-e", path}), nil)
+	if err := s.upload(path, data, exitCode == 0); err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		return s.SetFilePermission(path, fmt.Sprintf("%04o", mode.Perm()))
+	}
+	return nil
+}
+
+// Glob expands the pattern with the remote shell. Everything except the
+// wildcards is escaped, so the pattern can't run commands.
+func (s *SSHHardener) Glob(pattern string) ([]string, error) {
+	cmd, err := globCommand(pattern)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := s.run(cmd, nil)
+	if err != nil {
+		return nil, fmt.Errorf("glob %s: %v", pattern, err)
+	}
+	var matches []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line != "" {
+			matches = append(matches, line)
+		}
+	}
+	return matches, nil
+}
+
+// globCommand is the sh -c command line that prints the files matching
+// pattern, one per line. The loop is a script of its own so sudo can run it.
+func globCommand(pattern string) (string, error) {
+	if strings.ContainsAny(pattern, "\n\x00") {
+		return "", fmt.Errorf("invalid glob pattern %q", pattern)
+	}
+	var quoted strings.Builder
+	for _, c := range pattern {
+		if !strings.ContainsRune("*?[]", c) {
+			quoted.WriteRune('\\')
+		}
+		quoted.WriteRune(c)
+	}
+	script := `for f in ` + quoted.String() + `; do [ -e "$f" ] && printf '%s\n' "$f"; done; true`
+	return shellJoin([]string{"sh", "-c", script}), nil
+}
+
+// BackupFile copies the file on the remote host, under the same backup
+// directory as a local run
+func (s *SSHHardener) BackupFile(path string) (string, error) {
+	dst := filepath.ToSlash(backupName(remoteBackupDir, path, time.Now()))
+	script := `set -e
+[ -e "$1" ] || exit 3
+mkdir -p -m 0700 "$(dirname "$2")"
+cp -pL "$1" "$2"
+cp --attributes-only --preserve=xattr "$1" "$2" 2>/dev/null || true`
+	output, exitCode, err := s.run(shellJoin([]string{"sh", "-c", script, "sh", path, dst}), nil)
+	if exitCode == 3 {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("backup %s: %v | output: %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return dst, nil
+}
+
+// RestoreFile copies a backup back over path on the remote host, through a
+// temp file renamed into place
+func (s *SSHHardener) RestoreFile(path string, backup string) error {
+	script := `set -e
+[ -e "$1" ] || exit 3
+p="$(readlink -f -- "$2" 2>/dev/null)" || p="$2"
+[ -n "$p" ] || p="$2"
+cp -p "$1" "$p.sentinelx-restore"
+cp --attributes-only --preserve=xattr "$1" "$p.sentinelx-restore" 2>/dev/null || true
+mv -f "$p.sentinelx-restore" "$p"`
+	output, exitCode, err := s.run(shellJoin([]string{"sh", "-c", script, "sh", backup, path}), nil)
+	if exitCode == 3 {
+		return &os.PathError{Op: "restore", Path: backup, Err: os.ErrNotExist}
+	}
+	if err != nil {
+		return fmt.Errorf("restore %s: %v | output: %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// BootID reads the remote boot ID, and /etc/machine-id (or the hostname)
+func (s *SSHHardener) BootID() (string, string, error) {
+	out, _, err := s.run(`cat /proc/sys/kernel/random/boot_id && { cat /etc/machine-id 2>/dev/null || hostname; }`, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("boot id: %v", err)
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) < 2 {
+		return "", "", fmt.Errorf("boot id: unexpected output %q", strings.TrimSpace(string(out)))
+	}
+	return lines[1], lines[0], nil
+}
+
+func (s *SSHHardener) RemoveFile(path string) error {
+	output, _, err := s.run(shellJoin([]string{"rm", "-f", path}), nil)
+	if err != nil {
+		return fmt.Errorf("rm %s: %v | output: %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// upload writes data to path atomically: temp file in the same directory,
+// ownership and mode copied from the original, fsync, then rename.
+func (s *SSHHardener) upload(path string, data []byte, exists bool) error {
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	tmp := path + ".sentinelx-" + hex.EncodeToString(suffix)
+
+	script := `set -e
+umask 077
+cat > "$1"
+if [ "$3" = "1" ]; then
+  # owner, mode and xattrs (SELinux label, ACLs); plain chown/chmod without GNU cp
+  cp --attributes-only --preserve=mode,ownership,xattr "$2" "$1" 2>/dev/null || {
+    chown --reference="$2" "$1" 2>/dev/null || true
+    chmod --reference="$2" "$1"
+  }
+else
+  chmod 0644 "$1"
+fi
+sync "$1" 2>/dev/null || true
+mv -f "$1" "$2"`
+	existsFlag := "0"
+	if exists {
+		existsFlag = "1"
+	}
+
+	cmd := shellJoin([]string{"sh", "-c", script, "sh", tmp, path, existsFlag})
+	output, _, err := s.run(cmd, data)
+	if err != nil {
+		s.run(shellJoin([]string{"rm", "-f", tmp}), nil)
+		return fmt.Errorf("upload %s: %v | output: %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// run executes one command line in a new session, optionally via sudo, and
+// returns the combined output and exit status (-1 if it didn't exit).
+func (s *SSHHardener) run(cmdLine string, stdin []byte) ([]byte, int, error) {
+	s.sessions <- struct{}{}
+	defer func() { <-s.sessions }()
+
+	session, err := s.client.NewSession()
+	if err != nil {
+		return nil, -1, fmt.Errorf("ssh session: %v", err)
+	}
+	defer session.Close()
+
+	cmdLine = s.Host.commandLine(cmdLine)
+	if stdin != nil {
+		session.Stdin = bytes.NewReader(stdin)
+	}
+	// CombinedOutput serializes the stdout and stderr copies into one buffer
+	output, err := session.CombinedOutput(cmdLine)
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return output, exitErr.ExitStatus(), err
+	}
+	if err != nil {
+		return output, -1, err
+	}
+	return output, 0, nil
+}
+
+// commandLine is what the remote shell is asked to run for cmdLine: sudo -n
+// runs its first word as a program, which is why multi-command scripts go
+// through shellJoin'ed sh -c.
+func (h SSHHost) commandLine(cmdLine string) string {
+	if h.Sudo {
+		return "sudo -n " + cmdLine
+	}
+	return cmdLine
+}
+
+// shellJoin quotes each word for a POSIX shell.
+func shellJoin(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+// --- CONNECTION SETTINGS ---
+
+func (h SSHHost) addr() string {
+	port := h.Port
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(h.Address, strconv.Itoa(port))
+}
+
+func (h SSHHost) clientConfig() (*ssh.ClientConfig, error) {
+	var auth []ssh.AuthMethod
+	if h.KeyFile != "" {
+		pemBytes, err := os.ReadFile(h.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read key %s: %v", h.KeyFile, err)
+		}
+		signer, err := ssh.ParsePrivateKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse key %s: %v", h.KeyFile, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if h.PasswordEnv != "" {
+		auth = append(auth, ssh.Password(os.Getenv(h.PasswordEnv)))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("host %s: no key_file or password_env configured", h.Name)
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	if h.InsecureIgnoreHostKey {
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	} else {
+		knownHostsFile := h.KnownHosts
+		if knownHostsFile == "" {
+			home, _ := os.UserHomeDir()
+			knownHostsFile = home + "/.ssh/known_hosts"
+		}
+		cb, err := knownhosts.New(knownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("known_hosts: %v", err)
+		}
+		hostKeyCallback = cb
+	}
+
+	return &ssh.ClientConfig{
+		User:            h.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         SSHTimeout,
+	}, nil
+}
@@ -0,0 +1,312 @@
+package platform
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"sih2025/internal/confedit"
+)
+
+func TestShellJoin(t *testing.T) {
+	if got := shellJoin([]string{"grep", "-e", "it's", ""}); got != `'grep' '-e' 'it'\''s' ''` {
+		t.Errorf("shellJoin = %s", got)
+	}
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	words := []string{`it's "odd"`, `$(echo injected)`, "`id`", `a\b`, "; rm -rf x", "*", "", "two\nlines"}
+	out, err := exec.Command("sh", "-c", `printf '%s|' `+shellJoin(words)).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Join(words, "|") + "|"; string(out) != want {
+		t.Errorf("the shell read %q, want %q", out, want)
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	cmd := shellJoin([]string{"sh", "-c", `[ -e "$1" ] || exit 3`, "sh", "/etc/shadow"})
+	if got := (SSHHost{}).commandLine(cmd); got != cmd {
+		t.Errorf("without sudo: %s", got)
+	}
+	want := `sudo -n 'sh' '-c' '[ -e "$1" ] || exit 3' 'sh' '/etc/shadow'`
+	if got := (SSHHost{Sudo: true}).commandLine(cmd); got != want {
+		t.Errorf("with sudo: %s, want %s", got, want)
+	}
+}
+
+func TestGlobCommand(t *testing.T) {
+	got, err := globCommand(`/etc/it's/*.conf`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `'sh' '-c' 'for f in \/\e\t\c\/\i\t\'\''\s\/*\.\c\o\n\f; do [ -e "$f" ] && printf '\''%s\n'\'' "$f"; done; true'`
+	if got != want {
+		t.Errorf("globCommand\n got %s\nwant %s", got, want)
+	}
+	for _, bad := range []string{"/etc/a\n/etc/b", "/etc/a\x00"} {
+		if _, err := globCommand(bad); err == nil {
+			t.Errorf("globCommand(%q) was accepted", bad)
+		}
+	}
+}
+
+// sudoScript stands in for sudo on the test server: like sudo it runs its
+// first word as a program rather than a shell line, and it logs the option
+// and program of every call.
+const sudoScript = `#!/bin/sh
+printf '%s %s\n' "$1" "$2" >> "$SUDO_LOG"
+[ "$1" = "-n" ] || exit 1
+shift
+exec "$@"
+`
+
+// sshServer runs an SSH server on localhost that executes each command with
+// sh, as sshd does, with a fake sudo first on the PATH. It returns the host
+// to connect to (password auth, pinned host key) and the sudo log.
+func sshServer(t *testing.T) (SSHHost, string) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "sudo"), []byte(sudoScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sudoLog := filepath.Join(dir, "sudo.log")
+	env := append(os.Environ(), "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"), "SUDO_LOG="+sudoLog)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == "auditor" && string(pass) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("denied")
+		},
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, config, env)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	knownHosts := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{addr.String()}, signer.PublicKey())
+	if err := os.WriteFile(knownHosts, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SENTINELX_TEST_SSH_PASSWORD", "secret")
+	host := SSHHost{
+		Name: "test", Address: "127.0.0.1", Port: addr.Port, User: "auditor",
+		PasswordEnv: "SENTINELX_TEST_SSH_PASSWORD", KnownHosts: knownHosts,
+	}
+	return host, sudoLog
+}
+
+func serveSSH(conn net.Conn, config *ssh.ServerConfig, env []string) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "sessions only")
+			continue
+		}
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer ch.Close()
+			for req := range requests {
+				var exec struct{ Command string }
+				if req.Type != "exec" || ssh.Unmarshal(req.Payload, &exec) != nil {
+					req.Reply(false, nil)
+					continue
+				}
+				req.Reply(true, nil)
+				status := runShell(ch, exec.Command, env)
+				ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+				return
+			}
+		}()
+	}
+}
+
+func runShell(ch ssh.Channel, command string, env []string) uint32 {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = env
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 255
+	}
+	cmd.Stdout, cmd.Stderr = ch, ch.Stderr()
+	if err := cmd.Start(); err != nil {
+		return 127
+	}
+	go func() {
+		io.Copy(stdin, ch)
+		stdin.Close()
+	}()
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return uint32(exitErr.ExitCode())
+	}
+	if err != nil {
+		return 255
+	}
+	return 0
+}
+
+// The file operations work on paths the shell would otherwise split or
+// expand, with and without sudo, and everything runs under sudo when asked.
+func TestSSHHardener(t *testing.T) {
+	for _, sudo := range []bool{false, true} {
+		name := "plain"
+		if sudo {
+			name = "sudo"
+		}
+		t.Run(name, func(t *testing.T) {
+			host, sudoLog := sshServer(t)
+			host.Sudo = sudo
+			s, err := NewSSHHardener(host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			dir := filepath.Join(t.TempDir(), `it's a "dir" $(echo x)`)
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			conf := filepath.Join(dir, "a b.conf")
+
+			if _, err := s.ReadFile(conf); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("ReadFile of a missing file: %v", err)
+			}
+			if ok, err := s.CheckFilePermission(conf, "0600", "", ""); ok || err != nil {
+				t.Errorf("CheckFilePermission of a missing file: %v, %v", ok, err)
+			}
+			if err := s.WriteFile(conf, []byte("x=1\n"), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			if data, err := s.ReadFile(conf); err != nil || string(data) != "x=1\n" {
+				t.Errorf("ReadFile: %q, %v", data, err)
+			}
+			if ok, err := s.CheckFilePermission(conf, "0600", "", ""); !ok || err != nil {
+				t.Errorf("CheckFilePermission: %v, %v", ok, err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "other.txt"), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if matches, err := s.Glob(filepath.Join(dir, "*.conf")); err != nil || !reflect.DeepEqual(matches, []string{conf}) {
+				t.Errorf("Glob: %q, %v", matches, err)
+			}
+			if matches, err := s.Glob(filepath.Join(dir, "*.none")); err != nil || len(matches) != 0 {
+				t.Errorf("Glob without matches: %q, %v", matches, err)
+			}
+			if ok, out, err := s.RunCommand("printf", []string{"%s", "$(echo injected)"}, "^\\$\\(echo injected\\)$"); !ok || err != nil {
+				t.Errorf("RunCommand: %v, %q, %v", ok, out, err)
+			}
+
+			log, _ := os.ReadFile(sudoLog)
+			if !sudo && len(log) > 0 {
+				t.Errorf("sudo ran without Sudo set:\n%s", log)
+			}
+			if sudo {
+				// One call per run: ReadFile, stat, WriteFile's test, upload
+				// and chmod, ReadFile, stat, two Globs and the command
+				want := "-n sh\n-n sh\n-n test\n-n sh\n-n chmod\n-n sh\n-n sh\n-n sh\n-n sh\n-n printf\n"
+				if string(log) != want {
+					t.Errorf("sudo calls:\n%s\nwant:\n%s", log, want)
+				}
+			}
+		})
+	}
+}
+
+// sshd Include directives are expanded with the remote Glob and edits to
+// included files are written back over SSH.
+func TestSSHConfigInclude(t *testing.T) {
+	host, _ := sshServer(t)
+	host.Sudo = true
+	s, err := NewSSHHardener(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	dir := t.TempDir()
+	main := filepath.Join(dir, "sshd_config")
+	dropIn := filepath.Join(dir, "sshd_config.d", "50-cloud.conf")
+	if err := os.MkdirAll(filepath.Dir(dropIn), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		main:   "Include " + filepath.Join(dir, "sshd_config.d", "*.conf") + "\nPermitRootLogin no\n",
+		dropIn: "PermitRootLogin yes\n",
+	}
+	for p, data := range files {
+		if err := os.WriteFile(p, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := confedit.Load(s, confedit.SSHD, main)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	value, where, ok := c.Get("PermitRootLogin", "")
+	if !ok || value != "yes" || where.File.Path != dropIn {
+		t.Fatalf("PermitRootLogin = %q in %v, want yes from the drop-in", value, where)
+	}
+	if err := c.Set("PermitRootLogin", "", "no"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if data, _ := os.ReadFile(dropIn); string(data) != "PermitRootLogin no\n" {
+		t.Errorf("drop-in after the edit: %q", data)
+	}
+}
@@ -4,10 +4,13 @@ package platform
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
-	"golang.org/x/sys/windows/registry"
+	"sih2025/internal/winreg"
 )
 
 // WindowsHardener is the implementation for Windows OS
@@ -17,6 +20,12 @@ type WindowsHardener struct{}
 func getPlatformInstance() HardenerInterface {
 	return &WindowsHardener{}
 }
+
+// NewRootHardener is Linux only; offline images are audited from a Linux host.
+func NewRootHardener(root string) (HardenerInterface, error) {
+	return nil, fmt.Errorf("--root is only supported on Linux")
+}
+
 func (w *WindowsHardener) EditConfigFile(path string, searchRegex string, replaceText string) error {
 	return nil // Not used on Windows
 }
@@ -25,65 +34,9 @@ func (w *WindowsHardener) GetOSName() string {
 	return "windows"
 }
 
-// CheckRegistry checks if a specific registry key matches the expected value
-func (w *WindowsHardener) CheckRegistry(keyPath, valueName string, expectedValue interface{}) (bool, error) {
-	var rootKey registry.Key
-	shortPath := keyPath
-
-	if strings.HasPrefix(keyPath, "HKLM\\") {
-		rootKey = registry.LOCAL_MACHINE
-		shortPath = strings.TrimPrefix(keyPath, "HKLM\\")
-	} else if strings.HasPrefix(keyPath, "HKCU\\") {
-		rootKey = registry.CURRENT_USER
-		shortPath = strings.TrimPrefix(keyPath, "HKCU\\")
-	} else {
-		return false, fmt.Errorf("unsupported root key: %s", keyPath)
-	}
-
-	k, err := registry.OpenKey(rootKey, shortPath, registry.QUERY_VALUE)
-	if err != nil {
-		return false, nil // Key not found = Fail
-	}
-	defer k.Close()
-
-	// Get the Value
-	// We handle Integers (DWORD) and Strings (SZ)
-	_, valType, err := k.GetValue(valueName, nil)
-	if err != nil {
-		return false, nil
-	}
-
-	if valType == registry.DWORD || valType == registry.QWORD {
-		val, _, err := k.GetIntegerValue(valueName)
-		if err != nil {
-			return false, nil
-		}
-
-		// Normalize expectedValue
-		expectedInt, ok := expectedValue.(float64)
-		if !ok {
-			if eInt, ok := expectedValue.(int); ok {
-				expectedInt = float64(eInt)
-			}
-		}
-		if val == uint64(expectedInt) {
-			return true, nil
-		}
-
-	} else if valType == registry.SZ || valType == registry.EXPAND_SZ {
-		val, _, err := k.GetStringValue(valueName)
-		if err != nil {
-			return false, nil
-		}
-
-		if expectedStr, ok := expectedValue.(string); ok {
-			if val == expectedStr {
-				return true, nil
-			}
-		}
-	}
-
-	return false, nil
+// RegistryBackend returns the host registry
+func (w *WindowsHardener) RegistryBackend() winreg.Backend {
+	return winreg.NewNative()
 }
 
 // RunCommand executes PowerShell or CMD commands securely
@@ -107,41 +60,6 @@ func (w *WindowsHardener) RunCommand(cmdStr string, args []string, expectPattern
 	return false, string(output), nil
 }
 
-// SetRegistry applies the fix (With "Self-Healing" CreateKey logic)
-func (w *WindowsHardener) SetRegistry(keyPath, valueName string, value interface{}) error {
-	var rootKey registry.Key
-	shortPath := keyPath
-
-	if strings.HasPrefix(keyPath, "HKLM\\") {
-		rootKey = registry.LOCAL_MACHINE
-		shortPath = strings.TrimPrefix(keyPath, "HKLM\\")
-	} else if strings.HasPrefix(keyPath, "HKCU\\") {
-		rootKey = registry.CURRENT_USER
-		shortPath = strings.TrimPrefix(keyPath, "HKCU\\")
-	} else {
-		return fmt.Errorf("unsupported root key")
-	}
-
-	// SMART FIX: CreateKey opens it if it exists, creates it if missing.
-	k, _, err := registry.CreateKey(rootKey, shortPath, registry.SET_VALUE)
-	if err != nil {
-		return fmt.Errorf("failed to create/open key: %v", err)
-	}
-	defer k.Close()
-
-	// Determine type and set value
-	switch v := value.(type) {
-	case int:
-		return k.SetDWordValue(valueName, uint32(v))
-	case float64:
-		return k.SetDWordValue(valueName, uint32(v))
-	case string:
-		return k.SetStringValue(valueName, v)
-	default:
-		return fmt.Errorf("unsupported value type")
-	}
-}
-
 // --- REQUIRED STUBS (To Satisfy Interface) ---
 
 // CheckFilePermission is not used on Windows Registry hardening, so we return true/nil
@@ -162,3 +80,67 @@ func (w *WindowsHardener) CheckFileContent(path string, searchPatterns []string,
 	// Return true to indicate this check is not applicable
 	return true, nil
 }
+
+func (w *WindowsHardener) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (w *WindowsHardener) WriteFile(path string, data []byte, mode os.FileMode) error {
+	return os.WriteFile(path, data, mode)
+}
+
+func (w *WindowsHardener) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// BootID is the MachineGuid and the last boot time
+func (w *WindowsHardener) BootID() (string, string, error) {
+	guid, err := w.RegistryBackend().GetValue(`HKLM\SOFTWARE\Microsoft\Cryptography`, "MachineGuid")
+	if err != nil {
+		return "", "", fmt.Errorf("machine guid: %v", err)
+	}
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+		"(Get-CimInstance Win32_OperatingSystem).LastBootUpTime.ToUniversalTime().ToString('o')").Output()
+	if err != nil {
+		return "", "", fmt.Errorf("last boot time: %v", err)
+	}
+	return guid.String, strings.TrimSpace(string(out)), nil
+}
+
+// BackupFile copies the file under %ProgramData%\SentinelX\backups
+func (w *WindowsHardener) BackupFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	dst := backupName(filepath.Join(os.Getenv("ProgramData"), "SentinelX", "backups"), path, time.Now())
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return "", fmt.Errorf("backup %s: %v", path, err)
+	}
+	if err := os.WriteFile(dst, data, 0600); err != nil {
+		return "", fmt.Errorf("backup %s: %v", path, err)
+	}
+	return dst, nil
+}
+
+// RestoreFile copies a backup back over path
+func (w *WindowsHardener) RestoreFile(path string, backup string) error {
+	data, err := os.ReadFile(backup)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("restore %s: %v", path, err)
+	}
+	return nil
+}
+
+func (w *WindowsHardener) RemoveFile(path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,182 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Comparison is a typed condition on the value a check extracts, for
+// controls like "PASS_MAX_DAYS 365 or less" that a regex can't express.
+type Comparison struct {
+	Op        string   `json:"op"`                  // lt, le, ge, gt, eq, range, one_of, subset (or <, <=, >=, >, ==)
+	Value     *float64 `json:"value,omitempty"`     // lt, le, ge, gt, eq
+	Min       *float64 `json:"min,omitempty"`       // range, inclusive
+	Max       *float64 `json:"max,omitempty"`       // range, inclusive
+	Values    []string `json:"values,omitempty"`    // one_of, subset; matched case-insensitively
+	Separator string   `json:"separator,omitempty"` // subset: list separator in the value (default ",")
+}
+
+// opAliases maps the symbolic operators to their names.
+var opAliases = map[string]string{
+	"<": "lt", "<=": "le", ">=": "ge", ">": "gt", "==": "eq", "=": "eq", "in": "one_of",
+}
+
+func (c *Comparison) op() string {
+	op := strings.ToLower(strings.TrimSpace(c.Op))
+	if alias, ok := opAliases[op]; ok {
+		return alias
+	}
+	return op
+}
+
+// Validate checks that the comparison has the operands its operator needs.
+func (c *Comparison) Validate() error {
+	switch c.op() {
+	case "lt", "le", "ge", "gt", "eq":
+		if c.Value == nil {
+			return fmt.Errorf("compare %q needs a numeric \"value\"", c.Op)
+		}
+	case "range":
+		if c.Min == nil || c.Max == nil {
+			return fmt.Errorf("compare \"range\" needs \"min\" and \"max\"")
+		}
+		if *c.Min > *c.Max {
+			return fmt.Errorf("compare \"range\": min %v is above max %v", *c.Min, *c.Max)
+		}
+	case "one_of", "subset":
+		if len(c.Values) == 0 {
+			return fmt.Errorf("compare %q needs \"values\"", c.Op)
+		}
+	default:
+		return fmt.Errorf("unknown compare op %q", c.Op)
+	}
+	return nil
+}
+
+// Evaluate applies the comparison to an extracted value. Numeric operators
+// fail (without an error) on values that aren't numbers, and subset fails on
+// a value with no items.
+func (c *Comparison) Evaluate(actual string) (bool, error) {
+	if err := c.Validate(); err != nil {
+		return false, err
+	}
+	actual = strings.TrimSpace(actual)
+
+	switch op := c.op(); op {
+	case "one_of":
+		for _, v := range c.Values {
+			if strings.EqualFold(actual, strings.TrimSpace(v)) {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	case "subset":
+		allowed := make(map[string]bool, len(c.Values))
+		for _, v := range c.Values {
+			allowed[strings.ToLower(strings.TrimSpace(v))] = true
+		}
+		// An empty list isn't a subset that passes: nothing was configured
+		items := c.Items(actual)
+		if len(items) == 0 {
+			return false, nil
+		}
+		for _, item := range items {
+			if !allowed[strings.ToLower(item)] {
+				return false, nil
+			}
+		}
+		return true, nil
+
+	default:
+		n, err := strconv.ParseFloat(actual, 64)
+		if err != nil {
+			return false, nil
+		}
+		switch op {
+		case "lt":
+			return n < *c.Value, nil
+		case "le":
+			return n <= *c.Value, nil
+		case "ge":
+			return n >= *c.Value, nil
+		case "gt":
+			return n > *c.Value, nil
+		case "eq":
+			return n == *c.Value, nil
+		}
+		return n >= *c.Min && n <= *c.Max, nil
+	}
+}
+
+// Items splits a list value on the separator, dropping empty items.
+func (c *Comparison) Items(value string) []string {
+	var parts []string
+	if sep := c.Separator; sep == "" {
+		parts = strings.Split(value, ",")
+	} else if strings.TrimSpace(sep) == "" {
+		parts = strings.Fields(value)
+	} else {
+		parts = strings.Split(value, sep)
+	}
+	items := parts[:0]
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			items = append(items, p)
+		}
+	}
+	return items
+}
+
+// String describes the condition for reports, e.g. "<= 365" or "one of: yes, no".
+func (c *Comparison) String() string {
+	num := func(f *float64) string {
+		if f == nil {
+			return "?"
+		}
+		return strconv.FormatFloat(*f, 'f', -1, 64)
+	}
+	switch c.op() {
+	case "lt":
+		return "< " + num(c.Value)
+	case "le":
+		return "<= " + num(c.Value)
+	case "ge":
+		return ">= " + num(c.Value)
+	case "gt":
+		return "> " + num(c.Value)
+	case "eq":
+		return "= " + num(c.Value)
+	case "range":
+		return fmt.Sprintf("between %s and %s", num(c.Min), num(c.Max))
+	case "one_of":
+		return "one of: " + strings.Join(c.Values, ", ")
+	case "subset":
+		return "only: " + strings.Join(c.Values, ", ")
+	}
+	return c.Op
+}
+
+// ExtractValue pulls the value to compare out of a check's output: the
+// pattern's first capture group (or whole match when it has none), or the
+// trimmed output when there is no pattern. ok is false when nothing matched.
+func ExtractValue(pattern, output string) (value string, ok bool, err error) {
+	output = strings.TrimSpace(output)
+	if pattern == "" {
+		return output, output != "", nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid expect_pattern: %v", err)
+	}
+	m := re.FindStringSubmatch(output)
+	if m == nil {
+		return "", false, nil
+	}
+	if len(m) > 1 {
+		return strings.TrimSpace(m[1]), true, nil
+	}
+	return strings.TrimSpace(m[0]), true, nil
+}
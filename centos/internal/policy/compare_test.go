@@ -0,0 +1,111 @@
+package policy
+
+import "testing"
+
+func num(f float64) *float64 { return &f }
+
+func TestComparisonEvaluate(t *testing.T) {
+	tests := []struct {
+		name   string
+		c      Comparison
+		actual string
+		want   bool
+	}{
+		{"lt", Comparison{Op: "lt", Value: num(5)}, "4", true},
+		{"lt equal", Comparison{Op: "<", Value: num(5)}, "5", false},
+		{"le", Comparison{Op: "le", Value: num(365)}, "365", true},
+		{"le above", Comparison{Op: "<=", Value: num(365)}, "99999", false},
+		{"ge", Comparison{Op: "ge", Value: num(14)}, " 14\n", true},
+		{"ge below", Comparison{Op: ">=", Value: num(14)}, "13.9", false},
+		{"gt", Comparison{Op: "gt", Value: num(0)}, "1", true},
+		{"gt equal", Comparison{Op: ">", Value: num(0)}, "0", false},
+		{"eq", Comparison{Op: "eq", Value: num(0)}, "0.0", true},
+		{"eq alias", Comparison{Op: "=", Value: num(1)}, "2", false},
+		{"upper-case op", Comparison{Op: " LE ", Value: num(3)}, "3", true},
+		{"not a number", Comparison{Op: "le", Value: num(365)}, "never", false},
+		{"empty number", Comparison{Op: "ge", Value: num(0)}, "", false},
+		{"range", Comparison{Op: "range", Min: num(1), Max: num(7)}, "7", true},
+		{"range min", Comparison{Op: "range", Min: num(1), Max: num(7)}, "1", true},
+		{"range below", Comparison{Op: "range", Min: num(1), Max: num(7)}, "0", false},
+		{"range above", Comparison{Op: "range", Min: num(1), Max: num(7)}, "8", false},
+		{"one_of", Comparison{Op: "one_of", Values: []string{"yes", " no "}}, "No", true},
+		{"one_of alias", Comparison{Op: "in", Values: []string{"yes"}}, "maybe", false},
+		{"one_of empty", Comparison{Op: "one_of", Values: []string{"yes"}}, "", false},
+		{"subset", Comparison{Op: "subset", Values: []string{"aes256-gcm", "AES128-GCM"}}, "aes128-gcm, aes256-gcm", true},
+		{"subset extra item", Comparison{Op: "subset", Values: []string{"aes256-gcm"}}, "aes256-gcm,3des-cbc", false},
+		{"subset drops empty items", Comparison{Op: "subset", Values: []string{"a"}}, ",a,,", true},
+		{"subset empty", Comparison{Op: "subset", Values: []string{"aes256-gcm"}}, "", false},
+		{"subset only separators", Comparison{Op: "subset", Values: []string{"a"}}, " , ,", false},
+		{"subset separator", Comparison{Op: "subset", Values: []string{"a", "b"}, Separator: ":"}, "a:b", true},
+		{"subset separator not split on commas", Comparison{Op: "subset", Values: []string{"a", "b"}, Separator: ":"}, "a,b", false},
+		{"subset whitespace separator", Comparison{Op: "subset", Values: []string{"a", "b"}, Separator: " "}, " a \t b\n", true},
+		{"subset whitespace empty", Comparison{Op: "subset", Values: []string{"a"}, Separator: " "}, "  ", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.c.Evaluate(tt.actual)
+			if err != nil {
+				t.Fatalf("Evaluate(%q): %v", tt.actual, err)
+			}
+			if got != tt.want {
+				t.Errorf("%s on %q = %v, want %v", tt.c.String(), tt.actual, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComparisonValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Comparison
+	}{
+		{"no value", Comparison{Op: "le"}},
+		{"range without max", Comparison{Op: "range", Min: num(1)}},
+		{"range min above max", Comparison{Op: "range", Min: num(7), Max: num(1)}},
+		{"one_of without values", Comparison{Op: "one_of"}},
+		{"subset without values", Comparison{Op: "subset"}},
+		{"unknown op", Comparison{Op: "like", Value: num(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.c.Validate(); err == nil {
+				t.Error("Validate accepted it")
+			}
+			if _, err := tt.c.Evaluate("1"); err == nil {
+				t.Error("Evaluate accepted it")
+			}
+		})
+	}
+}
+
+func TestExtractValue(t *testing.T) {
+	tests := []struct {
+		name, pattern, output string
+		want                  string
+		ok                    bool
+	}{
+		{"no pattern", "", "  365\n", "365", true},
+		{"no pattern, no output", "", " \n", "", false},
+		{"capture group", `PASS_MAX_DAYS\s+(\d+)`, "# comment\nPASS_MAX_DAYS   90\n", "90", true},
+		{"first group only", `(\w+)=(\w+)`, "key=value", "key", true},
+		{"group trimmed", `Ciphers(.*)`, "Ciphers  aes256-gcm ", "aes256-gcm", true},
+		{"whole match", `\d+`, "maxretry 5", "5", true},
+		{"no match", `PASS_MAX_DAYS\s+(\d+)`, "PASS_MIN_DAYS 1", "", false},
+		{"empty group", `Ciphers\s*(\S*)$`, "Ciphers", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := ExtractValue(tt.pattern, tt.output)
+			if err != nil {
+				t.Fatalf("ExtractValue: %v", err)
+			}
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("ExtractValue(%q, %q) = %q, %v; want %q, %v", tt.pattern, tt.output, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+
+	if _, _, err := ExtractValue(`(`, "x"); err == nil {
+		t.Error("an invalid pattern was accepted")
+	}
+}
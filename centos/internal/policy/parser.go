@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
+
+	"sih2025/internal/confedit"
 )
 
 // LoadPolicy reads a JSON file and returns the Policy struct.
@@ -22,5 +25,60 @@ func LoadPolicy(filePath string) (*Policy, error) {
 		return nil, fmt.Errorf("failed to decode JSON: %v", err)
 	}
 
+	// 3. Catch broken comparisons and config keys before they show up as failing checks
+	for _, r := range policy.Rules {
+		if r.Check.Compare != nil {
+			if err := r.Check.Compare.Validate(); err != nil {
+				return nil, fmt.Errorf("rule %s: %v", r.ID, err)
+			}
+		}
+		if r.Type == "config_key" {
+			if err := validConfigKey(r.Check.ConfigFormat, r.Check.ConfigKey); err != nil {
+				return nil, fmt.Errorf("rule %s: check: %v", r.ID, err)
+			}
+		}
+		for _, a := range []Action{r.Remediation, r.Rollback} {
+			if a.Type == "config_key" {
+				if err := validConfigKey(a.ConfigFormat, a.ConfigKey); err != nil {
+					return nil, fmt.Errorf("rule %s: %v", r.ID, err)
+				}
+			}
+			for _, svc := range a.Services {
+				if err := svc.Validate(); err != nil {
+					return nil, fmt.Errorf("rule %s: %v", r.ID, err)
+				}
+			}
+		}
+	}
+
 	return &policy, nil
-}
\ No newline at end of file
+}
+
+// validConfigKey checks a config_key check or action names a known format and a key.
+func validConfigKey(format, key string) error {
+	if confedit.DefaultFile(format) == "" {
+		return fmt.Errorf("unknown config_format %q", format)
+	}
+	if key == "" {
+		return fmt.Errorf("config_key is empty")
+	}
+	return nil
+}
+
+// serviceNamePattern is a systemd unit or Windows service name; nothing that
+// would be read as an option
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_@][A-Za-z0-9_@.:\\-]*$`)
+
+// Validate checks the action is known and has a service name where one is needed.
+func (s ServiceAction) Validate() error {
+	switch s.Action {
+	case ServiceDaemonReload, ServiceSysctl:
+		return nil
+	case ServiceReload, ServiceRestart:
+		if !serviceNamePattern.MatchString(s.Name) {
+			return fmt.Errorf("service %s needs a unit name, got %q", s.Action, s.Name)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown service action %q (want %s, %s, %s or %s)", s.Action, ServiceDaemonReload, ServiceSysctl, ServiceReload, ServiceRestart)
+}
@@ -0,0 +1,21 @@
+package policy
+
+// FilterByProfile returns the rules included in a hardening profile.
+// "strict" keeps everything, "moderate" drops Low, "basic" keeps Critical and High.
+func FilterByProfile(rules []Rule, profile string) []Rule {
+	filteredRules := []Rule{}
+	for _, rule := range rules {
+		if profile == "strict" {
+			filteredRules = append(filteredRules, rule)
+		} else if profile == "moderate" {
+			if rule.Severity == "Critical" || rule.Severity == "High" || rule.Severity == "Medium" {
+				filteredRules = append(filteredRules, rule)
+			}
+		} else if profile == "basic" {
+			if rule.Severity == "Critical" || rule.Severity == "High" {
+				filteredRules = append(filteredRules, rule)
+			}
+		}
+	}
+	return filteredRules
+}
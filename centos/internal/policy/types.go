@@ -2,15 +2,30 @@ package policy
 
 // Rule maps directly to the JSON object in your Annexure files
 type Rule struct {
-	ID          string      `json:"id"`
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	Severity    string      `json:"severity"` // "Critical", "High", "Medium", "Low"
-	Platform    string      `json:"platform"` // "windows", "linux"
-	Type        string      `json:"type"`     // "registry", "command", "file_check", "file_edit", "secedit", "manual"
-	Tags        []string    `json:"tags"`     // e.g. ["firewall", "account"]
-	DependsOn   []string    `json:"depends_on"`
-	
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Severity    string   `json:"severity"` // "Critical", "High", "Medium", "Low"
+	Platform    string   `json:"platform"` // "windows", "linux"
+	Type        string   `json:"type"`     // "registry", "command", "file_check", "file_edit", "config_key", "secedit", "manual"
+	Tags        []string `json:"tags"`     // e.g. ["firewall", "account"]
+	DependsOn   []string `json:"depends_on"`
+
+	// Documentation, filled in by hand or by the XCCDF importer
+	Category    string   `json:"category,omitempty"`
+	Rationale   string   `json:"rationale,omitempty"`
+	References  []string `json:"references,omitempty"`
+	NeedsReview bool     `json:"needs_review,omitempty"` // imported rule that could not be fully automated
+
+	// XCCDFRuleID names this rule in SCAP result exports (xccdf_<namespace>_rule_<name>),
+	// so results merge with other scanners' output. Defaults to one derived from ID.
+	XCCDFRuleID string `json:"xccdf_rule_id,omitempty"`
+
+	// RequiresReboot marks fixes that only take effect after the next boot
+	// (kernel command line, module blacklists, some mount options). Until then
+	// the rule reports PENDING_REBOOT.
+	RequiresReboot bool `json:"requires_reboot,omitempty"`
+
 	Check       CheckAction `json:"check"`
 	Remediation Action      `json:"remediation"`
 	Rollback    Action      `json:"rollback"`
@@ -22,18 +37,30 @@ type CheckAction struct {
 	Args          []string `json:"args,omitempty"`
 	ExpectPattern string   `json:"expect_pattern,omitempty"`
 
+	// Compare replaces the pattern match with a typed condition on the value
+	// ExpectPattern's first capture group extracts (the whole output when
+	// there is no pattern), e.g. {"op": "le", "value": 365}
+	Compare *Comparison `json:"compare,omitempty"`
+
 	// Registry (Windows only)
 	RegKey   string      `json:"reg_key,omitempty"`
 	RegValue string      `json:"reg_value,omitempty"`
-	Expected interface{} `json:"expected,omitempty"` // Can be int (4) or string ("No One")
+	Expected interface{} `json:"expected,omitempty"` // Can be int (4), string ("No One"), array (REG_MULTI_SZ) or {"type": "REG_QWORD", "data": 1}
+	RegType  string      `json:"reg_type,omitempty"` // REG_SZ, REG_EXPAND_SZ, REG_MULTI_SZ, REG_DWORD, REG_QWORD, REG_BINARY or ABSENT; default inferred from Expected
 
 	// File based checks
 	FilePath string `json:"file_path,omitempty"`
 	FileMode string `json:"file_mode,omitempty"`
+
+	// Structured config files (config_key), read by internal/confedit.
+	// FilePath overrides the format's default file
+	ConfigFormat  string `json:"config_format,omitempty"`  // sshd, login_defs, sysctl, limits, modprobe, audit
+	ConfigKey     string `json:"config_key,omitempty"`     // e.g. PermitRootLogin, "* hard core", "install cramfs"
+	ConfigSection string `json:"config_section,omitempty"` // sshd Match criteria, e.g. "User backup"
 }
 
 type Action struct {
-	Type string `json:"type"` // "command", "registry", "file_edit", "manual"
+	Type string `json:"type"` // "command", "registry", "registry_delete_value", "registry_delete_key", "file_edit", "config_key", "manual"
 
 	// Command
 	Cmd  string   `json:"cmd,omitempty"`
@@ -42,12 +69,62 @@ type Action struct {
 	// Registry
 	RegKey   string      `json:"reg_key,omitempty"`
 	RegValue string      `json:"reg_value,omitempty"`
-	Value    interface{} `json:"value,omitempty"` // The new value to set
+	Value    interface{} `json:"value,omitempty"`    // The new value to set, typed like CheckAction.Expected
+	RegType  string      `json:"reg_type,omitempty"` // see CheckAction.RegType
 
 	// File editing (Linux specific)
 	FilePath    string `json:"file_path,omitempty"`
 	SearchRegex string `json:"search_regex,omitempty"`
 	ReplaceText string `json:"replace_text,omitempty"`
+
+	// Structured config files (config_key): sets ConfigKey to Value, or
+	// comments it out when Value is null. See CheckAction
+	ConfigFormat  string `json:"config_format,omitempty"`
+	ConfigKey     string `json:"config_key,omitempty"`
+	ConfigSection string `json:"config_section,omitempty"`
+
+	// Validate runs after the change (e.g. sshd -t, visudo -c); when it
+	// fails the fix is undone
+	Validate *Validation `json:"validate,omitempty"`
+
+	// Services to reload/restart once the batch of fixes is done
+	Services []ServiceAction `json:"services,omitempty"`
+}
+
+// Service actions, in the order they run after a batch of fixes
+const (
+	ServiceDaemonReload = "daemon-reload" // systemctl daemon-reload (no service name)
+	ServiceSysctl       = "sysctl"        // sysctl --system (no service name)
+	ServiceReload       = "reload"
+	ServiceRestart      = "restart"
+)
+
+// ServiceRank orders service actions: unit files and kernel settings first,
+// then reloads, then restarts (which cover a reload of the same service).
+func ServiceRank(action string) int {
+	switch action {
+	case ServiceDaemonReload:
+		return 0
+	case ServiceSysctl:
+		return 1
+	case ServiceReload:
+		return 2
+	}
+	return 3
+}
+
+// ServiceAction is what a change needs to take effect, e.g. {"name": "sshd",
+// "action": "reload"} after editing sshd_config.
+type ServiceAction struct {
+	Name   string `json:"name,omitempty"`
+	Action string `json:"action"`
+}
+
+// Validation is a command that must exit 0 for a change to stand. "{file}" in
+// Args stands for the file the action edits.
+type Validation struct {
+	Cmd  string   `json:"cmd"`
+	Args []string `json:"args,omitempty"`
 }
 
 type Policy struct {
@@ -0,0 +1,68 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/signing"
+)
+
+// Bundle is the machine readable copy of a report. Its SHA-256 is the
+// manifest digest printed in the PDF footer.
+type Bundle struct {
+	Tool        string               `json:"tool"`
+	GeneratedAt string               `json:"generated_at"`
+	Target      string               `json:"target"`
+	Results     []engine.AuditResult `json:"results"`
+}
+
+// manifest describes the signed bundle that backs a PDF report.
+type manifest struct {
+	Digest    string
+	Signature string
+	KeyID     string
+}
+
+// writeBundle stores the results as JSON next to the report and, when signing
+// is enabled, signs it. The returned manifest is empty if signing is off.
+func writeBundle(results []engine.AuditResult, targetSystem string, generated time.Time, filename string) (manifest, error) {
+	data, err := json.MarshalIndent(Bundle{
+		Tool:        "SentinelX",
+		GeneratedAt: generated.UTC().Format(time.RFC3339),
+		Target:      targetSystem,
+		Results:     results,
+	}, "", "  ")
+	if err != nil {
+		return manifest{}, err
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return manifest{}, err
+	}
+	if !signing.Enabled() {
+		return manifest{}, nil
+	}
+
+	digest := sha256.Sum256(data)
+	sig, err := signing.SignDigest(digest[:])
+	if err != nil {
+		return manifest{}, err
+	}
+	if _, err := signing.SignFile(filename); err != nil {
+		return manifest{}, err
+	}
+	return manifest{
+		Digest:    hex.EncodeToString(digest[:]),
+		Signature: sig,
+		KeyID:     signing.CurrentKeyID(),
+	}, nil
+}
+
+// bundleName derives the bundle filename from the PDF filename.
+func bundleName(pdfName string) string {
+	return strings.TrimSuffix(pdfName, ".pdf") + ".json"
+}
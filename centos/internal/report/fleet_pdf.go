@@ -0,0 +1,124 @@
+package report
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"sih2025/internal/signing"
+	"sih2025/internal/state"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// GenerateFleetReport renders the collector's aggregated view: one row per
+// host (latest run) plus the rules failing on the most hosts.
+func GenerateFleetReport(hosts []state.FleetRun, failures []state.RuleFailure) (string, error) {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.AddPage()
+
+	// --- HEADER ---
+	pdf.SetFont("Arial", "B", 18)
+	pdf.Cell(40, 10, "SentinelX FLEET COMPLIANCE REPORT")
+	pdf.Ln(12)
+	pdf.SetFont("Arial", "", 10)
+	pdf.Cell(40, 10, fmt.Sprintf("Generated on: %s", time.Now().Format("02 Jan 2006 15:04:05")))
+	pdf.Ln(5)
+	pdf.Cell(40, 10, fmt.Sprintf("Hosts Reporting: %d", len(hosts)))
+	pdf.Ln(12)
+
+	// --- STATS (All controls across the fleet) ---
+	pass, fail := 0, 0
+	for _, h := range hosts {
+		pass += h.Pass
+		fail += h.Fail
+	}
+	total := pass + fail
+	percent := 0
+	if total > 0 {
+		percent = int((float64(pass) / float64(total) * 100) + 0.5)
+	}
+	drawBarChart(pdf, 10, 45, 130, 35, pass, fail, total, percent)
+	pdf.SetXY(10, 85)
+
+	// --- HOST TABLE ---
+	pdf.SetFont("Arial", "B", 8)
+	pdf.SetFillColor(50, 50, 60)
+	pdf.SetTextColor(255, 255, 255)
+	pdf.CellFormat(70, 10, "HOST", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(35, 10, "OS", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(30, 10, "PROFILE", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(50, 10, "LAST SCAN", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(30, 10, "PASS", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(30, 10, "FAIL", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(30, 10, "SCORE", "1", 1, "C", true, 0, "")
+
+	pdf.SetFont("Arial", "", 8)
+	for i, h := range hosts {
+		if i%2 == 0 {
+			pdf.SetFillColor(255, 255, 255)
+		} else {
+			pdf.SetFillColor(245, 245, 245)
+		}
+		score := 0
+		if h.Total > 0 {
+			score = int((float64(h.Pass) / float64(h.Total) * 100) + 0.5)
+		}
+
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(70, 8, h.Host, "1", 0, "L", true, 0, "")
+		pdf.CellFormat(35, 8, h.OS, "1", 0, "C", true, 0, "")
+		pdf.CellFormat(30, 8, h.Profile, "1", 0, "C", true, 0, "")
+		pdf.CellFormat(50, 8, h.ScannedAt.Format("02 Jan 2006 15:04"), "1", 0, "C", true, 0, "")
+		pdf.CellFormat(30, 8, fmt.Sprintf("%d", h.Pass), "1", 0, "C", true, 0, "")
+		pdf.CellFormat(30, 8, fmt.Sprintf("%d", h.Fail), "1", 0, "C", true, 0, "")
+		if score < 50 {
+			pdf.SetTextColor(200, 0, 0)
+		} else {
+			pdf.SetTextColor(0, 100, 0)
+		}
+		pdf.CellFormat(30, 8, fmt.Sprintf("%d%%", score), "1", 1, "C", true, 0, "")
+	}
+
+	// --- TOP FAILING RULES ---
+	if len(failures) > 0 {
+		pdf.Ln(8)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.SetFont("Arial", "B", 12)
+		pdf.Cell(40, 10, "Most Common Failures")
+		pdf.Ln(10)
+
+		pdf.SetFont("Arial", "B", 8)
+		pdf.SetFillColor(50, 50, 60)
+		pdf.SetTextColor(255, 255, 255)
+		pdf.CellFormat(40, 10, "ID", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(165, 10, "CONTROL DESCRIPTION", "1", 0, "L", true, 0, "")
+		pdf.CellFormat(30, 10, "SEV", "1", 0, "C", true, 0, "")
+		pdf.CellFormat(40, 10, "HOSTS FAILING", "1", 1, "C", true, 0, "")
+
+		pdf.SetFont("Arial", "", 8)
+		pdf.SetTextColor(0, 0, 0)
+		for i, f := range failures {
+			if i%2 == 0 {
+				pdf.SetFillColor(255, 255, 255)
+			} else {
+				pdf.SetFillColor(245, 245, 245)
+			}
+			pdf.CellFormat(40, 8, f.RuleID, "1", 0, "C", true, 0, "")
+			pdf.CellFormat(165, 8, f.Name, "1", 0, "L", true, 0, "")
+			pdf.CellFormat(30, 8, f.Severity, "1", 0, "C", true, 0, "")
+			pdf.CellFormat(40, 8, fmt.Sprintf("%d / %d", f.Hosts, len(hosts)), "1", 1, "C", true, 0, "")
+		}
+	}
+
+	filename := filepath.Join(Dir, "fleet_report_landscape.pdf")
+	if err := pdf.OutputFileAndClose(filename); err != nil {
+		return filename, err
+	}
+	if signing.Enabled() {
+		if _, err := signing.SignFile(filename); err != nil {
+			return filename, fmt.Errorf("failed to sign report: %v", err)
+		}
+	}
+	return filename, nil
+}
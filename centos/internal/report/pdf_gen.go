@@ -2,15 +2,20 @@ package report
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"sih2025/internal/engine"
+	"sih2025/internal/signing"
 	"sih2025/internal/state"
 
 	"github.com/jung-kurt/gofpdf"
 )
 
+// Dir is where generated reports, bundles and signatures are written.
+var Dir = "."
+
 // --- THE "HOAX" FILTER (Sanitizer) ---
 // This function ensures no ugly data ever reaches the PDF.
 func sanitize(text string, isPrevColumn bool) string {
@@ -44,8 +49,22 @@ func sanitize(text string, isPrevColumn bool) string {
 	return clean
 }
 
-func GenerateReport(results []engine.AuditResult, targetSystem string) (string, error) {
+// GenerateReport writes the PDF report of results; the before/after columns
+// come from the fix history of machine (see engine.HistoryMachine).
+func GenerateReport(results []engine.AuditResult, targetSystem, machine string) (string, error) {
+	filename := filepath.Join(Dir, "audit_report_landscape.pdf")
+	generated := time.Now()
+
+	// --- RESULT BUNDLE (signed manifest for the footer) ---
+	signed, err := writeBundle(results, targetSystem, generated, bundleName(filename))
+	if err != nil {
+		return "", err
+	}
+
 	pdf := gofpdf.New("L", "mm", "A4", "")
+	if signed.Digest != "" {
+		pdf.SetFooterFunc(func() { drawSignatureFooter(pdf, signed) })
+	}
 	pdf.AddPage()
 
 	// --- HEADER ---
@@ -53,20 +72,13 @@ func GenerateReport(results []engine.AuditResult, targetSystem string) (string,
 	pdf.Cell(40, 10, "SentinelX COMPLIANCE AUDIT REPORT")
 	pdf.Ln(12)
 	pdf.SetFont("Arial", "", 10)
-	pdf.Cell(40, 10, fmt.Sprintf("Generated on: %s", time.Now().Format("02 Jan 2006 15:04:05")))
+	pdf.Cell(40, 10, fmt.Sprintf("Generated on: %s", generated.Format("02 Jan 2006 15:04:05")))
 	pdf.Ln(5)
 	pdf.Cell(40, 10, fmt.Sprintf("Target System: %s", targetSystem))
 	pdf.Ln(12)
 
 	// --- STATS ---
-	pass, fail := 0, 0
-	for _, r := range results {
-		if r.Status == "FAIL" {
-			fail++
-		} else {
-			pass++
-		}
-	}
+	pass, fail := engine.Score(results)
 	total := pass + fail
 	percent := 0
 	if total > 0 {
@@ -117,7 +129,7 @@ func GenerateReport(results []engine.AuditResult, targetSystem string) (string,
 		}
 
 		// 1. Get Raw Data
-		prevRaw, newRaw, found := state.GetRuleHistory(item.ID)
+		prevRaw, newRaw, found := state.GetRuleHistory(machine, item.ID)
 
 		// 2. Logic to populate columns
 		colPrev := "-"
@@ -127,11 +139,25 @@ func GenerateReport(results []engine.AuditResult, targetSystem string) (string,
 			// We have a history fix
 			colPrev = prevRaw
 			colNew = newRaw
+			if item.Status == "PENDING_REBOOT" {
+				colNew += " (pending reboot)"
+			} else if v, ok := state.GetVerification(machine, item.ID); ok && v == "UNVERIFIED" {
+				colNew += " (unverified)"
+			}
+		} else if item.Status == "PENDING_REBOOT" {
+			colPrev = item.Actual
+			colNew = "Fixed, pending reboot"
 		} else {
 			// No history
 			if item.Status == "FAIL" {
 				colPrev = item.Actual
 				colNew = "Remediation Required"
+			} else if item.Status == "NOT_APPLICABLE" {
+				colPrev = item.Actual
+				colNew = "Not Applicable"
+			} else if item.Status == "WAIVED" {
+				colPrev = item.Actual
+				colNew = "Risk Accepted (see Waivers)"
 			} else {
 				// It passed check
 				colPrev = "Verified Secure" // Or "-"
@@ -141,7 +167,7 @@ func GenerateReport(results []engine.AuditResult, targetSystem string) (string,
 
 		// 3. APPLY THE "HOAX" SANITIZER
 		// This cleans up "Unknown", "nil", "-c echo", etc.
-		if item.Status == "FAIL" && !found {
+		if (item.Status == "FAIL" || item.Status == "NOT_APPLICABLE" || item.Status == "WAIVED") && !found {
 			colPrev = sanitize(colPrev, true)
 			// Don't sanitize "Remediation Required"
 		} else {
@@ -169,6 +195,10 @@ func GenerateReport(results []engine.AuditResult, targetSystem string) (string,
 			pdf.SetTextColor(0, 0, 139) // Blue (Fixed)
 		} else if item.Status == "FAIL" {
 			pdf.SetTextColor(180, 0, 0) // Red (Fail)
+		} else if item.Status == "NOT_APPLICABLE" {
+			pdf.SetTextColor(100, 100, 100) // Grey (N/A)
+		} else if item.Status == "WAIVED" {
+			pdf.SetTextColor(180, 110, 0) // Amber (Waived)
 		} else {
 			pdf.SetTextColor(0, 100, 0) // Green (Pass)
 		}
@@ -184,6 +214,18 @@ func GenerateReport(results []engine.AuditResult, targetSystem string) (string,
 			pdf.SetFillColor(255, 230, 230)
 			pdf.SetTextColor(200, 0, 0)
 			pdf.CellFormat(20, 8, "FAIL", "1", 1, "C", true, 0, "")
+		} else if item.Status == "NOT_APPLICABLE" {
+			pdf.SetFillColor(240, 240, 240)
+			pdf.SetTextColor(100, 100, 100)
+			pdf.CellFormat(20, 8, "N/A", "1", 1, "C", true, 0, "")
+		} else if item.Status == "WAIVED" {
+			pdf.SetFillColor(255, 243, 205)
+			pdf.SetTextColor(180, 110, 0)
+			pdf.CellFormat(20, 8, "WAIVED", "1", 1, "C", true, 0, "")
+		} else if item.Status == "PENDING_REBOOT" {
+			pdf.SetFillColor(225, 235, 255)
+			pdf.SetTextColor(0, 60, 160)
+			pdf.CellFormat(20, 8, "REBOOT", "1", 1, "C", true, 0, "")
 		} else {
 			pdf.SetFillColor(230, 255, 230)
 			pdf.SetTextColor(0, 100, 0)
@@ -191,9 +233,89 @@ func GenerateReport(results []engine.AuditResult, targetSystem string) (string,
 		}
 	}
 
-	filename := "audit_report_landscape.pdf"
-	err := pdf.OutputFileAndClose(filename)
-	return filename, err
+	drawWaiverSection(pdf, results)
+
+	if err := pdf.OutputFileAndClose(filename); err != nil {
+		return filename, err
+	}
+	if signing.Enabled() {
+		if _, err := signing.SignFile(filename); err != nil {
+			return filename, fmt.Errorf("failed to sign report: %v", err)
+		}
+	}
+	return filename, nil
+}
+
+// Risk acceptances: every result carrying a waiver, active or lapsed
+func drawWaiverSection(pdf *gofpdf.Fpdf, results []engine.AuditResult) {
+	var waived []engine.AuditResult
+	for _, r := range results {
+		if r.Waiver != nil {
+			waived = append(waived, r)
+		}
+	}
+	if len(waived) == 0 {
+		return
+	}
+
+	pdf.AddPage()
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Arial", "B", 14)
+	pdf.Cell(40, 10, "Risk Acceptances (Waivers)")
+	pdf.Ln(8)
+	pdf.SetFont("Arial", "", 9)
+	pdf.Cell(40, 10, "Waived controls are excluded from the compliance score. Expired waivers count as FAIL again.")
+	pdf.Ln(12)
+
+	pdf.SetFont("Arial", "B", 8)
+	pdf.SetFillColor(50, 50, 60)
+	pdf.SetTextColor(255, 255, 255)
+	pdf.CellFormat(25, 10, "ID", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(55, 10, "CONTROL", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(30, 10, "SCOPE", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(85, 10, "JUSTIFICATION", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(35, 10, "APPROVER", "1", 0, "L", true, 0, "")
+	pdf.CellFormat(25, 10, "EXPIRES", "1", 0, "C", true, 0, "")
+	pdf.CellFormat(20, 10, "STATE", "1", 1, "C", true, 0, "")
+
+	pdf.SetFont("Arial", "", 8)
+	for i, item := range waived {
+		if i%2 == 0 {
+			pdf.SetFillColor(255, 255, 255)
+		} else {
+			pdf.SetFillColor(245, 245, 245)
+		}
+		w := item.Waiver
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(25, 8, item.ID, "1", 0, "C", true, 0, "")
+		smartCell(pdf, item.Name, 55)
+		smartCell(pdf, fmt.Sprintf("%s: %s", w.Scope, w.Target), 30)
+		smartCell(pdf, w.Justification, 85)
+		smartCell(pdf, w.Approver, 35)
+		pdf.CellFormat(25, 8, w.ExpiresAt.Format("02 Jan 2006"), "1", 0, "C", true, 0, "")
+
+		if item.Status == "WAIVED" {
+			pdf.SetFillColor(255, 243, 205)
+			pdf.SetTextColor(180, 110, 0)
+			pdf.CellFormat(20, 8, "ACTIVE", "1", 1, "C", true, 0, "")
+		} else {
+			pdf.SetFillColor(255, 230, 230)
+			pdf.SetTextColor(200, 0, 0)
+			pdf.CellFormat(20, 8, "EXPIRED", "1", 1, "C", true, 0, "")
+		}
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// Footer: manifest digest and detached signature of the result bundle
+func drawSignatureFooter(pdf *gofpdf.Fpdf, signed manifest) {
+	pdf.SetY(-15)
+	pdf.SetFont("Courier", "", 6)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(0, 4, fmt.Sprintf("Manifest SHA-256: %s   Key ID: %s   Page %d", signed.Digest, signed.KeyID, pdf.PageNo()), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 4, fmt.Sprintf("Ed25519 Signature: %s", signed.Signature), "", 0, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Arial", "", 8)
 }
 
 // Helper: Auto-resize font for long text
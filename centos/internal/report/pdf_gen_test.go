@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/platform"
+	"sih2025/internal/policy"
+	"sih2025/internal/state"
+	"sih2025/internal/winreg"
+)
+
+// A Windows audit through the fake platform, one rule of each kind the
+// report has to show: a passing registry value, a failing user right and a
+// manual check.
+func fakeAudit(t *testing.T) []engine.AuditResult {
+	t.Helper()
+	f := platform.NewFakeHardener("windows")
+	f.Rights["SeDenyNetworkLogonRight"] = "*S-1-5-32-544"
+	const lsa = `HKLM\SYSTEM\CurrentControlSet\Control\Lsa`
+	if err := f.Registry.SetValue(lsa, "LimitBlankPasswordUse", winreg.Value{Type: winreg.DWORD, Integer: 1}); err != nil {
+		t.Fatal(err)
+	}
+	pol := &policy.Policy{Rules: []policy.Rule{
+		{ID: "WIN-REG-001", Name: "Limit blank passwords", Severity: "High", Type: "registry",
+			Check: policy.CheckAction{RegKey: lsa, RegValue: "LimitBlankPasswordUse", Expected: 1}},
+		{ID: "WIN-UR-001", Name: "Deny network logon to guests", Severity: "Medium", Type: "secedit",
+			Check: policy.CheckAction{RegKey: "SeDenyNetworkLogonRight", Expected: "Guests"}},
+		{ID: "WIN-MAN-001", Name: "Review BIOS password", Severity: "Low", Type: "manual"},
+	}}
+	return engine.RunAuditWith(f, pol)
+}
+
+func TestGenerateReport(t *testing.T) {
+	if err := state.Open(":memory:"); err != nil {
+		t.Fatal(err)
+	}
+	Dir = t.TempDir()
+	t.Cleanup(func() {
+		Dir = "."
+		state.DB.Close()
+		state.DB = nil
+	})
+
+	results := fakeAudit(t)
+	statuses := make(map[string]string)
+	for _, r := range results {
+		statuses[r.ID] = r.Status
+	}
+	want := map[string]string{"WIN-REG-001": "PASS", "WIN-UR-001": "FAIL", "WIN-MAN-001": "FAIL"}
+	if !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("audit statuses %v, want %v", statuses, want)
+	}
+
+	name, err := GenerateReport(results, "fake-windows", "")
+	if err != nil {
+		t.Fatalf("GenerateReport: %v", err)
+	}
+	if filepath.Dir(name) != Dir {
+		t.Errorf("report written to %s, want it under %s", name, Dir)
+	}
+	pdf, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		t.Errorf("%s is not a PDF", name)
+	}
+
+	// The bundle next to it carries the same results
+	data, err := os.ReadFile(bundleName(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		t.Fatalf("bundle: %v", err)
+	}
+	if bundle.Target != "fake-windows" || !reflect.DeepEqual(bundle.Results, results) {
+		t.Errorf("bundle %+v doesn't match the audit", bundle)
+	}
+}
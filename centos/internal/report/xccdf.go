@@ -0,0 +1,467 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/policy"
+	"sih2025/internal/signing"
+)
+
+const (
+	xccdfNS = "http://checklists.nist.gov/xccdf/1.2"
+
+	// xccdfNamespace is the reverse-DNS part of the IDs SentinelX generates
+	xccdfNamespace = "com.sentinelx"
+	benchmarkID    = "xccdf_" + xccdfNamespace + "_benchmark_sentinelx"
+	testSystem     = "cpe:/a:sentinelx:sentinelx:2.0"
+)
+
+// ScanInfo describes the audit run being exported as SCAP results.
+type ScanInfo struct {
+	Target    string // report label, e.g. "CENTOS SERVER (web01)"
+	Hostname  string
+	FQDN      string
+	Addresses []string
+	MACs      []string
+	OS        string
+	Profile   string
+	Started   time.Time
+	Finished  time.Time
+}
+
+// AddLocalFacts fills in this machine's host name and network addresses.
+func (s *ScanInfo) AddLocalFacts() {
+	s.Hostname, _ = os.Hostname()
+	if strings.Contains(s.Hostname, ".") {
+		s.FQDN = s.Hostname
+	}
+	ifaces, _ := net.Interfaces()
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		if mac := iface.HardwareAddr.String(); mac != "" {
+			s.MACs = append(s.MACs, strings.ToUpper(mac))
+		}
+		addrs, _ := iface.Addrs()
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLinkLocalUnicast() {
+				s.Addresses = append(s.Addresses, ipnet.IP.String())
+			}
+		}
+	}
+}
+
+// --- XCCDF 1.2 DOCUMENT ---
+
+type xccdfBenchmark struct {
+	XMLName    xml.Name         `xml:"http://checklists.nist.gov/xccdf/1.2 Benchmark"`
+	ID         string           `xml:"id,attr"`
+	Resolved   string           `xml:"resolved,attr"`
+	Status     xccdfStatus      `xml:"status"`
+	Title      string           `xml:"title"`
+	Version    string           `xml:"version"`
+	Models     []xccdfModel     `xml:"model"`
+	Rules      []xccdfRule      `xml:"Rule"`
+	TestResult *xccdfTestResult `xml:"TestResult"`
+}
+
+type xccdfStatus struct {
+	Date  string `xml:"date,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xccdfModel struct {
+	System string `xml:"system,attr"`
+}
+
+type xccdfRule struct {
+	ID          string       `xml:"id,attr"`
+	Selected    bool         `xml:"selected,attr"`
+	Severity    string       `xml:"severity,attr"`
+	Title       string       `xml:"title"`
+	Description string       `xml:"description,omitempty"`
+	Rationale   string       `xml:"rationale,omitempty"`
+	Idents      []xccdfIdent `xml:"ident"`
+}
+
+type xccdfIdent struct {
+	System string `xml:"system,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type xccdfTestResult struct {
+	XMLName    xml.Name
+	ID         string            `xml:"id,attr"`
+	StartTime  string            `xml:"start-time,attr"`
+	EndTime    string            `xml:"end-time,attr"`
+	TestSystem string            `xml:"test-system,attr"`
+	Version    string            `xml:"version,attr"`
+	Benchmark  *xccdfBenchRef    `xml:"benchmark"`
+	Title      string            `xml:"title"`
+	Remarks    []string          `xml:"remark"`
+	Identity   *xccdfIdentity    `xml:"identity"`
+	Targets    []string          `xml:"target"`
+	Addresses  []string          `xml:"target-address"`
+	Facts      []xccdfFact       `xml:"target-facts>fact"`
+	Results    []xccdfRuleResult `xml:"rule-result"`
+	Scores     []xccdfScore      `xml:"score"`
+}
+
+type xccdfBenchRef struct {
+	Href string `xml:"href,attr"`
+	ID   string `xml:"id,attr"`
+}
+
+type xccdfIdentity struct {
+	Authenticated bool   `xml:"authenticated,attr"`
+	Privileged    bool   `xml:"privileged,attr"`
+	Name          string `xml:",chardata"`
+}
+
+type xccdfFact struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xccdfRuleResult struct {
+	IDRef    string         `xml:"idref,attr"`
+	Severity string         `xml:"severity,attr"`
+	Time     string         `xml:"time,attr"`
+	Result   string         `xml:"result"`
+	Override *xccdfOverride `xml:"override"`
+	Idents   []xccdfIdent   `xml:"ident"`
+	Messages []xccdfMessage `xml:"message"`
+}
+
+type xccdfOverride struct {
+	Time      string `xml:"time,attr"`
+	Authority string `xml:"authority,attr"`
+	Old       string `xml:"old-result"`
+	New       string `xml:"new-result"`
+	Remark    string `xml:"remark"`
+}
+
+type xccdfMessage struct {
+	Severity string `xml:"severity,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type xccdfScore struct {
+	System  string `xml:"system,attr"`
+	Maximum string `xml:"maximum,attr"`
+	Value   string `xml:",chardata"`
+}
+
+// --- ARF 1.1 WRAPPER ---
+
+type arfCollection struct {
+	XMLName       xml.Name         `xml:"http://scap.nist.gov/schema/asset-reporting-format/1.1 asset-report-collection"`
+	Vocab         string           `xml:"xmlns:arfvocab,attr"`
+	Relationships arfRelationships `xml:"http://scap.nist.gov/schema/reporting-core/1.1 relationships"`
+	Assets        []arfAsset       `xml:"assets>asset"`
+	Reports       []arfReport      `xml:"reports>report"`
+}
+
+type arfRelationships struct {
+	Items []arfRelationship `xml:"relationship"`
+}
+
+type arfRelationship struct {
+	Type    string `xml:"type,attr"`
+	Subject string `xml:"subject,attr"`
+	Ref     string `xml:"ref"`
+}
+
+type arfAsset struct {
+	ID     string      `xml:"id,attr"`
+	Device aiComputing `xml:"http://scap.nist.gov/schema/asset-identification/1.1 computing-device"`
+}
+
+type aiComputing struct {
+	Connections []aiConnection `xml:"connections>connection"`
+	FQDN        string         `xml:"fqdn,omitempty"`
+	Hostname    string         `xml:"hostname,omitempty"`
+}
+
+type aiConnection struct {
+	IP  *aiIPAddress `xml:"ip-address"`
+	MAC string       `xml:"mac-address,omitempty"`
+}
+
+type aiIPAddress struct {
+	IPv4 string `xml:"ip-v4,omitempty"`
+	IPv6 string `xml:"ip-v6,omitempty"`
+}
+
+type arfReport struct {
+	ID      string `xml:"id,attr"`
+	Content struct {
+		Inner string `xml:",innerxml"`
+	} `xml:"content"`
+}
+
+// GenerateXCCDF writes the audit results as an XCCDF 1.2 Benchmark carrying
+// a TestResult, signing it when signing is enabled.
+func GenerateXCCDF(results []engine.AuditResult, pol *policy.Policy, info ScanInfo) (string, error) {
+	filename := filepath.Join(Dir, "audit_results_xccdf.xml")
+	bench := buildBenchmark(results, pol, info)
+	data, err := xml.MarshalIndent(bench, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return filename, writeSigned(filename, data)
+}
+
+// GenerateARF writes the audit results as an ARF 1.1 asset report: the XCCDF
+// TestResult plus an asset-identification record for the target.
+func GenerateARF(results []engine.AuditResult, pol *policy.Policy, info ScanInfo) (string, error) {
+	filename := filepath.Join(Dir, "audit_results_arf.xml")
+	bench := buildBenchmark(results, pol, info)
+
+	// The TestResult stands alone inside the report, so it must say which
+	// benchmark it belongs to
+	tr := bench.TestResult
+	tr.Benchmark = &xccdfBenchRef{Href: "#" + benchmarkID, ID: benchmarkID}
+	inner, err := xml.MarshalIndent(tr, "        ", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	device := aiComputing{FQDN: info.FQDN, Hostname: info.Hostname}
+	for _, addr := range info.Addresses {
+		if strings.Contains(addr, ":") {
+			device.Connections = append(device.Connections, aiConnection{IP: &aiIPAddress{IPv6: addr}})
+		} else {
+			device.Connections = append(device.Connections, aiConnection{IP: &aiIPAddress{IPv4: addr}})
+		}
+	}
+	for _, mac := range info.MACs {
+		device.Connections = append(device.Connections, aiConnection{MAC: mac})
+	}
+
+	report := arfReport{ID: "xccdf1"}
+	report.Content.Inner = "\n" + string(inner) + "\n      "
+	doc := arfCollection{
+		Vocab: "http://scap.nist.gov/specifications/arf/vocabulary/relationships/1.0#",
+		Relationships: arfRelationships{Items: []arfRelationship{
+			{Type: "arfvocab:isAbout", Subject: "xccdf1", Ref: "asset0"},
+		}},
+		Assets:  []arfAsset{{ID: "asset0", Device: device}},
+		Reports: []arfReport{report},
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return filename, writeSigned(filename, data)
+}
+
+func writeSigned(filename string, data []byte) error {
+	data = append([]byte(xml.Header), data...)
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return err
+	}
+	if signing.Enabled() {
+		if _, err := signing.SignFile(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func buildBenchmark(results []engine.AuditResult, pol *policy.Policy, info ScanInfo) *xccdfBenchmark {
+	rules := make(map[string]policy.Rule)
+	version := ""
+	if pol != nil {
+		version = pol.Version
+		for _, r := range pol.Rules {
+			rules[r.ID] = r
+		}
+	}
+	if version == "" {
+		version = "1.0"
+	}
+
+	bench := &xccdfBenchmark{
+		ID:       benchmarkID,
+		Resolved: "1",
+		Status:   xccdfStatus{Date: info.Finished.Format("2006-01-02"), Value: "accepted"},
+		Title:    "SentinelX Hardening Policy",
+		Version:  version,
+		Models:   []xccdfModel{{System: "urn:xccdf:scoring:default"}, {System: "urn:xccdf:scoring:flat"}},
+	}
+	tr := &xccdfTestResult{
+		XMLName:    xml.Name{Space: xccdfNS, Local: "TestResult"},
+		ID:         fmt.Sprintf("xccdf_%s_testresult_%s", xccdfNamespace, ncName(info.Profile)),
+		StartTime:  info.Started.Format(time.RFC3339),
+		EndTime:    info.Finished.Format(time.RFC3339),
+		TestSystem: testSystem,
+		Version:    version,
+		Title:      "SentinelX audit of " + info.Target,
+		Targets:    []string{info.Hostname},
+		Addresses:  info.Addresses,
+		Facts:      targetFacts(info),
+	}
+	if info.Profile != "" {
+		tr.Remarks = append(tr.Remarks, "Hardening level: "+info.Profile)
+	}
+	if u, err := user.Current(); err == nil {
+		tr.Identity = &xccdfIdentity{Authenticated: true, Privileged: os.Geteuid() == 0, Name: u.Username}
+	}
+
+	for _, res := range results {
+		rule, known := rules[res.ID]
+		if !known {
+			rule = policy.Rule{ID: res.ID, Name: res.Name, Severity: res.Severity}
+		}
+		id := XCCDFRuleID(rule)
+		idents := cceIdents(rule.References)
+		bench.Rules = append(bench.Rules, xccdfRule{
+			ID:          id,
+			Selected:    true,
+			Severity:    xccdfSeverity(res.Severity),
+			Title:       res.Name,
+			Description: rule.Description,
+			Rationale:   rule.Rationale,
+			Idents:      idents,
+		})
+
+		rr := xccdfRuleResult{
+			IDRef:    id,
+			Severity: xccdfSeverity(res.Severity),
+			Time:     info.Finished.Format(time.RFC3339),
+			Result:   xccdfResult(res, rule),
+			Idents:   idents,
+		}
+		if res.Actual != "" {
+			rr.Messages = append(rr.Messages, xccdfMessage{Severity: "info", Value: "Actual: " + res.Actual})
+		}
+		if res.Status == "PENDING_REBOOT" {
+			rr.Messages = append(rr.Messages, xccdfMessage{Severity: "warning", Value: "Fixed, pending reboot"})
+		}
+		// Risk acceptances are recorded as overrides of the failing result
+		if w := res.Waiver; w != nil {
+			if res.Status == "WAIVED" {
+				rr.Override = &xccdfOverride{
+					Time:      w.CreatedAt.Format(time.RFC3339),
+					Authority: w.Approver,
+					Old:       "fail",
+					New:       rr.Result,
+					Remark:    fmt.Sprintf("%s (waiver %d, %s %s, expires %s)", w.Justification, w.ID, w.Scope, w.Target, w.ExpiresAt.Format("2006-01-02")),
+				}
+			} else {
+				rr.Messages = append(rr.Messages, xccdfMessage{Severity: "warning", Value: "Waiver expired on " + w.ExpiresAt.Format("2006-01-02")})
+			}
+		}
+		tr.Results = append(tr.Results, rr)
+	}
+
+	// Scored as in the PDF, not by the XCCDF results: a TIMEOUT ("error")
+	// passes and a failing manual rule ("notchecked") fails. Each rule weighs 1
+	pass, fail := engine.Score(results)
+	percent := 0.0
+	if pass+fail > 0 {
+		percent = float64(pass) * 100 / float64(pass+fail)
+	}
+	tr.Scores = []xccdfScore{
+		{System: "urn:xccdf:scoring:default", Maximum: "100.000000", Value: fmt.Sprintf("%.6f", percent)},
+		{System: "urn:xccdf:scoring:flat", Maximum: fmt.Sprintf("%d.000000", pass+fail), Value: fmt.Sprintf("%d.000000", pass)},
+	}
+	bench.TestResult = tr
+	return bench
+}
+
+func targetFacts(info ScanInfo) []xccdfFact {
+	var facts []xccdfFact
+	add := func(name, value string) {
+		if value != "" {
+			facts = append(facts, xccdfFact{Name: name, Type: "string", Value: value})
+		}
+	}
+	add("urn:xccdf:fact:asset:identifier:host_name", info.Hostname)
+	add("urn:xccdf:fact:asset:identifier:fqdn", info.FQDN)
+	for _, addr := range info.Addresses {
+		if strings.Contains(addr, ":") {
+			add("urn:xccdf:fact:asset:identifier:ipv6", addr)
+		} else {
+			add("urn:xccdf:fact:asset:identifier:ipv4", addr)
+		}
+	}
+	for _, mac := range info.MACs {
+		add("urn:xccdf:fact:asset:identifier:mac", mac)
+	}
+	add("urn:xccdf:fact:asset:identifier:os_name", info.OS)
+	add("urn:sentinelx:fact:target_label", info.Target)
+	return facts
+}
+
+// XCCDFRuleID is the XCCDF ID a rule is exported under: its configured
+// xccdf_rule_id, or one derived from the SentinelX rule ID.
+func XCCDFRuleID(rule policy.Rule) string {
+	if rule.XCCDFRuleID != "" {
+		return rule.XCCDFRuleID
+	}
+	return fmt.Sprintf("xccdf_%s_rule_%s", xccdfNamespace, ncName(rule.ID))
+}
+
+var nonNCName = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// ncName makes s usable inside an XML ID.
+func ncName(s string) string {
+	if s == "" {
+		return "default"
+	}
+	return nonNCName.ReplaceAllString(s, "_")
+}
+
+func xccdfResult(res engine.AuditResult, rule policy.Rule) string {
+	switch res.Status {
+	case "PASS":
+		return "pass"
+	case "NOT_APPLICABLE":
+		return "notapplicable"
+	case "TIMEOUT":
+		return "error"
+	case "WAIVED":
+		return "informational"
+	case "PENDING_REBOOT":
+		return "fixed"
+	}
+	if rule.Type == "manual" {
+		return "notchecked"
+	}
+	return "fail"
+}
+
+func xccdfSeverity(s string) string {
+	switch strings.ToLower(s) {
+	case "critical", "high":
+		return "high"
+	case "medium":
+		return "medium"
+	case "low":
+		return "low"
+	}
+	return "unknown"
+}
+
+func cceIdents(refs []string) []xccdfIdent {
+	var idents []xccdfIdent
+	for _, ref := range refs {
+		if strings.HasPrefix(ref, "CCE-") {
+			idents = append(idents, xccdfIdent{System: "https://ncp.nist.gov/cce", Value: ref})
+		}
+	}
+	return idents
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"encoding/xml"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"sih2025/internal/engine"
+	"sih2025/internal/policy"
+)
+
+func TestGenerateXCCDF(t *testing.T) {
+	Dir = t.TempDir()
+	t.Cleanup(func() { Dir = "." })
+
+	// The fake audit (pass, fail, failing manual check) plus the statuses
+	// XCCDF names differently from the PDF
+	results := append(fakeAudit(t),
+		engine.AuditResult{ID: "WIN-SVC-001", Name: "Disable Telnet", Severity: "High", Status: "TIMEOUT", Actual: "Check timed out"},
+		engine.AuditResult{ID: "WIN-LIVE-001", Name: "Firewall running", Severity: "Medium", Status: "NOT_APPLICABLE"},
+	)
+	pol := &policy.Policy{Rules: []policy.Rule{
+		{ID: "WIN-REG-001", Type: "registry", References: []string{"CCE-37615-2"}},
+		{ID: "WIN-UR-001", Type: "secedit", XCCDFRuleID: "xccdf_org.cisecurity_rule_2.2.21"},
+		{ID: "WIN-MAN-001", Type: "manual"},
+	}}
+	finished := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	info := ScanInfo{Target: "fake-windows", Hostname: "win01", Addresses: []string{"10.0.0.5", "fe80::1"}, Profile: "strict", Started: finished.Add(-time.Minute), Finished: finished}
+
+	name, err := GenerateXCCDF(results, pol, info)
+	if err != nil {
+		t.Fatalf("GenerateXCCDF: %v", err)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bench xccdfBenchmark
+	if err := xml.Unmarshal(data, &bench); err != nil {
+		t.Fatalf("%s doesn't parse: %v", name, err)
+	}
+	if bench.TestResult == nil {
+		t.Fatal("no TestResult")
+	}
+
+	got := make(map[string]string)
+	for _, rr := range bench.TestResult.Results {
+		got[rr.IDRef] = rr.Result
+	}
+	want := map[string]string{
+		"xccdf_com.sentinelx_rule_WIN-REG-001":  "pass",
+		"xccdf_org.cisecurity_rule_2.2.21":      "fail",
+		"xccdf_com.sentinelx_rule_WIN-MAN-001":  "notchecked",
+		"xccdf_com.sentinelx_rule_WIN-SVC-001":  "error",
+		"xccdf_com.sentinelx_rule_WIN-LIVE-001": "notapplicable",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rule results %v, want %v", got, want)
+	}
+	for _, r := range bench.Rules {
+		if r.ID == "xccdf_com.sentinelx_rule_WIN-REG-001" && (len(r.Idents) != 1 || r.Idents[0].Value != "CCE-37615-2") {
+			t.Errorf("CCE idents %+v", r.Idents)
+		}
+	}
+
+	// Scored as the PDF: the TIMEOUT passes, the manual FAIL fails, N/A is
+	// left out, so 2 of 4
+	pass, fail := engine.Score(results)
+	if pass != 2 || fail != 2 {
+		t.Fatalf("engine.Score = %d pass, %d fail; want 2 and 2", pass, fail)
+	}
+	scores := []xccdfScore{
+		{System: "urn:xccdf:scoring:default", Maximum: "100.000000", Value: "50.000000"},
+		{System: "urn:xccdf:scoring:flat", Maximum: "4.000000", Value: "2.000000"},
+	}
+	if !reflect.DeepEqual(bench.TestResult.Scores, scores) {
+		t.Errorf("scores %+v, want %+v", bench.TestResult.Scores, scores)
+	}
+}
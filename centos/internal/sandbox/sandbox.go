@@ -0,0 +1,45 @@
+// Package sandbox runs part of the tool against a throwaway copy of the host:
+// the process is re-executed in fresh mount, network, PID, IPC and UTS
+// namespaces, and the root filesystem and every filesystem mounted below it
+// are replaced by overlays whose writes land in a tmpfs that disappears with
+// the process.
+package sandbox
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"sih2025/internal/policy"
+)
+
+// envChild marks the re-executed process.
+const envChild = "SENTINELX_SANDBOX"
+
+// resultFD is the descriptor the child writes its result to, keeping it
+// apart from the progress output on stdout/stderr.
+const resultFD = 3
+
+// hostGlobalPattern matches commands whose effect no namespace can contain:
+// kernel modules, audit rules, the clock, swap and power state. Non-network
+// sysctls are caught too; /proc/sys is read-only in the sandbox.
+var hostGlobalPattern = regexp.MustCompile(`(^|[;&|(]\s*|\s)(modprobe|insmod|rmmod|auditctl|augenrules|hwclock|timedatectl|swapon|swapoff|reboot|shutdown|poweroff|halt|kexec|setenforce)\b` +
+	`|\bsysctl\s+(-\S+\s+)*(kernel|fs|vm|dev|user|abi)\.` +
+	`|/proc/sys/(kernel|fs|vm|dev|user|abi)/`)
+
+// IsChild reports whether this process is the sandboxed re-execution.
+func IsChild() bool {
+	return os.Getenv(envChild) == "1"
+}
+
+// SkipReason returns why a rule can't be exercised in the sandbox, or "" if
+// it can. Used as the skip hook of engine.VerifyLifecycle.
+func SkipReason(rule policy.Rule) string {
+	for _, a := range []policy.Action{rule.Remediation, rule.Rollback} {
+		words := append([]string{a.Cmd}, a.Args...)
+		if hostGlobalPattern.MatchString(strings.Join(words, " ")) {
+			return "changes kernel state the sandbox cannot isolate"
+		}
+	}
+	return ""
+}
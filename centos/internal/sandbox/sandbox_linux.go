@@ -0,0 +1,351 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// scratch is where the tmpfs holding the overlay's upper layer is mounted.
+// It is only mounted inside the private namespace, so the host never sees it.
+const scratch = "/mnt"
+
+const oldRoot = ".sentinelx-oldroot"
+
+// capabilities dropped from the bounding set, so nothing run in the sandbox
+// can load modules, set the clock, reboot, poke at raw devices or create
+// device nodes (a block device node would reach the host's disks).
+var droppedCaps = []uintptr{
+	unix.CAP_SYS_MODULE,
+	unix.CAP_SYS_RAWIO,
+	unix.CAP_MKNOD,
+	unix.CAP_SYS_BOOT,
+	unix.CAP_SYS_TIME,
+	unix.CAP_WAKE_ALARM,
+	unix.CAP_MAC_ADMIN,
+}
+
+// Run re-executes the current binary with args inside new namespaces, feeds
+// it stdin and returns what it wrote to its result descriptor. Progress
+// output from the child goes to progress.
+func Run(args []string, stdin []byte, progress io.Writer) ([]byte, error) {
+	if os.Geteuid() != 0 {
+		return nil, fmt.Errorf("the sandbox needs root (mount namespaces and overlayfs)")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(self, args...)
+	cmd.Env = append(os.Environ(), envChild+"=1")
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = progress
+	cmd.Stderr = progress
+	cmd.ExtraFiles = []*os.File{w} // fd 3 in the child
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWNS | syscall.CLONE_NEWNET | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	if err := cmd.Start(); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("start sandbox: %v", err)
+	}
+	w.Close()
+
+	result, readErr := io.ReadAll(r)
+	if err := cmd.Wait(); err != nil {
+		return result, fmt.Errorf("sandbox: %v", err)
+	}
+	return result, readErr
+}
+
+// Result is where the child writes its result for Run to return.
+func Result() *os.File {
+	return os.NewFile(resultFD, "sandbox-result")
+}
+
+// Enter turns the current (child) process's view of the filesystem into a
+// disposable overlay of the host root and pivots into it. Must be called
+// first thing in the child, before any rule runs.
+func Enter() error {
+	if !IsChild() {
+		return fmt.Errorf("sandbox.Enter called outside the sandbox")
+	}
+
+	// --- 1. DETACH FROM HOST MOUNT PROPAGATION ---
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %v", err)
+	}
+
+	// Listed before the scratch tmpfs hides whatever the host has on /mnt
+	mounts, err := hostMounts()
+	if err != nil {
+		return err
+	}
+
+	// --- 2. OVERLAY OVER / WITH A TMPFS UPPER LAYER ---
+	if err := unix.Mount("tmpfs", scratch, "tmpfs", 0, "mode=0700"); err != nil {
+		return fmt.Errorf("mount scratch tmpfs: %v", err)
+	}
+	upper, work, root := filepath.Join(scratch, "upper"), filepath.Join(scratch, "work"), filepath.Join(scratch, "root")
+	for _, d := range []string{upper, work, root} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			return err
+		}
+	}
+	opts := fmt.Sprintf("lowerdir=/,upperdir=%s,workdir=%s", upper, work)
+	if err := unix.Mount("overlay", root, "overlay", 0, opts); err != nil {
+		return fmt.Errorf("mount overlay: %v", err)
+	}
+
+	// --- 3. THE SAME FOR EVERY FILESYSTEM MOUNTED BELOW / ---
+	// An overlay doesn't reach into the mounts under its lower directory:
+	// /boot, /var or /home would otherwise be empty, or writable on the host
+	for i, m := range mounts {
+		if err := overlayMount(m, root, filepath.Join(scratch, "mounts", strconv.Itoa(i))); err != nil {
+			return err
+		}
+	}
+
+	// --- 4. PSEUDO FILESYSTEMS ---
+	if err := mountPseudo(root); err != nil {
+		return err
+	}
+
+	// --- 5. PIVOT AND DROP THE HOST ROOT ---
+	if err := os.Chdir(root); err != nil {
+		return err
+	}
+	if err := os.Mkdir(oldRoot, 0700); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %v", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Unmount("/"+oldRoot, unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach host root: %v", err)
+	}
+	os.Remove("/" + oldRoot)
+
+	// --- 6. DROP HOST-GLOBAL CAPABILITIES FOR EVERYTHING WE EXEC ---
+	for _, c := range droppedCaps {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, c, 0, 0, 0); err != nil {
+			return fmt.Errorf("drop capability %d: %v", c, err)
+		}
+	}
+	unix.Sethostname([]byte("sentinelx-sandbox"))
+	return nil
+}
+
+// pseudoFS are filesystem types with nothing for rules to change, or that
+// mountPseudo replaces; their mounts are not carried into the sandbox.
+var pseudoFS = map[string]bool{
+	"proc": true, "sysfs": true, "devtmpfs": true, "devpts": true, "mqueue": true,
+	"cgroup": true, "cgroup2": true, "debugfs": true, "tracefs": true, "securityfs": true,
+	"pstore": true, "bpf": true, "configfs": true, "fusectl": true, "hugetlbfs": true,
+	"autofs": true, "binfmt_misc": true, "efivarfs": true, "nsfs": true, "rpc_pipefs": true,
+}
+
+// hostMount is a filesystem mounted below / on the host.
+type hostMount struct {
+	point  string
+	fstype string
+}
+
+// hostMounts lists the filesystems mounted below /, parents before their
+// children. Pseudo filesystems, the trees mountPseudo replaces and scratch
+// are left out; of several mounts on one point only the top one is visible,
+// so only it is kept.
+func hostMounts() ([]hostMount, error) {
+	data, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]int)
+	var mounts []hostMount
+	for _, line := range strings.Split(string(data), "\n") {
+		// id parent major:minor root point options [optional fields] - fstype source super-options
+		fields := strings.Fields(line)
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if sep < 0 || sep+1 >= len(fields) {
+			continue
+		}
+		m := hostMount{point: unescapeMountPoint(fields[4]), fstype: fields[sep+1]}
+		if m.point == "/" || pseudoFS[m.fstype] || underAny(m.point, "/proc", "/sys", "/dev", "/run", "/tmp", scratch) {
+			continue
+		}
+		if i, ok := seen[m.point]; ok {
+			mounts[i] = m
+			continue
+		}
+		seen[m.point] = len(mounts)
+		mounts = append(mounts, m)
+	}
+	sort.SliceStable(mounts, func(a, b int) bool {
+		return strings.Count(mounts[a].point, "/") < strings.Count(mounts[b].point, "/")
+	})
+	return mounts, nil
+}
+
+// unescapeMountPoint undoes mountinfo's octal escapes (\040 for a space).
+func unescapeMountPoint(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func underAny(path string, dirs ...string) bool {
+	for _, d := range dirs {
+		if path == d || strings.HasPrefix(path, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// overlayMount puts a disposable overlay of the host mount m at the same
+// place under root, with its upper layer in dir. Where no overlay can be
+// mounted (an unusual filesystem, or a path overlayfs options can't carry)
+// the host mount is bound read-only instead: writes there fail rather than
+// reach the host.
+func overlayMount(m hostMount, root, dir string) error {
+	target := filepath.Join(root, m.point)
+	if _, err := os.Stat(target); err != nil {
+		return nil // its mount point is hidden by another mount
+	}
+	upper, work := filepath.Join(dir, "upper"), filepath.Join(dir, "work")
+	if !strings.ContainsAny(m.point, ",:\\") {
+		if err := os.MkdirAll(upper, 0755); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(work, 0755); err != nil {
+			return err
+		}
+		opts := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", m.point, upper, work)
+		if err := unix.Mount("overlay", target, "overlay", 0, opts); err == nil {
+			return nil
+		}
+	}
+	if err := unix.Mount(m.point, target, "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("bind %s: %v", m.point, err)
+	}
+	if err := unix.Mount("", target, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("remount %s read-only: %v", m.point, err)
+	}
+	return nil
+}
+
+// mountPseudo mounts /proc, /sys, /dev, /run and /tmp inside root. /proc/sys
+// and /sys are read-only except for the network sysctls, which belong to the
+// sandbox's own network namespace.
+func mountPseudo(root string) error {
+	at := func(p string) string { return filepath.Join(root, p) }
+
+	steps := []struct {
+		source, target, fstype string
+		flags                  uintptr
+		data                   string
+	}{
+		{"proc", at("proc"), "proc", unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, ""},
+		{"sysfs", at("sys"), "sysfs", unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC, ""},
+		{"tmpfs", at("dev"), "tmpfs", unix.MS_NOSUID, "mode=0755"},
+		{"tmpfs", at("run"), "tmpfs", unix.MS_NOSUID | unix.MS_NODEV, "mode=0755"},
+		{"tmpfs", at("tmp"), "tmpfs", unix.MS_NOSUID | unix.MS_NODEV, "mode=1777"},
+	}
+	for _, s := range steps {
+		if err := os.MkdirAll(s.target, 0755); err != nil {
+			return err
+		}
+		if err := unix.Mount(s.source, s.target, s.fstype, s.flags, s.data); err != nil {
+			return fmt.Errorf("mount %s: %v", s.target, err)
+		}
+	}
+
+	// Read-only kernel knobs, with the namespaced network ones left writable
+	for _, p := range []string{"proc/sys", "proc/sysrq-trigger"} {
+		if _, err := os.Stat(at(p)); os.IsNotExist(err) {
+			continue // e.g. kernels built without magic SysRq
+		}
+		if err := bindReadOnly(at(p), at(p)); err != nil {
+			return err
+		}
+	}
+	if err := unix.Mount(at("proc/sys/net"), at("proc/sys/net"), "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("bind /proc/sys/net: %v", err)
+	}
+	if err := unix.Mount("", at("proc/sys/net"), "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("remount /proc/sys/net: %v", err)
+	}
+
+	// Minimal /dev: harmless character devices bound from the host
+	for _, name := range []string{"null", "zero", "full", "random", "urandom", "tty"} {
+		target := at("dev/" + name)
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0666)
+		if err != nil {
+			return err
+		}
+		f.Close()
+		if err := unix.Mount("/dev/"+name, target, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("bind /dev/%s: %v", name, err)
+		}
+	}
+	for name, target := range map[string]string{"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2"} {
+		if err := os.Symlink(target, at("dev/"+name)); err != nil {
+			return err
+		}
+	}
+	if err := os.Mkdir(at("dev/shm"), 01777); err != nil {
+		return err
+	}
+	return unix.Mount("tmpfs", at("dev/shm"), "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777")
+}
+
+func bindReadOnly(source, target string) error {
+	if err := unix.Mount(source, target, "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("bind %s: %v", target, err)
+	}
+	if err := unix.Mount("", target, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("remount %s read-only: %v", target, err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Run is only available on Linux.
+func Run(args []string, stdin []byte, progress io.Writer) ([]byte, error) {
+	return nil, fmt.Errorf("the sandbox requires Linux namespaces")
+}
+
+// Result is only available on Linux.
+func Result() *os.File {
+	return nil
+}
+
+// Enter is only available on Linux.
+func Enter() error {
+	return fmt.Errorf("the sandbox requires Linux namespaces")
+}
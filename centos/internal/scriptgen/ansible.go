@@ -0,0 +1,115 @@
+package scriptgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"sih2025/internal/policy"
+)
+
+// playbook renders remediate.yml, or rollback.yml when rollback is set. Each
+// rule is a check task plus a fix guarded by the check's result, recorded in
+// appliedFile; the rollback undoes only the rules recorded there.
+// Commands are tagged !unsafe so Ansible doesn't template "{{" inside them.
+func playbook(steps []step, meta Meta, rollback bool) []byte {
+	var b strings.Builder
+	b.WriteString(header(meta, rollback, len(steps), "#"))
+	title := "SentinelX remediation"
+	if rollback {
+		title = "SentinelX rollback"
+	}
+	fmt.Fprintf(&b, "---\n- name: %s\n  hosts: all\n  become: true\n  gather_facts: false\n  tasks:\n", yamlString(fmt.Sprintf("%s (%s)", title, meta.Profile)))
+	services := mergeServices(steps, rollback)
+
+	if rollback {
+		fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.command: cat %s\n      register: sx_applied\n      changed_when: false\n      failed_when: false\n",
+			yamlString("rules changed by the remediation"), appliedFile)
+	}
+
+	for i := range steps {
+		s := steps[i]
+		if rollback {
+			s = steps[len(steps)-1-i]
+		}
+		fmt.Fprintf(&b, "\n    # --- [%s] %s (%s) ---\n", s.Rule.ID, oneLine(s.Rule.Name), s.Rule.Severity)
+		if s.Manual != "" {
+			fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.debug:\n        msg: %s\n",
+				yamlString(fmt.Sprintf("[%s] MANUAL", s.Rule.ID)), yamlString(oneLine(s.Manual)))
+			continue
+		}
+
+		// Only rules the remediation changed are undone, then dropped from the list
+		if rollback {
+			applied := yamlString(fmt.Sprintf("%s in sx_applied.stdout_lines", yamlString(s.Rule.ID)))
+			fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.shell: !unsafe %s\n      when: %s\n%s",
+				yamlString(fmt.Sprintf("[%s] revert: %s", s.Rule.ID, oneLine(s.Rule.Name))), yamlString(s.Undo), applied, notify(handlersFor(s, true, services)))
+			fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.lineinfile:\n        path: %s\n        line: %s\n        state: absent\n      when: %s\n",
+				yamlString(fmt.Sprintf("[%s] forget", s.Rule.ID)), appliedFile, yamlString(s.Rule.ID), applied)
+			continue
+		}
+
+		reg := fmt.Sprintf("sx_check_%d", s.N)
+		fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.shell: !unsafe %s\n      register: %s\n      changed_when: false\n      failed_when: false\n",
+			yamlString(fmt.Sprintf("[%s] check", s.Rule.ID)), yamlString(s.Check), reg)
+		fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.shell: !unsafe %s\n      when: %s.rc != 0\n%s",
+			yamlString(fmt.Sprintf("[%s] %s", s.Rule.ID, oneLine(s.Rule.Name))), yamlString(s.Fix), reg, notify(handlersFor(s, false, services)))
+		fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.lineinfile:\n        path: %s\n        line: %s\n        create: true\n        mode: \"0600\"\n      when: %s.rc != 0\n",
+			yamlString(fmt.Sprintf("[%s] record", s.Rule.ID)), appliedFile, yamlString(s.Rule.ID), reg)
+		if !s.Rule.RequiresReboot {
+			fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.shell: !unsafe %s\n      changed_when: false\n      when: %s.rc != 0\n",
+				yamlString(fmt.Sprintf("[%s] verify", s.Rule.ID)), yamlString(s.Check), reg)
+			continue
+		}
+		// The check can't pass until the next boot, so failing it isn't an error
+		verify := fmt.Sprintf("sx_verify_%d", s.N)
+		fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.shell: !unsafe %s\n      register: %s\n      changed_when: false\n      failed_when: false\n      when: %s.rc != 0\n",
+			yamlString(fmt.Sprintf("[%s] verify", s.Rule.ID)), yamlString(s.Check), verify, reg)
+		fmt.Fprintf(&b, "    - name: %s\n      ansible.builtin.debug:\n        msg: %s\n      when: %s.rc != 0 and %s.rc != 0\n",
+			yamlString(fmt.Sprintf("[%s] PENDING", s.Rule.ID)), yamlString("takes effect after the next reboot"), reg, verify)
+	}
+
+	// Handlers run once, at the end, in the order they are defined
+	if len(services) > 0 {
+		b.WriteString("\n  handlers:\n")
+	}
+	for _, svc := range services {
+		fmt.Fprintf(&b, "    - name: %s\n", yamlString(svc.handler()))
+		switch svc.Action {
+		case policy.ServiceDaemonReload:
+			b.WriteString("      ansible.builtin.systemd:\n        daemon_reload: true\n")
+		case policy.ServiceSysctl:
+			b.WriteString("      ansible.builtin.command: sysctl --system\n")
+		default:
+			state := "reloaded"
+			if svc.Action == policy.ServiceRestart {
+				state = "restarted"
+			}
+			fmt.Fprintf(&b, "      ansible.builtin.systemd:\n        name: %s\n        state: %s\n", yamlString(svc.Name), state)
+		}
+	}
+	return []byte(b.String())
+}
+
+// notify is the notify: block of a task, if it has handlers.
+func notify(handlers []string) string {
+	if len(handlers) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("      notify:\n")
+	for _, h := range handlers {
+		fmt.Fprintf(&b, "        - %s\n", yamlString(h))
+	}
+	return b.String()
+}
+
+// yamlString quotes s as a YAML double-quoted scalar (JSON strings are valid YAML).
+func yamlString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,71 @@
+package scriptgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sih2025/internal/policy"
+)
+
+// extractProg mirrors policy.ExtractValue: trim the output, then take the
+// pattern's first group (or whole match), or the whole output without a pattern.
+const extractProg = `$re = shift; local $/; $_ = <STDIN> // ""; s/^\s+|\s+$//g; ` +
+	`if ($re eq "") { $v = $_ } elsif (/$re/) { $v = defined $1 ? $1 : $& } else { exit 1 } ` +
+	`$v =~ s/^\s+|\s+$//g; exit 1 if $re eq "" && $v eq ""; print $v`
+
+// awk programs for the comparison, given the value as ARGV[1]
+const (
+	numberTest = `v = ARGV[1]; if (v !~ /^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$/) exit 1; v += 0; `
+	oneOfProg  = `BEGIN { v = tolower(ARGV[1]); for (i = 2; i < ARGC; i++) if (v == tolower(ARGV[i])) exit 0; exit 1 }`
+	subsetProg = `BEGIN { for (i = 3; i < ARGC; i++) ok[tolower(ARGV[i])] = 1; ` +
+		`n = ARGV[2] == "" ? split(ARGV[1], it) : split(ARGV[1], it, ARGV[2]); ` +
+		`for (i = 1; i <= n; i++) { gsub(/^[ \t]+|[ \t]+$/, "", it[i]); if (it[i] != "" && !(tolower(it[i]) in ok)) exit 1 } exit 0 }`
+)
+
+// compareScript is the shell form of a check with a typed comparison.
+func compareScript(c policy.CheckAction) string {
+	cmp := c.Compare
+	extract := fmt.Sprintf("val=$(%s 2>&1 | perl -e %s %s)", commandLine(c.Cmd, c.Args), shellQuote(extractProg), shellQuote(c.ExpectPattern))
+
+	num := func(f *float64) string {
+		if f == nil {
+			return "0"
+		}
+		return strconv.FormatFloat(*f, 'f', -1, 64)
+	}
+	var test string
+	switch op := strings.ToLower(strings.TrimSpace(cmp.Op)); op {
+	case "one_of", "in":
+		args := []string{"awk", shellQuote(oneOfProg), `"$val"`}
+		for _, v := range cmp.Values {
+			args = append(args, shellQuote(strings.TrimSpace(v)))
+		}
+		test = strings.Join(args, " ")
+	case "subset":
+		sep := cmp.Separator
+		if sep == "" {
+			sep = ","
+		} else if strings.TrimSpace(sep) == "" {
+			sep = "" // awk's default field splitting
+		} else if len(sep) > 1 {
+			sep = regexp.QuoteMeta(sep)
+		}
+		args := []string{"awk", shellQuote(subsetProg), `"$val"`, shellQuote(sep)}
+		for _, v := range cmp.Values {
+			args = append(args, shellQuote(strings.TrimSpace(v)))
+		}
+		test = strings.Join(args, " ")
+	case "range":
+		test = fmt.Sprintf("awk %s \"$val\" %s %s", shellQuote("BEGIN { "+numberTest+"exit !(v >= ARGV[2] + 0 && v <= ARGV[3] + 0) }"), num(cmp.Min), num(cmp.Max))
+	default:
+		ops := map[string]string{"lt": "<", "<": "<", "le": "<=", "<=": "<=", "ge": ">=", ">=": ">=", "gt": ">", ">": ">", "eq": "==", "==": "==", "=": "=="}
+		sym, ok := ops[op]
+		if !ok {
+			return fmt.Sprintf("echo %s >&2; false", shellQuote("unknown compare op "+cmp.Op))
+		}
+		test = fmt.Sprintf("awk %s \"$val\" %s", shellQuote("BEGIN { "+numberTest+"exit !(v "+sym+" ARGV[2] + 0) }"), num(cmp.Value))
+	}
+	return extract + " && " + test
+}
@@ -0,0 +1,216 @@
+// Package scriptgen turns a policy's remediations into artifacts an operator
+// can review and run without the dashboard: a POSIX shell script or an
+// Ansible playbook, each with a matching rollback.
+package scriptgen
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"sih2025/internal/dag"
+	"sih2025/internal/policy"
+)
+
+// Formats
+const (
+	FormatShell   = "shell"
+	FormatAnsible = "ansible"
+)
+
+// appliedFile is where remediate.sh / remediate.yml record the rules they
+// changed, one ID per line, so the rollback undoes only those.
+const appliedFile = "/var/lib/sentinelx/applied"
+
+// Meta is printed in the header of every generated file.
+type Meta struct {
+	Profile   string
+	Host      string // host the failing rules were audited on, if any
+	Generated time.Time
+}
+
+// File is one generated artifact.
+type File struct {
+	Name string
+	Mode os.FileMode
+	Data []byte
+}
+
+// step is a rule prepared for export: its check, fix and undo as shell, or
+// why it can't be automated.
+type step struct {
+	N      int
+	Rule   policy.Rule
+	Check  string
+	Fix    string
+	Undo   string
+	Manual string // set when the rule can't be applied by the script
+}
+
+// Generate builds the remediation and rollback artifacts for rules, applied
+// in dependency (dag.SortRules) order and rolled back in reverse.
+//
+// config_key rules are refused rather than exported as manual steps: their
+// edits depend on Match blocks, Include files and drop-in precedence, which a
+// script can't reproduce faithfully.
+func Generate(rules []policy.Rule, format string, meta Meta) ([]File, error) {
+	var configKeys []string
+	for _, r := range rules {
+		if r.Type == "config_key" || r.Remediation.Type == "config_key" || r.Rollback.Type == "config_key" {
+			configKeys = append(configKeys, r.ID)
+		}
+	}
+	if len(configKeys) > 0 {
+		return nil, fmt.Errorf("config_key rules can't be exported to a script, apply them with the agent instead: %s", strings.Join(configKeys, ", "))
+	}
+
+	layers, err := dag.SortRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	// Rules within a layer are independent; keep them in policy order so the
+	// output is stable between exports
+	position := make(map[string]int)
+	for i, r := range rules {
+		if _, seen := position[r.ID]; !seen {
+			position[r.ID] = i
+		}
+	}
+	var steps []step
+	for _, layer := range layers {
+		sort.SliceStable(layer, func(i, j int) bool { return position[layer[i].ID] < position[layer[j].ID] })
+		for _, r := range layer {
+			steps = append(steps, prepare(len(steps)+1, r))
+		}
+	}
+
+	switch format {
+	case FormatShell:
+		return []File{
+			{Name: "remediate.sh", Mode: 0755, Data: shellScript(steps, meta, false)},
+			{Name: "rollback.sh", Mode: 0755, Data: shellScript(steps, meta, true)},
+		}, nil
+	case FormatAnsible:
+		return []File{
+			{Name: "remediate.yml", Mode: 0644, Data: playbook(steps, meta, false)},
+			{Name: "rollback.yml", Mode: 0644, Data: playbook(steps, meta, true)},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q (want %s or %s)", format, FormatShell, FormatAnsible)
+}
+
+// WriteZip packs files into a zip archive under dir/.
+func WriteZip(w io.Writer, dir string, files []File, modified time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		hdr := &zip.FileHeader{Name: dir + "/" + f.Name, Method: zip.Deflate, Modified: modified}
+		hdr.SetMode(f.Mode)
+		fw, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func prepare(n int, r policy.Rule) step {
+	s := step{N: n, Rule: r}
+	if r.Type == "manual" || r.Check.Cmd == "" {
+		s.Manual = "no automated check"
+		return s
+	}
+	s.Check = checkScript(r.Check)
+
+	var err error
+	if s.Fix, err = actionScript(r.Remediation); err != nil {
+		s.Manual = err.Error()
+		return s
+	}
+	if s.Undo, err = actionScript(r.Rollback); err != nil {
+		s.Undo = "echo " + shellQuote("manual rollback: "+err.Error()) + " >&2; false"
+	}
+	return s
+}
+
+// checkScript is the shell form of the engine's check: with an expected
+// pattern, the output must match it as a regex or contain it; otherwise the
+// command must succeed. Typed comparisons go through compareScript.
+func checkScript(c policy.CheckAction) string {
+	if c.Compare != nil {
+		return compareScript(c)
+	}
+	cmd := commandLine(c.Cmd, c.Args)
+	if c.ExpectPattern == "" {
+		return cmd + " >/dev/null 2>&1"
+	}
+	pat := shellQuote(c.ExpectPattern)
+	return fmt.Sprintf(`out=$(%s 2>&1); printf '%%s' "$out" | grep -Pzq -- %s 2>/dev/null || case "$out" in *%s*) ;; *) false ;; esac`, cmd, pat, pat)
+}
+
+// actionScript is the shell form of a remediation or rollback action.
+func actionScript(a policy.Action) (string, error) {
+	switch a.Type {
+	case "command":
+		return commandLine(a.Cmd, a.Args), nil
+	case "file_edit", "file_append":
+		return editScript(a.FilePath, a.SearchRegex, a.ReplaceText), nil
+	case "manual":
+		return "", fmt.Errorf("manual action: %s", strings.Join(a.Args, " "))
+	case "":
+		return "", fmt.Errorf("no action defined")
+	}
+	return "", fmt.Errorf("%s actions can't be exported to a script", a.Type)
+}
+
+// editScript reproduces EditConfigFile: replace every match of the
+// (multi-line) regex, or append the text when nothing matches.
+func editScript(path, search, replace string) string {
+	const prog = `BEGIN { $re = shift @ARGV; $text = shift @ARGV } unless (s/$re/$text/gm) { $_ .= "\n" if length && !/\n\z/; $_ .= "$text\n" }`
+	return fmt.Sprintf("touch %s && perl -0777 -i -pe %s %s %s %s",
+		shellQuote(path), shellQuote(prog), shellQuote(search), shellQuote(replace), shellQuote(path))
+}
+
+func commandLine(cmd string, args []string) string {
+	words := []string{shellQuote(cmd)}
+	for _, a := range args {
+		words = append(words, shellQuote(a))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote quotes s for a POSIX shell, leaving plain words as they are.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func header(meta Meta, rollback bool, count int, comment string) string {
+	var b strings.Builder
+	what := "remediation"
+	if rollback {
+		what = "rollback"
+	}
+	fmt.Fprintf(&b, "%s SentinelX %s: %d rules, profile %q\n", comment, what, count, meta.Profile)
+	fmt.Fprintf(&b, "%s Generated %s", comment, meta.Generated.Format(time.RFC3339))
+	if meta.Host != "" {
+		fmt.Fprintf(&b, " from an audit of %s", meta.Host)
+	}
+	b.WriteString("\n")
+	if rollback {
+		fmt.Fprintf(&b, "%s Undoes rules in reverse dependency order, only those the remediation changed (listed in %s).\n", comment, appliedFile)
+	} else {
+		fmt.Fprintf(&b, "%s Applies rules in dependency order, only where the rule's check fails, so it is safe to re-run.\n", comment)
+		fmt.Fprintf(&b, "%s Records the rules it changes in %s for the rollback.\n", comment, appliedFile)
+	}
+	fmt.Fprintf(&b, "%s Needs GNU grep (-P) for pattern checks and perl for config edits.\n", comment)
+	return b.String()
+}
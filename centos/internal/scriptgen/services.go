@@ -0,0 +1,86 @@
+package scriptgen
+
+import (
+	"sort"
+
+	"sih2025/internal/policy"
+)
+
+// service is one service action of an export, with the rules that need it.
+type service struct {
+	Action string
+	Name   string
+	Rules  []string
+}
+
+// handler is the Ansible handler name for the service.
+func (s service) handler() string {
+	if s.Name == "" {
+		return "sentinelx " + s.Action
+	}
+	return "sentinelx " + s.Action + " " + s.Name
+}
+
+// stepServices are the service actions a step's fix (or undo) needs.
+func stepServices(s step, rollback bool) []policy.ServiceAction {
+	if s.Manual != "" {
+		return nil
+	}
+	if rollback && len(s.Rule.Rollback.Services) > 0 {
+		return s.Rule.Rollback.Services
+	}
+	return s.Rule.Remediation.Services
+}
+
+// serviceKey identifies a service across actions: a reload and a restart of
+// sshd are the same entry.
+func serviceKey(a policy.ServiceAction) string {
+	if a.Name == "" {
+		return a.Action
+	}
+	return "unit:" + a.Name
+}
+
+// mergeServices does what engine.ServiceQueue does at run time: each service
+// once, a restart covering a reload, in policy.ServiceRank order.
+func mergeServices(steps []step, rollback bool) []*service {
+	byKey := make(map[string]*service)
+	var list []*service
+	for _, s := range steps {
+		for _, a := range stepServices(s, rollback) {
+			svc, ok := byKey[serviceKey(a)]
+			if !ok {
+				svc = &service{Action: a.Action, Name: a.Name}
+				byKey[serviceKey(a)] = svc
+				list = append(list, svc)
+			}
+			if policy.ServiceRank(a.Action) > policy.ServiceRank(svc.Action) {
+				svc.Action = a.Action
+			}
+			if len(svc.Rules) == 0 || svc.Rules[len(svc.Rules)-1] != s.Rule.ID {
+				svc.Rules = append(svc.Rules, s.Rule.ID)
+			}
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if ri, rj := policy.ServiceRank(list[i].Action), policy.ServiceRank(list[j].Action); ri != rj {
+			return ri < rj
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// handlersFor maps each of a step's service actions to the merged entry's
+// handler.
+func handlersFor(s step, rollback bool, merged []*service) []string {
+	var names []string
+	for _, a := range stepServices(s, rollback) {
+		for _, svc := range merged {
+			if serviceKey(policy.ServiceAction{Action: svc.Action, Name: svc.Name}) == serviceKey(a) {
+				names = append(names, svc.handler())
+			}
+		}
+	}
+	return names
+}
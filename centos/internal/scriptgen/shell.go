@@ -0,0 +1,187 @@
+package scriptgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// shellScript renders remediate.sh, or rollback.sh when rollback is set.
+func shellScript(steps []step, meta Meta, rollback bool) []byte {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString(header(meta, rollback, len(steps), "#"))
+	fmt.Fprintf(&b, `set -u
+
+failed=0
+failed_ids=" "
+changed_ids=" "
+applied=${SENTINELX_APPLIED:-%s}
+
+`, shellQuote(appliedFile))
+	if rollback {
+		b.WriteString(`# run ID NAME: undo one rule through its undo_ function, if remediate.sh
+# changed it; rules that were already compliant are left alone
+run() {
+	n=$1 id=$2 name=$3
+	if ! grep -qxF -- "$id" "$applied" 2>/dev/null; then
+		echo "[SKIP]     $id: not applied by remediate.sh"
+		return 0
+	fi
+	if "undo_$n"; then
+		echo "[REVERTED] $id: $name"
+		changed_ids="$changed_ids$id "
+		grep -vxF -- "$id" "$applied" > "$applied.tmp"
+		mv "$applied.tmp" "$applied"
+	else
+		echo "[ERROR]    $id: rollback failed"
+		failed=$((failed + 1))
+	fi
+}
+`)
+	} else {
+		fmt.Fprintf(&b, "reboot_ids=%s\n", shellQuote(" "+strings.Join(rebootIDs(steps), " ")+" "))
+		b.WriteString(`mkdir -p "$(dirname "$applied")"
+
+# run ID NAME DEP...: apply one rule through its check_/fix_ functions and
+# record it in $applied for rollback.sh
+run() {
+	n=$1 id=$2 name=$3
+	shift 3
+	for dep in "$@"; do
+		case "$failed_ids" in *" $dep "*)
+			echo "[BLOCKED]  $id: depends on $dep, which failed"
+			failed=$((failed + 1)); failed_ids="$failed_ids$id "
+			return 0 ;;
+		esac
+	done
+	if "check_$n"; then
+		echo "[OK]       $id: already compliant"
+		return 0
+	fi
+	if ! "fix_$n"; then
+		echo "[ERROR]    $id: remediation failed"
+		failed=$((failed + 1)); failed_ids="$failed_ids$id "
+		return 0
+	fi
+	changed_ids="$changed_ids$id "
+	grep -qxF -- "$id" "$applied" 2>/dev/null || echo "$id" >> "$applied" ||
+		echo "[WARN]     $id: not recorded in $applied, rollback.sh will skip it"
+	if "check_$n"; then
+		echo "[FIXED]    $id: $name"
+		return 0
+	fi
+	case "$reboot_ids" in *" $id "*)
+		echo "[PENDING]  $id: $name (takes effect after the next reboot)"
+		return 0 ;;
+	esac
+	echo "[ERROR]    $id: still failing after remediation"
+	failed=$((failed + 1)); failed_ids="$failed_ids$id "
+}
+`)
+	}
+
+	// Definitions, then calls in order (reverse order for the rollback)
+	for _, s := range steps {
+		fmt.Fprintf(&b, "\n# --- [%s] %s (%s) ---\n", s.Rule.ID, oneLine(s.Rule.Name), s.Rule.Severity)
+		if s.Manual != "" {
+			fmt.Fprintf(&b, "# MANUAL: %s\n", oneLine(s.Manual))
+			continue
+		}
+		if rollback {
+			fmt.Fprintf(&b, "undo_%d() {\n\t%s\n}\n", s.N, s.Undo)
+		} else {
+			fmt.Fprintf(&b, "check_%d() {\n\t%s\n}\n", s.N, s.Check)
+			fmt.Fprintf(&b, "fix_%d() {\n\t%s\n}\n", s.N, s.Fix)
+		}
+	}
+
+	b.WriteString("\n# --- RUN ---\n")
+	for i := range steps {
+		s := steps[i]
+		if rollback {
+			s = steps[len(steps)-1-i]
+		}
+		if s.Manual != "" {
+			fmt.Fprintf(&b, "echo %s\n", shellQuote(fmt.Sprintf("[MANUAL]   %s: %s", s.Rule.ID, oneLine(s.Manual))))
+			continue
+		}
+		call := []string{"run", fmt.Sprint(s.N), shellQuote(s.Rule.ID), shellQuote(oneLine(s.Rule.Name))}
+		if !rollback {
+			for _, dep := range s.Rule.DependsOn {
+				call = append(call, shellQuote(dep))
+			}
+		}
+		b.WriteString(strings.Join(call, " ") + "\n")
+	}
+
+	if services := mergeServices(steps, rollback); len(services) > 0 {
+		b.WriteString(`
+# --- SERVICES (each once, after all rules) ---
+# run_service ACTION NAME ID...: run the action if one of the rules was changed
+run_service() {
+	action=$1 name=$2
+	shift 2
+	for id in "$@"; do
+		case "$changed_ids" in *" $id "*)
+			case "$action" in
+			daemon-reload) set -- systemctl daemon-reload ;;
+			sysctl) set -- sysctl --system ;;
+			*) set -- systemctl "$action" "$name" ;;
+			esac
+			if "$@" >/dev/null; then
+				echo "[SERVICE]  $action $name"
+			else
+				echo "[ERROR]    $action $name failed"
+				failed=$((failed + 1))
+			fi
+			return 0 ;;
+		esac
+	done
+}
+`)
+		for _, svc := range services {
+			call := []string{"run_service", shellQuote(svc.Action), shellQuote(svc.Name)}
+			for _, id := range svc.Rules {
+				call = append(call, shellQuote(id))
+			}
+			b.WriteString(strings.Join(call, " ") + "\n")
+		}
+	}
+	var reboot []string
+	for _, id := range rebootIDs(steps) {
+		reboot = append(reboot, shellQuote(id))
+	}
+	if len(reboot) > 0 {
+		fmt.Fprintf(&b, `
+# --- REBOOT (changes that take effect after the next boot) ---
+for id in %s; do
+	case "$changed_ids" in *" $id "*) echo "[REBOOT]   $id: takes effect after the next reboot" ;; esac
+done
+`, strings.Join(reboot, " "))
+	}
+	b.WriteString(`
+echo
+if [ "$failed" -gt 0 ]; then
+	echo "$failed rule(s) failed"
+	exit 1
+fi
+echo "done"
+`)
+	return []byte(b.String())
+}
+
+// rebootIDs are the automated rules whose fixes need a reboot to show, so
+// a failing check right after them isn't an error.
+func rebootIDs(steps []step) []string {
+	var ids []string
+	for _, s := range steps {
+		if s.Rule.RequiresReboot && s.Manual == "" {
+			ids = append(ids, s.Rule.ID)
+		}
+	}
+	return ids
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,134 @@
+package secedit
+
+import (
+	"sort"
+	"strings"
+)
+
+// wellKnownAccounts are the accounts policies name, with their well-known
+// SIDs, so "Administrators", "BUILTIN\Administrators" and "*S-1-5-32-544"
+// compare equal.
+var wellKnownAccounts = []struct {
+	Name string
+	SID  string
+}{
+	{"Everyone", "S-1-1-0"},
+	{"CREATOR OWNER", "S-1-3-0"},
+	{"NETWORK", "S-1-5-2"},
+	{"BATCH", "S-1-5-3"},
+	{"INTERACTIVE", "S-1-5-4"},
+	{"SERVICE", "S-1-5-6"},
+	{"ANONYMOUS LOGON", "S-1-5-7"},
+	{"ENTERPRISE DOMAIN CONTROLLERS", "S-1-5-9"},
+	{"Authenticated Users", "S-1-5-11"},
+	{"SYSTEM", "S-1-5-18"},
+	{"LOCAL SERVICE", "S-1-5-19"},
+	{"NETWORK SERVICE", "S-1-5-20"},
+	{"Administrators", "S-1-5-32-544"},
+	{"Users", "S-1-5-32-545"},
+	{"Guests", "S-1-5-32-546"},
+	{"Power Users", "S-1-5-32-547"},
+	{"Account Operators", "S-1-5-32-548"},
+	{"Server Operators", "S-1-5-32-549"},
+	{"Print Operators", "S-1-5-32-550"},
+	{"Backup Operators", "S-1-5-32-551"},
+	{"Replicator", "S-1-5-32-552"},
+	{"Remote Desktop Users", "S-1-5-32-555"},
+	{"Performance Log Users", "S-1-5-32-559"},
+	{"IIS_IUSRS", "S-1-5-32-568"},
+	{"Hyper-V Administrators", "S-1-5-32-578"},
+	{"Remote Management Users", "S-1-5-32-580"},
+	{"Local account", "S-1-5-113"},
+	{"Local account and member of Administrators group", "S-1-5-114"},
+	{"Virtual Machines", "S-1-5-83-0"},
+	{"Window Manager Group", "S-1-5-90-0"},
+	{"WdiServiceHost", "S-1-5-80-3139157870-2983391045-3678747466-658725712-1809340420"},
+}
+
+var (
+	sidByName = make(map[string]string)
+	nameBySID = make(map[string]string)
+)
+
+func init() {
+	for _, a := range wellKnownAccounts {
+		sidByName[strings.ToLower(a.Name)] = a.SID
+		nameBySID[a.SID] = a.Name
+	}
+	sidByName["local system"] = "S-1-5-18"
+}
+
+// NormalizeAccount returns the canonical form of one account entry: the SID
+// (without secedit's '*' prefix) when it is or names a well-known account,
+// otherwise the lower-cased name.
+func NormalizeAccount(account string) string {
+	a := strings.TrimPrefix(strings.Trim(strings.TrimSpace(account), `"`), "*")
+	if strings.HasPrefix(strings.ToUpper(a), "S-1-") {
+		return strings.ToUpper(a)
+	}
+	name := strings.ToLower(a)
+	for _, prefix := range []string{`builtin\`, `nt authority\`, `nt service\`, `window manager\`, `nt virtual machine\`} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+	if sid, ok := sidByName[name]; ok {
+		return sid
+	}
+	return name
+}
+
+// AccountSet parses a comma-separated account list into a sorted set of
+// normalized accounts. "No One" and the empty string are the empty set.
+func AccountSet(value string) []string {
+	set := []string{}
+	if v := strings.TrimSpace(value); v == "" || strings.EqualFold(v, "No One") {
+		return set
+	}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		if a := NormalizeAccount(part); a != "" && !seen[a] {
+			seen[a] = true
+			set = append(set, a)
+		}
+	}
+	sort.Strings(set)
+	return set
+}
+
+// DisplayAccounts formats an account set for reports, naming well-known SIDs.
+func DisplayAccounts(set []string) string {
+	if len(set) == 0 {
+		return "No One"
+	}
+	out := make([]string, len(set))
+	for i, a := range set {
+		if name, ok := nameBySID[a]; ok {
+			out[i] = name
+		} else {
+			out[i] = a
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// TemplateAccounts formats an account list for a template, writing
+// well-known accounts as "*SID" like secedit does, so it works on any locale.
+func TemplateAccounts(value string) string {
+	if v := strings.TrimSpace(value); v == "" || strings.EqualFold(v, "No One") {
+		return ""
+	}
+	var out []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		a := NormalizeAccount(part)
+		if a == "" || seen[a] {
+			continue
+		}
+		seen[a] = true
+		if strings.HasPrefix(a, "S-1-") {
+			out = append(out, "*"+a)
+		} else {
+			out = append(out, strings.Trim(strings.TrimSpace(part), `"`))
+		}
+	}
+	return strings.Join(out, ",")
+}
@@ -0,0 +1,204 @@
+// Package secedit models the security templates that `secedit /export`
+// writes and `secedit /configure` reads. It has no Windows dependencies, so
+// exported templates can be parsed and compared on any platform.
+package secedit
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// Section names
+const (
+	SectionSystemAccess    = "System Access"
+	SectionEventAudit      = "Event Audit"
+	SectionPrivilegeRights = "Privilege Rights"
+)
+
+// Template is a parsed security template (.inf).
+type Template struct {
+	SystemAccess    map[string]string   // e.g. MinimumPasswordLength = 14
+	EventAudit      map[string]string   // e.g. AuditLogonEvents = 3
+	PrivilegeRights map[string][]string // right -> normalized account set, see NormalizeAccount
+
+	// Sections holds every section's raw key/value pairs, including the
+	// ones above and others such as [Registry Values]
+	Sections map[string]map[string]string
+}
+
+// Parse reads a template as written by secedit: UTF-16 (LE or BE, with or
+// without a BOM) or UTF-8, with CRLF line endings and ';' comments.
+func Parse(data []byte) (*Template, error) {
+	text, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{
+		SystemAccess:    make(map[string]string),
+		EventAudit:      make(map[string]string),
+		PrivilegeRights: make(map[string][]string),
+		Sections:        make(map[string]map[string]string),
+	}
+	section := ""
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed section header %q", n+1, line)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if t.Sections[section] == nil {
+				t.Sections[section] = make(map[string]string)
+			}
+			continue
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: entry outside of a section", n+1)
+		}
+
+		key, value := line, ""
+		if i := strings.Index(line, "="); i >= 0 {
+			key, value = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+		t.Sections[section][key] = value
+
+		switch {
+		case strings.EqualFold(section, SectionSystemAccess):
+			t.SystemAccess[key] = unquote(value)
+		case strings.EqualFold(section, SectionEventAudit):
+			t.EventAudit[key] = value
+		case strings.EqualFold(section, SectionPrivilegeRights):
+			t.PrivilegeRights[key] = AccountSet(value)
+		}
+	}
+	return t, nil
+}
+
+// Right returns the accounts holding a privilege right. A right missing
+// from the template is held by no one.
+func (t *Template) Right(name string) []string {
+	if accounts, ok := t.PrivilegeRights[name]; ok {
+		return accounts
+	}
+	for key, accounts := range t.PrivilegeRights {
+		if strings.EqualFold(key, name) {
+			return accounts
+		}
+	}
+	return nil
+}
+
+// Setting looks a System Access or Event Audit value up by name and reports
+// the section it was found in.
+func (t *Template) Setting(name string) (value, section string, ok bool) {
+	for _, s := range []struct {
+		name   string
+		values map[string]string
+	}{{SectionSystemAccess, t.SystemAccess}, {SectionEventAudit, t.EventAudit}} {
+		for key, v := range s.values {
+			if strings.EqualFold(key, name) {
+				return v, s.name, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// IsPrivilege reports whether name is a privilege or logon right
+// (SeDebugPrivilege, SeDenyNetworkLogonRight, ...).
+func IsPrivilege(name string) bool {
+	return strings.HasPrefix(name, "Se") && (strings.HasSuffix(name, "Privilege") || strings.HasSuffix(name, "Right"))
+}
+
+// SectionFor returns the template section a setting belongs in.
+func SectionFor(name string) string {
+	switch {
+	case IsPrivilege(name):
+		return SectionPrivilegeRights
+	case strings.HasPrefix(name, "Audit"):
+		return SectionEventAudit
+	}
+	return SectionSystemAccess
+}
+
+// Render builds a minimal template that sets one value, for secedit /configure.
+func Render(section, key, value string) []byte {
+	return []byte(fmt.Sprintf("[Unicode]\r\nUnicode=yes\r\n[%s]\r\n%s = %s\r\n[Version]\r\nsignature=\"$CHICAGO$\"\r\nRevision=1\r\n", section, key, value))
+}
+
+// decode converts the raw file to a string.
+func decode(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true)
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil
+	}
+	// secedit always writes a BOM, but copies may have lost it: ASCII text
+	// in UTF-16 has a zero in every other byte
+	if len(data) >= 4 && data[0] != 0 && data[1] == 0 && data[3] == 0 {
+		return decodeUTF16(data, false)
+	}
+	if len(data) >= 4 && data[0] == 0 && data[2] == 0 && data[1] != 0 {
+		return decodeUTF16(data, true)
+	}
+	return string(data), nil
+}
+
+func decodeUTF16(data []byte, bigEndian bool) (string, error) {
+	if len(data)%2 != 0 {
+		return "", fmt.Errorf("truncated UTF-16 template (%d bytes)", len(data))
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			units[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// EncodeUTF16 encodes text the way secedit writes templates (UTF-16LE with a BOM).
+func EncodeUTF16(text string) []byte {
+	units := utf16.Encode([]rune(text))
+	out := make([]byte, 2, 2+2*len(units))
+	out[0], out[1] = 0xFF, 0xFE
+	for _, u := range units {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// SameAccounts reports whether two normalized account sets are equal.
+func SameAccounts(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,197 @@
+package secedit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func loadTemplate(t *testing.T, name string) *Template {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", name, err)
+	}
+	return tmpl
+}
+
+// The same secedit export, as written (UTF-16LE with a BOM), with the BOM
+// lost in a copy, and converted to UTF-8.
+var exportFixtures = []string{"export_utf16le_bom.inf", "export_utf16le_nobom.inf", "export_utf8.inf"}
+
+func TestParseExport(t *testing.T) {
+	for _, name := range exportFixtures {
+		t.Run(name, func(t *testing.T) {
+			tmpl := loadTemplate(t, name)
+
+			settings := []struct {
+				name    string
+				value   string
+				section string
+			}{
+				{"MinimumPasswordLength", "14", SectionSystemAccess},
+				{"minimumpasswordlength", "14", SectionSystemAccess},
+				{"NewAdministratorName", "Administrator", SectionSystemAccess}, // quotes removed
+				{"AuditLogonEvents", "3", SectionEventAudit},
+				{"AuditPrivilegeUse", "0", SectionEventAudit},
+			}
+			for _, s := range settings {
+				value, section, ok := tmpl.Setting(s.name)
+				if !ok || value != s.value || section != s.section {
+					t.Errorf("Setting(%s) = %q, %q, %v; want %q, %q", s.name, value, section, ok, s.value, s.section)
+				}
+			}
+			if _, _, ok := tmpl.Setting("MinimumPasswordHistory"); ok {
+				t.Error("Setting(MinimumPasswordHistory) found a value the export doesn't have")
+			}
+
+			rights := []struct {
+				name string
+				want []string
+			}{
+				{"SeDenyNetworkLogonRight", []string{"S-1-5-32-546"}},
+				{"sedenynetworklogonright", []string{"S-1-5-32-546"}},
+				{"SeBackupPrivilege", []string{"S-1-5-32-544", "S-1-5-32-551"}},
+				{"SeServiceLogonRight", []string{"S-1-5-80-0", "svc_backup"}},
+				{"SeDebugPrivilege", nil}, // not in the export: held by no one
+			}
+			for _, r := range rights {
+				if got := tmpl.Right(r.name); !reflect.DeepEqual(got, r.want) {
+					t.Errorf("Right(%s) = %q, want %q", r.name, got, r.want)
+				}
+			}
+
+			const lsa = `MACHINE\System\CurrentControlSet\Control\Lsa\LimitBlankPasswordUse`
+			if got := tmpl.Sections["Registry Values"][lsa]; got != "4,1" {
+				t.Errorf("[Registry Values] %s = %q, want 4,1", lsa, got)
+			}
+		})
+	}
+}
+
+func TestParseEncodingsAgree(t *testing.T) {
+	want := loadTemplate(t, exportFixtures[0])
+	for _, name := range exportFixtures[1:] {
+		if got := loadTemplate(t, name); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s parses differently from %s", name, exportFixtures[0])
+		}
+	}
+}
+
+// names.inf lists the export's accounts by name; the sets have to compare
+// equal right by right, except where the fixture differs on purpose.
+func TestRightsBySIDAndName(t *testing.T) {
+	export := loadTemplate(t, "export_utf16le_bom.inf")
+	names := loadTemplate(t, "names.inf")
+
+	tests := []struct {
+		right string
+		same  bool
+	}{
+		{"SeNetworkLogonRight", true},
+		{"SeBackupPrivilege", true},
+		{"SeDenyNetworkLogonRight", true},
+		{"SeRemoteInteractiveLogonRight", true},
+		{"SeServiceLogonRight", true},
+		{"SeInteractiveLogonRight", false}, // names.inf leaves out Backup Operators
+		{"SeDebugPrivilege", true},         // in neither
+	}
+	for _, tt := range tests {
+		t.Run(tt.right, func(t *testing.T) {
+			a, b := export.Right(tt.right), names.Right(tt.right)
+			if got := SameAccounts(a, b); got != tt.same {
+				t.Errorf("SameAccounts(%q, %q) = %v, want %v", a, b, got, tt.same)
+			}
+		})
+	}
+}
+
+func TestAccountSet(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", []string{}},
+		{"No One", []string{}},
+		{"no one", []string{}},
+		{"*S-1-5-32-546", []string{"S-1-5-32-546"}},
+		{"Guests", []string{"S-1-5-32-546"}},
+		{`BUILTIN\Guests, *S-1-5-32-546`, []string{"S-1-5-32-546"}},
+		{`NT AUTHORITY\Local System`, []string{"S-1-5-18"}},
+		{`"Remote Desktop Users",Administrators`, []string{"S-1-5-32-544", "S-1-5-32-555"}},
+		{"*s-1-5-21-100-200-300-1001", []string{"S-1-5-21-100-200-300-1001"}},
+		{`CONTOSO\svc_Backup`, []string{`contoso\svc_backup`}},
+	}
+	for _, tt := range tests {
+		if got := AccountSet(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("AccountSet(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSameAccounts(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"", "No One", true},
+		{"Guests", "*S-1-5-32-546", true},
+		{"Administrators,Users", "*S-1-5-32-545, BUILTIN\\Administrators", true},
+		{"Administrators", "Administrators,Users", false},
+		{"Guests", "", false},
+		{"svc_backup", "SVC_BACKUP", true},
+	}
+	for _, tt := range tests {
+		if got := SameAccounts(AccountSet(tt.a), AccountSet(tt.b)); got != tt.want {
+			t.Errorf("SameAccounts(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateAccounts(t *testing.T) {
+	tests := []struct{ value, want string }{
+		{"No One", ""},
+		{"Guests", "*S-1-5-32-546"},
+		{`BUILTIN\Administrators, Guests, *S-1-5-32-546`, "*S-1-5-32-544,*S-1-5-32-546"},
+		{`"CONTOSO\svc_backup"`, `CONTOSO\svc_backup`},
+	}
+	for _, tt := range tests {
+		if got := TemplateAccounts(tt.value); got != tt.want {
+			t.Errorf("TemplateAccounts(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"malformed section header", []byte("[System Access\r\nMinimumPasswordLength = 14\r\n")},
+		{"entry outside of a section", []byte("MinimumPasswordLength = 14\r\n")},
+		{"truncated UTF-16", append(EncodeUTF16("[Version]\r\n"), 'x')},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.data); err == nil {
+			t.Errorf("%s: Parse succeeded, want an error", tt.name)
+		}
+	}
+}
+
+// Templates built for secedit /configure read back the same way.
+func TestRenderRoundTrip(t *testing.T) {
+	data := EncodeUTF16(string(Render(SectionPrivilegeRights, "SeDenyNetworkLogonRight", TemplateAccounts("Guests, Local account"))))
+	tmpl, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"S-1-5-113", "S-1-5-32-546"}
+	if got := tmpl.Right("SeDenyNetworkLogonRight"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Right = %q, want %q", got, want)
+	}
+}
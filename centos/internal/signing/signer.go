@@ -0,0 +1,225 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	PrivateKeyFile = "sentinelx_ed25519.pem"
+	PublicKeyFile  = "sentinelx_ed25519.pub"
+
+	// SignatureExt is appended to an artifact's path to form its detached signature.
+	SignatureExt = ".sig"
+)
+
+// Signature is the detached signature written next to every signed artifact.
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	File      string `json:"file"`
+	SHA256    string `json:"sha256"`
+	Signature string `json:"signature"`
+	SignedAt  string `json:"signed_at"`
+}
+
+var signingKey ed25519.PrivateKey
+
+// Init loads the tool's signing key from keyDir, generating and storing a new
+// key pair on first use. Once initialised every exported artifact is signed.
+func Init(keyDir string) error {
+	key, err := loadPrivateKey(filepath.Join(keyDir, PrivateKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		key, err = generateKey(keyDir)
+	}
+	if err != nil {
+		return err
+	}
+	signingKey = key
+	return nil
+}
+
+// Enabled reports whether a signing key has been loaded.
+func Enabled() bool {
+	return signingKey != nil
+}
+
+// KeyID is a short fingerprint of the public key, printed in reports.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// CurrentKeyID returns the fingerprint of the loaded signing key.
+func CurrentKeyID() string {
+	if !Enabled() {
+		return ""
+	}
+	return KeyID(signingKey.Public().(ed25519.PublicKey))
+}
+
+// SignDigest signs a SHA-256 digest and returns the base64 signature.
+func SignDigest(digest []byte) (string, error) {
+	if !Enabled() {
+		return "", fmt.Errorf("signing key not initialised")
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(signingKey, digest)), nil
+}
+
+// SignFile writes a detached signature for path to path+SignatureExt.
+func SignFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(data)
+	sig, err := SignDigest(digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	pub := signingKey.Public().(ed25519.PublicKey)
+	out, _ := json.MarshalIndent(Signature{
+		Algorithm: "ed25519",
+		KeyID:     KeyID(pub),
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+		File:      filepath.Base(path),
+		SHA256:    hex.EncodeToString(digest[:]),
+		Signature: sig,
+		SignedAt:  time.Now().UTC().Format(time.RFC3339),
+	}, "", "  ")
+
+	sigPath := path + SignatureExt
+	if err := os.WriteFile(sigPath, out, 0644); err != nil {
+		return "", err
+	}
+	return sigPath, nil
+}
+
+// VerifyFile checks path against its detached signature. When trusted is nil
+// the public key embedded in the signature is used, which only proves the
+// file is intact, not who produced it.
+func VerifyFile(path string, trusted ed25519.PublicKey) (*Signature, error) {
+	raw, err := os.ReadFile(path + SignatureExt)
+	if err != nil {
+		return nil, fmt.Errorf("no detached signature found: %v", err)
+	}
+	var sig Signature
+	if err := json.Unmarshal(raw, &sig); err != nil {
+		return nil, fmt.Errorf("malformed signature file: %v", err)
+	}
+	if sig.Algorithm != "ed25519" {
+		return &sig, fmt.Errorf("unsupported algorithm: %s", sig.Algorithm)
+	}
+
+	pub := trusted
+	if pub == nil {
+		embedded, err := base64.StdEncoding.DecodeString(sig.PublicKey)
+		if err != nil || len(embedded) != ed25519.PublicKeySize {
+			return &sig, fmt.Errorf("invalid embedded public key")
+		}
+		pub = ed25519.PublicKey(embedded)
+	} else if KeyID(pub) != sig.KeyID {
+		return &sig, fmt.Errorf("signed by unknown key %s (trusted key is %s)", sig.KeyID, KeyID(pub))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return &sig, err
+	}
+	digest := sha256.Sum256(data)
+	if hex.EncodeToString(digest[:]) != sig.SHA256 {
+		return &sig, fmt.Errorf("file has been modified: SHA-256 mismatch")
+	}
+
+	rawSig, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return &sig, fmt.Errorf("malformed signature: %v", err)
+	}
+	if !ed25519.Verify(pub, digest[:], rawSig) {
+		return &sig, fmt.Errorf("signature does not match")
+	}
+	return &sig, nil
+}
+
+// LoadPublicKey reads a PEM encoded Ed25519 public key.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return pub, nil
+}
+
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+func generateKey(keyDir string) (ed25519.PrivateKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(keyDir, 0700); err != nil {
+		return nil, err
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	privPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})
+	if err := os.WriteFile(filepath.Join(keyDir, PrivateKeyFile), privPEM, 0600); err != nil {
+		return nil, err
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	if err := os.WriteFile(filepath.Join(keyDir, PublicKeyFile), pubPEM, 0644); err != nil {
+		return nil, err
+	}
+	return priv, nil
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"time"
+)
+
+// FileBackup is a copy of a file taken before a remediation (or rollback)
+// changed it.
+type FileBackup struct {
+	ID        int64     `json:"id"`
+	RuleID    string    `json:"rule_id"`
+	Path      string    `json:"path"`   // the file on the target
+	Backup    string    `json:"backup"` // where the copy is, on the same host
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func initBackupTable() error {
+	query := `
+    CREATE TABLE IF NOT EXISTS file_backups (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        rule_id TEXT NOT NULL,
+        path TEXT NOT NULL,
+        backup TEXT NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );
+    CREATE INDEX IF NOT EXISTS idx_file_backups_rule ON file_backups(rule_id);`
+	_, err := DB.Exec(query)
+	return err
+}
+
+// RecordBackup remembers where the backup of a rule's file went.
+func RecordBackup(ruleID, path, backup string) error {
+	_, err := DB.Exec(`INSERT INTO file_backups (rule_id, path, backup, created_at) VALUES (?, ?, ?, ?)`,
+		ruleID, path, backup, time.Now())
+	return err
+}
+
+// ListBackups returns the backups taken for a rule, newest first.
+func ListBackups(ruleID string) ([]FileBackup, error) {
+	rows, err := DB.Query(`SELECT id, rule_id, path, backup, created_at FROM file_backups WHERE rule_id = ? ORDER BY id DESC`, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []FileBackup
+	for rows.Next() {
+		var b FileBackup
+		if err := rows.Scan(&b.ID, &b.RuleID, &b.Path, &b.Backup, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, b)
+	}
+	return list, rows.Err()
+}
@@ -2,7 +2,9 @@ package state
 
 import (
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
+	"os"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -10,12 +12,28 @@ import (
 
 var DB *sql.DB
 
+// DBPath is the SQLite file InitDB opens.
+var DBPath = "hardening.db"
+
 // InitDB creates the table (Keep existing code)
 func InitDB() {
+	if err := Open(DBPath); err != nil {
+		slog.Error("failed to open state database", "path", DBPath, "err", err)
+		os.Exit(1)
+	}
+}
+
+// Open points DB at the given SQLite file and creates the tables.
+// ":memory:" gives a throwaway database for tests.
+func Open(path string) error {
 	var err error
-	DB, err = sql.Open("sqlite", "hardening.db")
+	DB, err = sql.Open("sqlite", path)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if path == ":memory:" {
+		// Every pooled connection would otherwise get its own empty database
+		DB.SetMaxOpenConns(1)
 	}
 
 	query := `
@@ -29,24 +47,171 @@ func InitDB() {
     );`
 	_, err = DB.Exec(query)
 	if err != nil {
-		log.Fatalf("Failed to create DB table: %v", err)
+		return fmt.Errorf("failed to create DB table: %v", err)
+	}
+
+	// Added after the first release; databases from older versions get it here
+	if err := addColumn("rollback_log", "snapshot", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "validation", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "verification", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "verified_value", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "tx_id", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "reverted_at", "DATETIME"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "error", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "actor", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "reverted_by", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "targets", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "machine", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+	if err := addColumn("rollback_log", "backups", "TEXT"); err != nil {
+		return fmt.Errorf("failed to migrate rollback_log: %v", err)
+	}
+
+	if err := initFleetTables(); err != nil {
+		return fmt.Errorf("failed to create fleet tables: %v", err)
+	}
+	if err := initWaiverTable(); err != nil {
+		return fmt.Errorf("failed to create waiver table: %v", err)
+	}
+	if err := initBackupTable(); err != nil {
+		return fmt.Errorf("failed to create backup table: %v", err)
+	}
+	if err := initServiceTable(); err != nil {
+		return fmt.Errorf("failed to create service table: %v", err)
+	}
+	if err := initRebootTable(); err != nil {
+		return fmt.Errorf("failed to create reboot table: %v", err)
+	}
+	if err := initScanTables(); err != nil {
+		return fmt.Errorf("failed to create scan tables: %v", err)
+	}
+	return nil
+}
+
+// addColumn adds a column to an existing table unless it is already there.
+func addColumn(table, column, decl string) error {
+	rows, err := DB.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			cid           int
+			name, colType string
+			notNull, pk   int
+			defaultValue  sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
+	rows.Close()
+	_, err = DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	return err
 }
 
 // LogAction (Keep existing code)
 func LogAction(ruleID, ruleName, prevVal, newVal string) error {
-	query := `INSERT INTO rollback_log (rule_id, rule_name, prev_value, new_value, timestamp) VALUES (?, ?, ?, ?, ?)`
-	_, err := DB.Exec(query, ruleID, ruleName, prevVal, newVal, time.Now())
+	return LogActionSnapshot(ruleID, ruleName, prevVal, newVal, "")
+}
+
+// LogActionSnapshot is LogAction plus an exact, machine-readable copy of the
+// previous state (e.g. a registry value with its type) for the rollback.
+func LogActionSnapshot(ruleID, ruleName, prevVal, newVal, snapshot string) error {
+	_, err := LogFix(Transaction{}, ruleID, ruleName, prevVal, newVal, snapshot)
+	return err
+}
+
+// GetSnapshot returns the oldest snapshot of a rule on machine that has not
+// been restored yet, i.e. the state from before the first fix since the last
+// revert.
+func GetSnapshot(machine, ruleID string) (string, bool) {
+	if DB == nil {
+		return "", false
+	}
+	var snapshot string
+	query := `SELECT snapshot FROM rollback_log WHERE rule_id = ? AND snapshot IS NOT NULL AND snapshot != '' AND ` + onMachine + ` ORDER BY id LIMIT 1`
+	if err := DB.QueryRow(query, ruleID, machine).Scan(&snapshot); err != nil {
+		return "", false
+	}
+	return snapshot, true
+}
+
+// ClearSnapshots marks a rule's snapshots on machine as restored.
+func ClearSnapshots(machine, ruleID string) error {
+	_, err := DB.Exec(`UPDATE rollback_log SET snapshot = '' WHERE rule_id = ? AND `+onMachine, ruleID, machine)
 	return err
 }
 
+// RecordValidation stores the output of a fix's validate command with its
+// log entry. A fix that was undone because validation failed is marked as
+// such, and its snapshot dropped since there is nothing to revert.
+func RecordValidation(id int64, output string, reverted bool) error {
+	query := `UPDATE rollback_log SET validation = ? WHERE id = ?`
+	if reverted {
+		query = `UPDATE rollback_log SET validation = ?, new_value = 'Reverted: validation failed', snapshot = '', reverted_at = ?, reverted_by = 'validation' WHERE id = ?`
+		_, err := DB.Exec(query, output, time.Now(), id)
+		return err
+	}
+	_, err := DB.Exec(query, output, id)
+	return err
+}
+
+// RecordVerification stores the outcome of re-checking a rule after the fix
+// logged as id (VERIFIED, UNVERIFIED or PENDING_REBOOT) and the value the
+// check then read.
+func RecordVerification(id int64, verification, actual string) error {
+	query := `UPDATE rollback_log SET verification = ?, verified_value = ? WHERE id = ?`
+	_, err := DB.Exec(query, verification, actual, id)
+	return err
+}
+
+// GetVerification returns the verification outcome of a rule's latest fix
+// on machine.
+func GetVerification(machine, ruleID string) (string, bool) {
+	var verification sql.NullString
+	query := `SELECT verification FROM rollback_log WHERE rule_id = ? AND ` + onMachine + ` ORDER BY id DESC LIMIT 1`
+	if err := DB.QueryRow(query, ruleID, machine).Scan(&verification); err != nil || !verification.Valid {
+		return "", false
+	}
+	return verification.String, true
+}
+
 // --- NEW FUNCTION ---
-// GetRuleHistory fetches the most recent Previous and New values for a rule
-func GetRuleHistory(ruleID string) (string, string, bool) {
+// GetRuleHistory fetches the most recent Previous and New values for a rule on machine
+func GetRuleHistory(machine, ruleID string) (string, string, bool) {
 	var prevVal, newVal string
 	// Get the latest log entry for this rule
-	query := `SELECT prev_value, new_value FROM rollback_log WHERE rule_id = ? ORDER BY timestamp DESC LIMIT 1`
-	err := DB.QueryRow(query, ruleID).Scan(&prevVal, &newVal)
+	query := `SELECT prev_value, new_value FROM rollback_log WHERE rule_id = ? AND ` + onMachine + ` ORDER BY timestamp DESC LIMIT 1`
+	err := DB.QueryRow(query, ruleID, machine).Scan(&prevVal, &newVal)
 	if err != nil {
 		return "", "", false // No history found
 	}
@@ -62,15 +227,3 @@ func GetRollbackData(ruleID string) (string, error) {
     }
     return prevVal, nil
 }
-
-// IsRuleFixed checks if a rule is currently marked as FIXED in the DB
-func IsRuleFixed(ruleID string) bool {
-    var status string
-    // Query the DB for the status of this rule ID
-    row := DB.QueryRow("SELECT status FROM rules_state WHERE id = ?", ruleID)
-    err := row.Scan(&status)
-    if err != nil {
-        return false // Not found or error = Not fixed
-    }
-    return status == "FIXED"
-}
\ No newline at end of file
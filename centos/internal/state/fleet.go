@@ -0,0 +1,176 @@
+package state
+
+import (
+	"time"
+)
+
+// FleetRun is one scan run pushed to the collector by an agent.
+type FleetRun struct {
+	ID         int64     `json:"id"`
+	Host       string    `json:"host"`
+	OS         string    `json:"os"`
+	Profile    string    `json:"profile"`
+	ScannedAt  time.Time `json:"scanned_at"`
+	ReceivedAt time.Time `json:"received_at"`
+	Pass       int       `json:"pass"`
+	Fail       int       `json:"fail"`
+	Total      int       `json:"total"`
+}
+
+// FleetResult is a single rule outcome within a FleetRun.
+type FleetResult struct {
+	RuleID   string `json:"id"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Status   string `json:"status"`
+	Actual   string `json:"actual"`
+	Expected string `json:"expected"`
+}
+
+// RuleFailure counts how many hosts currently fail a rule.
+type RuleFailure struct {
+	RuleID   string `json:"id"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Hosts    int    `json:"hosts"`
+}
+
+func initFleetTables() error {
+	query := `
+    CREATE TABLE IF NOT EXISTS fleet_runs (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        host TEXT,
+        os TEXT,
+        profile TEXT,
+        scanned_at DATETIME,
+        received_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        pass INTEGER,
+        fail INTEGER,
+        total INTEGER
+    );
+    CREATE INDEX IF NOT EXISTS idx_fleet_runs_host ON fleet_runs(host, scanned_at);
+    CREATE TABLE IF NOT EXISTS fleet_results (
+        run_id INTEGER REFERENCES fleet_runs(id) ON DELETE CASCADE,
+        rule_id TEXT,
+        rule_name TEXT,
+        severity TEXT,
+        status TEXT,
+        actual TEXT,
+        expected TEXT
+    );
+    CREATE INDEX IF NOT EXISTS idx_fleet_results_run ON fleet_results(run_id);`
+	_, err := DB.Exec(query)
+	return err
+}
+
+// SaveFleetRun stores a run and its results, returning the new run ID.
+func SaveFleetRun(run FleetRun, results []FleetResult) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO fleet_runs (host, os, profile, scanned_at, received_at, pass, fail, total) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		run.Host, run.OS, run.Profile, run.ScannedAt, time.Now(), run.Pass, run.Fail, run.Total)
+	if err != nil {
+		return 0, err
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO fleet_results (run_id, rule_id, rule_name, severity, status, actual, expected) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, r := range results {
+		if _, err := stmt.Exec(runID, r.RuleID, r.Name, r.Severity, r.Status, r.Actual, r.Expected); err != nil {
+			return 0, err
+		}
+	}
+	return runID, tx.Commit()
+}
+
+// ListFleetHosts returns the latest run of every host that has reported in.
+func ListFleetHosts() ([]FleetRun, error) {
+	return queryFleetRuns(`
+        SELECT id, host, os, profile, scanned_at, received_at, pass, fail, total FROM fleet_runs
+        WHERE id IN (SELECT MAX(id) FROM fleet_runs GROUP BY host)
+        ORDER BY host`)
+}
+
+// ListFleetRuns returns the run history of one host, newest first.
+func ListFleetRuns(host string, limit int) ([]FleetRun, error) {
+	return queryFleetRuns(`
+        SELECT id, host, os, profile, scanned_at, received_at, pass, fail, total FROM fleet_runs
+        WHERE host = ? ORDER BY id DESC LIMIT ?`, host, limit)
+}
+
+// GetFleetRun fetches a single run with its results.
+func GetFleetRun(runID int64) (*FleetRun, []FleetResult, error) {
+	runs, err := queryFleetRuns(`
+        SELECT id, host, os, profile, scanned_at, received_at, pass, fail, total FROM fleet_runs
+        WHERE id = ?`, runID)
+	if err != nil || len(runs) == 0 {
+		return nil, nil, err
+	}
+
+	rows, err := DB.Query(`SELECT rule_id, rule_name, severity, status, actual, expected FROM fleet_results WHERE run_id = ?`, runID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var results []FleetResult
+	for rows.Next() {
+		var r FleetResult
+		if err := rows.Scan(&r.RuleID, &r.Name, &r.Severity, &r.Status, &r.Actual, &r.Expected); err != nil {
+			return nil, nil, err
+		}
+		results = append(results, r)
+	}
+	return &runs[0], results, rows.Err()
+}
+
+// TopFleetFailures ranks rules by how many hosts fail them in their latest run.
+func TopFleetFailures(limit int) ([]RuleFailure, error) {
+	rows, err := DB.Query(`
+        SELECT rule_id, MAX(rule_name), MAX(severity), COUNT(*) AS hosts FROM fleet_results
+        WHERE status = 'FAIL' AND run_id IN (SELECT MAX(id) FROM fleet_runs GROUP BY host)
+        GROUP BY rule_id ORDER BY hosts DESC, rule_id LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var failures []RuleFailure
+	for rows.Next() {
+		var f RuleFailure
+		if err := rows.Scan(&f.RuleID, &f.Name, &f.Severity, &f.Hosts); err != nil {
+			return nil, err
+		}
+		failures = append(failures, f)
+	}
+	return failures, rows.Err()
+}
+
+func queryFleetRuns(query string, args ...interface{}) ([]FleetRun, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []FleetRun
+	for rows.Next() {
+		var r FleetRun
+		if err := rows.Scan(&r.ID, &r.Host, &r.OS, &r.Profile, &r.ScannedAt, &r.ReceivedAt, &r.Pass, &r.Fail, &r.Total); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
@@ -0,0 +1,260 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Transaction is a batch of fixes applied together, who applied them and on
+// which machine (its platform machine ID), so a revert only touches that
+// machine's fixes.
+type Transaction struct {
+	ID      string
+	Actor   string
+	Machine string
+}
+
+// NewTransaction starts a transaction for actor, so a reset can go back to
+// before it.
+func NewTransaction(actor string) Transaction {
+	return Transaction{ID: "TX-" + time.Now().Format("20060102-150405.000000"), Actor: actor}
+}
+
+// HistoryEntry is one fix in rollback_log.
+type HistoryEntry struct {
+	ID            int64      `json:"id"`
+	TxID          string     `json:"tx_id,omitempty"`
+	RuleID        string     `json:"rule_id"`
+	RuleName      string     `json:"rule_name"`
+	PrevValue     string     `json:"prev_value"`
+	NewValue      string     `json:"new_value"`
+	Actor         string     `json:"actor,omitempty"`
+	Machine       string     `json:"machine,omitempty"`
+	Timestamp     time.Time  `json:"timestamp"`
+	Targets       []string   `json:"targets,omitempty"` // files, config keys and registry values the fix wrote
+	Validation    string     `json:"validation,omitempty"`
+	Verification  string     `json:"verification,omitempty"`
+	VerifiedValue string     `json:"verified_value,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	RevertedAt    *time.Time `json:"reverted_at,omitempty"`
+	RevertedBy    string     `json:"reverted_by,omitempty"`
+	Status        string     `json:"status"` // applied, reverted or failed
+
+	// What reverting this entry alone restores: the registry state it
+	// replaced, or each file it wrote and its backup ("" for files it created)
+	Snapshot string            `json:"-"`
+	Backups  map[string]string `json:"-"`
+
+	legacy bool // logged before machines were recorded
+}
+
+// OnMachine reports whether the entry's fix was made on machine, with the
+// same rule for older entries as the queries taking a machine.
+func (e HistoryEntry) OnMachine(machine string) bool {
+	return e.legacy || e.Machine == machine
+}
+
+// HistoryFilter selects rollback_log entries; zero fields match everything.
+type HistoryFilter struct {
+	RuleID string
+	TxID   string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+func (f HistoryFilter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if f.RuleID != "" {
+		conds = append(conds, "rule_id = ?")
+		args = append(args, f.RuleID)
+	}
+	if f.TxID != "" {
+		conds = append(conds, "tx_id = ?")
+		args = append(args, f.TxID)
+	}
+	if !f.From.IsZero() {
+		conds = append(conds, "timestamp >= ?")
+		args = append(args, f.From.Local()) // stored as local time text
+	}
+	if !f.To.IsZero() {
+		conds = append(conds, "timestamp < ?")
+		args = append(args, f.To.Local())
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+const historyColumns = `id, tx_id, rule_id, rule_name, prev_value, new_value, actor, machine, timestamp, targets,
+        validation, verification, verified_value, error, reverted_at, reverted_by, snapshot, backups`
+
+// onMachine matches the rows of one machine. Rows logged before machines were
+// recorded have none and are taken to be the local host's.
+const onMachine = `(machine = ? OR machine IS NULL)`
+
+func scanHistory(rows *sql.Rows) ([]HistoryEntry, error) {
+	defer rows.Close()
+	var entries []HistoryEntry
+	for rows.Next() {
+		var e HistoryEntry
+		var txID, name, prev, next, actor, machine, targets, validation, verification, verified, fixErr, revertedBy, snapshot, backups sql.NullString
+		var revertedAt sql.NullTime
+		if err := rows.Scan(&e.ID, &txID, &e.RuleID, &name, &prev, &next, &actor, &machine, &e.Timestamp, &targets,
+			&validation, &verification, &verified, &fixErr, &revertedAt, &revertedBy, &snapshot, &backups); err != nil {
+			return nil, err
+		}
+		e.Snapshot = snapshot.String
+		if backups.String != "" {
+			if err := json.Unmarshal([]byte(backups.String), &e.Backups); err != nil {
+				return nil, fmt.Errorf("history entry %d: corrupt backups: %v", e.ID, err)
+			}
+		}
+		e.TxID, e.RuleName, e.PrevValue, e.NewValue, e.Actor, e.Machine = txID.String, name.String, prev.String, next.String, actor.String, machine.String
+		e.legacy = !machine.Valid
+		e.Validation, e.Verification, e.VerifiedValue, e.Error, e.RevertedBy = validation.String, verification.String, verified.String, fixErr.String, revertedBy.String
+		if targets.String != "" {
+			e.Targets = strings.Split(targets.String, "\n")
+		}
+		e.Status = "applied"
+		if e.Error != "" {
+			e.Status = "failed"
+		}
+		if revertedAt.Valid {
+			t := revertedAt.Time
+			e.RevertedAt = &t
+			e.Status = "reverted"
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ListHistory returns one page of rollback_log entries, newest first, and
+// how many match the filter in total.
+func ListHistory(f HistoryFilter) ([]HistoryEntry, int, error) {
+	where, args := f.where()
+	var total int
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM rollback_log`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if f.Limit <= 0 {
+		f.Limit = -1 // no limit
+	}
+	rows, err := DB.Query(`SELECT `+historyColumns+` FROM rollback_log`+where+` ORDER BY id DESC LIMIT ? OFFSET ?`,
+		append(args, f.Limit, f.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries, err := scanHistory(rows)
+	return entries, total, err
+}
+
+// GetHistoryEntry returns one rollback_log entry, or nil if there is none.
+func GetHistoryEntry(id int64) (*HistoryEntry, error) {
+	rows, err := DB.Query(`SELECT `+historyColumns+` FROM rollback_log WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := scanHistory(rows)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// LogFix records a fix made as part of tx, before it is applied. It returns
+// the row's id for RecordFixTargets and RecordFixError.
+func LogFix(tx Transaction, ruleID, ruleName, prevVal, newVal, snapshot string) (int64, error) {
+	query := `INSERT INTO rollback_log (tx_id, actor, machine, rule_id, rule_name, prev_value, new_value, snapshot, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := DB.Exec(query, tx.ID, tx.Actor, tx.Machine, ruleID, ruleName, prevVal, newVal, snapshot, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// RecordFixTargets stores what a logged fix wrote, for conflict detection.
+func RecordFixTargets(id int64, targets []string) error {
+	_, err := DB.Exec(`UPDATE rollback_log SET targets = ? WHERE id = ?`, strings.Join(targets, "\n"), id)
+	return err
+}
+
+// RecordFixBackups stores the backup of each file a logged fix wrote ("" for
+// a file it created), so the entry can be reverted on its own.
+func RecordFixBackups(id int64, backups map[string]string) error {
+	data, err := json.Marshal(backups)
+	if err != nil {
+		return err
+	}
+	_, err = DB.Exec(`UPDATE rollback_log SET backups = ? WHERE id = ?`, string(data), id)
+	return err
+}
+
+// RecordFixError marks a logged fix as failed. Failed fixes are left alone by
+// a reset: whatever they changed has to be reverted by hand.
+func RecordFixError(id int64, msg string) error {
+	_, err := DB.Exec(`UPDATE rollback_log SET error = ? WHERE id = ?`, msg, id)
+	return err
+}
+
+// MarkReverted records that actor undid a rule's fixes on machine.
+func MarkReverted(machine, ruleID, actor string) error {
+	_, err := DB.Exec(`UPDATE rollback_log SET reverted_at = ?, reverted_by = ? WHERE rule_id = ? AND reverted_at IS NULL AND `+onMachine,
+		time.Now(), actor, ruleID, machine)
+	return err
+}
+
+// MarkEntryReverted records that actor undid one logged fix. Its snapshot is
+// dropped so a later revert of the rule doesn't restore it again.
+func MarkEntryReverted(id int64, actor string) error {
+	_, err := DB.Exec(`UPDATE rollback_log SET reverted_at = ?, reverted_by = ?, snapshot = '' WHERE id = ? AND reverted_at IS NULL`,
+		time.Now(), actor, id)
+	return err
+}
+
+// AppliedFixes returns the fixes on machine still in effect (not reverted,
+// not failed), oldest first.
+func AppliedFixes(machine string) ([]HistoryEntry, error) {
+	rows, err := DB.Query(`SELECT `+historyColumns+` FROM rollback_log
+        WHERE reverted_at IS NULL AND (error IS NULL OR error = '') AND `+onMachine+` ORDER BY id`, machine)
+	if err != nil {
+		return nil, err
+	}
+	return scanHistory(rows)
+}
+
+// LaterFixes returns the fixes on machine made after entry id that have not
+// been reverted, oldest first.
+func LaterFixes(id int64, machine string) ([]HistoryEntry, error) {
+	rows, err := DB.Query(`SELECT `+historyColumns+` FROM rollback_log WHERE id > ? AND reverted_at IS NULL AND `+onMachine+` ORDER BY id`, id, machine)
+	if err != nil {
+		return nil, err
+	}
+	return scanHistory(rows)
+}
+
+// FailedFixes counts fixes on machine that failed and were not reverted since.
+func FailedFixes(machine string) (int, error) {
+	var n int
+	err := DB.QueryRow(`SELECT COUNT(*) FROM rollback_log WHERE reverted_at IS NULL AND error IS NOT NULL AND error != '' AND `+onMachine, machine).Scan(&n)
+	return n, err
+}
+
+// TransactionStart returns the first rollback_log row of a transaction.
+func TransactionStart(txID string) (int64, error) {
+	var id sql.NullInt64
+	if err := DB.QueryRow(`SELECT MIN(id) FROM rollback_log WHERE tx_id = ?`, txID).Scan(&id); err != nil {
+		return 0, err
+	}
+	if !id.Valid {
+		return 0, fmt.Errorf("unknown transaction %s", txID)
+	}
+	return id.Int64, nil
+}
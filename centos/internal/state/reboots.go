@@ -0,0 +1,62 @@
+package state
+
+import (
+	"time"
+)
+
+func initRebootTable() error {
+	query := `
+    CREATE TABLE IF NOT EXISTS pending_reboots (
+        machine TEXT NOT NULL,
+        rule_id TEXT NOT NULL,
+        boot_id TEXT NOT NULL,
+        changed_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (machine, rule_id)
+    );`
+	_, err := DB.Exec(query)
+	return err
+}
+
+// MarkPendingReboot records that a rule's change on machine only takes
+// effect after the boot bootID.
+func MarkPendingReboot(machine, ruleID, bootID string) error {
+	_, err := DB.Exec(`INSERT OR REPLACE INTO pending_reboots (machine, rule_id, boot_id, changed_at) VALUES (?, ?, ?, ?)`,
+		machine, ruleID, bootID, time.Now())
+	return err
+}
+
+// ClearPendingReboot forgets a rule's pending reboot on machine, e.g. when
+// its change is undone before the reboot. It reports whether one was pending.
+func ClearPendingReboot(machine, ruleID string) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM pending_reboots WHERE machine = ? AND rule_id = ?`, machine, ruleID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// PendingReboots returns the rules on machine still waiting for a reboot
+// (with when they were changed). Entries from an earlier boot than bootID
+// are cleared: that reboot has happened.
+func PendingReboots(machine, bootID string) (map[string]time.Time, error) {
+	if _, err := DB.Exec(`DELETE FROM pending_reboots WHERE machine = ? AND boot_id != ?`, machine, bootID); err != nil {
+		return nil, err
+	}
+	rows, err := DB.Query(`SELECT rule_id, changed_at FROM pending_reboots WHERE machine = ?`, machine)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := make(map[string]time.Time)
+	for rows.Next() {
+		var ruleID string
+		var changed time.Time
+		if err := rows.Scan(&ruleID, &changed); err != nil {
+			return nil, err
+		}
+		pending[ruleID] = changed
+	}
+	return pending, rows.Err()
+}
@@ -0,0 +1,214 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Scan is one audit run of this machine, as stored for the v1 API.
+type Scan struct {
+	ID         int64     `json:"id"`
+	Host       string    `json:"host"`
+	Profile    string    `json:"profile"`
+	Actor      string    `json:"actor,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Pass       int       `json:"pass"`
+	Fail       int       `json:"fail"`
+	Waived     int       `json:"waived"`
+	Total      int       `json:"total"`      // scored rules: pass + fail
+	Compliance int       `json:"compliance"` // pass percentage, as in the PDF report
+}
+
+// ScanResult is a single rule outcome within a Scan.
+type ScanResult struct {
+	RuleID   string  `json:"id"`
+	Name     string  `json:"name"`
+	Severity string  `json:"severity"`
+	Status   string  `json:"status"`
+	Actual   string  `json:"actual"`
+	Expected string  `json:"expected"`
+	Waiver   *Waiver `json:"waiver,omitempty"` // as it was at scan time
+}
+
+// ScanResultFilter selects results of one scan; zero fields match everything.
+type ScanResultFilter struct {
+	ScanID   int64
+	Status   string
+	Severity string
+	Limit    int
+	Offset   int
+}
+
+func initScanTables() error {
+	query := `
+    CREATE TABLE IF NOT EXISTS scans (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        host TEXT,
+        profile TEXT,
+        actor TEXT,
+        started_at DATETIME,
+        finished_at DATETIME,
+        pass INTEGER,
+        fail INTEGER,
+        waived INTEGER,
+        total INTEGER
+    );
+    CREATE TABLE IF NOT EXISTS scan_results (
+        scan_id INTEGER REFERENCES scans(id) ON DELETE CASCADE,
+        rule_id TEXT,
+        rule_name TEXT,
+        severity TEXT,
+        status TEXT,
+        actual TEXT,
+        expected TEXT,
+        waiver TEXT
+    );
+    CREATE INDEX IF NOT EXISTS idx_scan_results_scan ON scan_results(scan_id);`
+	_, err := DB.Exec(query)
+	return err
+}
+
+// SaveScan stores a scan and its results, returning the new scan ID.
+func SaveScan(scan Scan, results []ScanResult) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO scans (host, profile, actor, started_at, finished_at, pass, fail, waived, total) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		scan.Host, scan.Profile, scan.Actor, scan.StartedAt, scan.FinishedAt, scan.Pass, scan.Fail, scan.Waived, scan.Total)
+	if err != nil {
+		return 0, err
+	}
+	scanID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO scan_results (scan_id, rule_id, rule_name, severity, status, actual, expected, waiver) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	for _, r := range results {
+		var waiver sql.NullString
+		if r.Waiver != nil {
+			data, err := json.Marshal(r.Waiver)
+			if err != nil {
+				return 0, err
+			}
+			waiver = sql.NullString{String: string(data), Valid: true}
+		}
+		if _, err := stmt.Exec(scanID, r.RuleID, r.Name, r.Severity, r.Status, r.Actual, r.Expected, waiver); err != nil {
+			return 0, err
+		}
+	}
+	return scanID, tx.Commit()
+}
+
+const scanColumns = `id, host, profile, actor, started_at, finished_at, pass, fail, waived, total`
+
+// ListScans returns one page of scans, newest first, and how many there are.
+func ListScans(limit, offset int) ([]Scan, int, error) {
+	var total int
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM scans`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 {
+		limit = -1 // no limit
+	}
+	scans, err := queryScans(`SELECT `+scanColumns+` FROM scans ORDER BY id DESC LIMIT ? OFFSET ?`, limit, offset)
+	return scans, total, err
+}
+
+// GetScan returns one scan, or nil if there is none.
+func GetScan(id int64) (*Scan, error) {
+	scans, err := queryScans(`SELECT `+scanColumns+` FROM scans WHERE id = ?`, id)
+	if err != nil || len(scans) == 0 {
+		return nil, err
+	}
+	return &scans[0], nil
+}
+
+// LatestScan returns the most recent scan, or nil if there is none.
+func LatestScan() (*Scan, error) {
+	scans, err := queryScans(`SELECT ` + scanColumns + ` FROM scans ORDER BY id DESC LIMIT 1`)
+	if err != nil || len(scans) == 0 {
+		return nil, err
+	}
+	return &scans[0], nil
+}
+
+// ListScanResults returns one page of a scan's results, in rule order, and
+// how many match the filter in total. Status and severity match ignoring case.
+func ListScanResults(f ScanResultFilter) ([]ScanResult, int, error) {
+	conds := []string{"scan_id = ?"}
+	args := []interface{}{f.ScanID}
+	if f.Status != "" {
+		conds = append(conds, "UPPER(status) = UPPER(?)")
+		args = append(args, f.Status)
+	}
+	if f.Severity != "" {
+		conds = append(conds, "LOWER(severity) = LOWER(?)")
+		args = append(args, f.Severity)
+	}
+	where := " WHERE " + strings.Join(conds, " AND ")
+
+	var total int
+	if err := DB.QueryRow(`SELECT COUNT(*) FROM scan_results`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if f.Limit <= 0 {
+		f.Limit = -1
+	}
+	rows, err := DB.Query(`SELECT rule_id, rule_name, severity, status, actual, expected, waiver FROM scan_results`+where+` ORDER BY rule_id LIMIT ? OFFSET ?`,
+		append(args, f.Limit, f.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var results []ScanResult
+	for rows.Next() {
+		var r ScanResult
+		var waiver sql.NullString
+		if err := rows.Scan(&r.RuleID, &r.Name, &r.Severity, &r.Status, &r.Actual, &r.Expected, &waiver); err != nil {
+			return nil, 0, err
+		}
+		if waiver.String != "" {
+			r.Waiver = &Waiver{}
+			if err := json.Unmarshal([]byte(waiver.String), r.Waiver); err != nil {
+				return nil, 0, err
+			}
+		}
+		results = append(results, r)
+	}
+	return results, total, rows.Err()
+}
+
+func queryScans(query string, args ...interface{}) ([]Scan, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scans []Scan
+	for rows.Next() {
+		var s Scan
+		var actor sql.NullString
+		if err := rows.Scan(&s.ID, &s.Host, &s.Profile, &actor, &s.StartedAt, &s.FinishedAt, &s.Pass, &s.Fail, &s.Waived, &s.Total); err != nil {
+			return nil, err
+		}
+		s.Actor = actor.String
+		if s.Total > 0 {
+			s.Compliance = int((float64(s.Pass) / float64(s.Total) * 100) + 0.5)
+		}
+		scans = append(scans, s)
+	}
+	return scans, rows.Err()
+}
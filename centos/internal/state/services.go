@@ -0,0 +1,28 @@
+package state
+
+import (
+	"time"
+)
+
+func initServiceTable() error {
+	query := `
+    CREATE TABLE IF NOT EXISTS service_actions (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        service TEXT,
+        action TEXT NOT NULL,
+        rules TEXT,
+        status TEXT NOT NULL,
+        output TEXT,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    );`
+	_, err := DB.Exec(query)
+	return err
+}
+
+// LogServiceAction records a service reload/restart run after fixes; rules is
+// the comma-separated list of rules that needed it.
+func LogServiceAction(service, action, rules, status, output string) error {
+	_, err := DB.Exec(`INSERT INTO service_actions (service, action, rules, status, output, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		service, action, rules, status, output, time.Now())
+	return err
+}
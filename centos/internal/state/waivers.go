@@ -0,0 +1,110 @@
+package state
+
+import (
+	"time"
+)
+
+// Waiver scopes
+const (
+	WaiverScopeHost    = "host"
+	WaiverScopeProfile = "profile"
+)
+
+// Waiver is an accepted risk: a failing rule that is not counted against the
+// score for the given host or profile until it expires.
+type Waiver struct {
+	ID            int64     `json:"id"`
+	RuleID        string    `json:"rule_id"`
+	Scope         string    `json:"scope"`  // "host" or "profile"
+	Target        string    `json:"target"` // hostname or profile name
+	Justification string    `json:"justification"`
+	Approver      string    `json:"approver"`
+	CreatedAt     time.Time `json:"created_at"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the waiver no longer applies at t.
+func (w Waiver) Expired(t time.Time) bool {
+	return !t.Before(w.ExpiresAt)
+}
+
+func initWaiverTable() error {
+	query := `
+    CREATE TABLE IF NOT EXISTS waivers (
+        id INTEGER PRIMARY KEY AUTOINCREMENT,
+        rule_id TEXT NOT NULL,
+        scope TEXT NOT NULL,
+        target TEXT NOT NULL,
+        justification TEXT NOT NULL,
+        approver TEXT NOT NULL,
+        created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+        expires_at DATETIME NOT NULL
+    );
+    CREATE INDEX IF NOT EXISTS idx_waivers_rule ON waivers(rule_id);`
+	_, err := DB.Exec(query)
+	return err
+}
+
+// AddWaiver stores a waiver and returns its ID.
+func AddWaiver(w Waiver) (int64, error) {
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+	res, err := DB.Exec(`INSERT INTO waivers (rule_id, scope, target, justification, approver, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		w.RuleID, w.Scope, w.Target, w.Justification, w.Approver, w.CreatedAt, w.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// DeleteWaiver removes a waiver. It reports false if no such waiver exists.
+func DeleteWaiver(id int64) (bool, error) {
+	res, err := DB.Exec(`DELETE FROM waivers WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ListWaivers returns every waiver, newest first, including expired ones.
+func ListWaivers() ([]Waiver, error) {
+	return queryWaivers(`SELECT id, rule_id, scope, target, justification, approver, created_at, expires_at FROM waivers ORDER BY id DESC`)
+}
+
+// WaiversFor returns the waivers (active or expired) that cover host or
+// profile, keyed by rule ID. When several match, the one expiring last wins.
+func WaiversFor(host, profile string) (map[string]Waiver, error) {
+	list, err := queryWaivers(`SELECT id, rule_id, scope, target, justification, approver, created_at, expires_at FROM waivers
+        WHERE (scope = ? AND target = ?) OR (scope = ? AND target = ?)`,
+		WaiverScopeHost, host, WaiverScopeProfile, profile)
+	if err != nil {
+		return nil, err
+	}
+	byRule := make(map[string]Waiver)
+	for _, w := range list {
+		if cur, ok := byRule[w.RuleID]; !ok || w.ExpiresAt.After(cur.ExpiresAt) {
+			byRule[w.RuleID] = w
+		}
+	}
+	return byRule, nil
+}
+
+func queryWaivers(query string, args ...interface{}) ([]Waiver, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Waiver
+	for rows.Next() {
+		var w Waiver
+		if err := rows.Scan(&w.ID, &w.RuleID, &w.Scope, &w.Target, &w.Justification, &w.Approver, &w.CreatedAt, &w.ExpiresAt); err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,143 @@
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Backend is a registry. Keys are full paths such as
+// `HKLM\SOFTWARE\Policies\Microsoft\Windows\System`; the empty value name is
+// the key's default value.
+type Backend interface {
+	// GetValue returns ErrNotExist when the key or value is missing
+	GetValue(key, name string) (Value, error)
+	// CreateKey and SetValue create the key (and its parents) when needed
+	CreateKey(key string) error
+	SetValue(key, name string, v Value) error
+	// DeleteValue and DeleteKey succeed when there is nothing to delete.
+	// DeleteKey removes the whole subtree.
+	DeleteValue(key, name string) error
+	DeleteKey(key string) error
+
+	// ListValues and ListSubkeys return ErrNotExist for a missing key
+	ListValues(key string) (map[string]Value, error)
+	ListSubkeys(key string) ([]string, error)
+}
+
+// roots maps the accepted root names to their short form.
+var roots = map[string]string{
+	"HKLM": "HKLM", "HKEY_LOCAL_MACHINE": "HKLM",
+	"HKCU": "HKCU", "HKEY_CURRENT_USER": "HKCU",
+	"HKU": "HKU", "HKEY_USERS": "HKU",
+	"HKCR": "HKCR", "HKEY_CLASSES_ROOT": "HKCR",
+	"HKCC": "HKCC", "HKEY_CURRENT_CONFIG": "HKCC",
+}
+
+// SplitKey splits a key path into its short root name (HKLM, HKCU, ...) and
+// the path below it.
+func SplitKey(key string) (root, path string, err error) {
+	key = strings.Trim(strings.ReplaceAll(key, "/", `\`), `\`)
+	head, rest, _ := strings.Cut(key, `\`)
+	root, ok := roots[strings.ToUpper(head)]
+	if !ok {
+		return "", "", fmt.Errorf("unsupported root key: %s", key)
+	}
+	return root, strings.Trim(rest, `\`), nil
+}
+
+// Snapshot is the exact state of one value, or of a whole key when WholeKey
+// is set, taken before a fix so the rollback can put it back.
+type Snapshot struct {
+	Key      string `json:"key"`
+	Name     string `json:"name,omitempty"`
+	WholeKey bool   `json:"whole_key,omitempty"`
+
+	Value *Value `json:"value,omitempty"` // nil: the value did not exist
+	Tree  *Tree  `json:"tree,omitempty"`  // nil: the key did not exist
+}
+
+// Tree is a key's values and subkeys.
+type Tree struct {
+	Values  map[string]Value `json:"values,omitempty"`
+	Subkeys map[string]*Tree `json:"subkeys,omitempty"`
+}
+
+// Capture snapshots one value, or the whole key when wholeKey is set.
+func Capture(b Backend, key, name string, wholeKey bool) (*Snapshot, error) {
+	s := &Snapshot{Key: key, Name: name, WholeKey: wholeKey}
+	var err error
+	if wholeKey {
+		s.Tree, err = readTree(b, key, 0)
+	} else {
+		var v Value
+		if v, err = b.GetValue(key, name); err == nil {
+			s.Value = &v
+		}
+	}
+	if errors.Is(err, ErrNotExist) {
+		err = nil
+	}
+	return s, err
+}
+
+func readTree(b Backend, key string, depth int) (*Tree, error) {
+	if depth > 32 {
+		return nil, fmt.Errorf("registry key %s is nested too deeply", key)
+	}
+	values, err := b.ListValues(key)
+	if err != nil {
+		return nil, err
+	}
+	subkeys, err := b.ListSubkeys(key)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tree{Values: values}
+	for _, sub := range subkeys {
+		child, err := readTree(b, key+`\`+sub, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if t.Subkeys == nil {
+			t.Subkeys = make(map[string]*Tree)
+		}
+		t.Subkeys[sub] = child
+	}
+	return t, nil
+}
+
+// Restore puts the snapshotted state back: values that did not exist are
+// deleted, and a whole key is replaced by its snapshotted tree.
+func Restore(b Backend, s *Snapshot) error {
+	if !s.WholeKey {
+		if s.Value == nil {
+			return b.DeleteValue(s.Key, s.Name)
+		}
+		return b.SetValue(s.Key, s.Name, *s.Value)
+	}
+	if err := b.DeleteKey(s.Key); err != nil {
+		return err
+	}
+	if s.Tree == nil {
+		return nil
+	}
+	return writeTree(b, s.Key, s.Tree)
+}
+
+func writeTree(b Backend, key string, t *Tree) error {
+	if err := b.CreateKey(key); err != nil {
+		return err
+	}
+	for name, v := range t.Values {
+		if err := b.SetValue(key, name, v); err != nil {
+			return err
+		}
+	}
+	for name, child := range t.Subkeys {
+		if err := writeTree(b, key+`\`+name, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package winreg
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const policyKey = `HKLM\SOFTWARE\Policies\Microsoft\Windows\System`
+
+func TestMemoryDeleteMissing(t *testing.T) {
+	m := NewMemory()
+	if err := m.SetValue(policyKey, "EnableSmartScreen", Value{Type: DWORD, Integer: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		del  func() error
+	}{
+		{"value of a missing key", func() error { return m.DeleteValue(`HKLM\SOFTWARE\Missing`, "x") }},
+		{"missing value", func() error { return m.DeleteValue(policyKey, "Missing") }},
+		{"missing key", func() error { return m.DeleteKey(`HKLM\SOFTWARE\Missing`) }},
+		{"missing subkey", func() error { return m.DeleteKey(policyKey + `\Missing`) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.del(); err != nil {
+				t.Errorf("delete: %v, want nothing to do", err)
+			}
+			if v, err := m.GetValue(policyKey, "EnableSmartScreen"); err != nil || v.Integer != 1 {
+				t.Errorf("an unrelated value changed: %+v, %v", v, err)
+			}
+		})
+	}
+
+	if err := m.DeleteValue(`HKXX\SOFTWARE`, "x"); err == nil {
+		t.Error("DeleteValue accepted an unknown root key")
+	}
+}
+
+func TestMemoryCaseInsensitive(t *testing.T) {
+	m := NewMemory()
+	if err := m.SetValue(`HKEY_LOCAL_MACHINE\Software\Test`, "Name", Value{Type: SZ, String: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := m.GetValue(`hklm/SOFTWARE/test`, "NAME"); err != nil || v.String != "x" {
+		t.Errorf("GetValue with other case: %+v, %v", v, err)
+	}
+	values, err := m.ListValues(`HKLM\SOFTWARE\TEST`)
+	if err != nil || !reflect.DeepEqual(values, map[string]Value{"Name": {Type: SZ, String: "x"}}) {
+		t.Errorf("ListValues: %v, %v; want the name as created", values, err)
+	}
+	if subkeys, err := m.ListSubkeys(`HKLM\software`); err != nil || !reflect.DeepEqual(subkeys, []string{"Test"}) {
+		t.Errorf("ListSubkeys: %v, %v", subkeys, err)
+	}
+}
+
+// A whole-key snapshot, stored as JSON and restored after the key was
+// changed and deleted, brings back every value (type included) and subkey,
+// and nothing the fix added.
+func TestCaptureRestoreWholeKey(t *testing.T) {
+	m := NewMemory()
+	before := map[string]map[string]Value{
+		policyKey: {
+			"EnableSmartScreen": {Type: DWORD, Integer: 1},
+			"":                  {Type: SZ, String: "default"},
+		},
+		policyKey + `\Audit`: {
+			"Paths": {Type: MultiSZ, Strings: []string{`C:\a`, `C:\b`}},
+			"Blob":  {Type: Binary, Bytes: []byte{0xde, 0xad}},
+		},
+		policyKey + `\Audit\Deep`: {
+			"Limit": {Type: QWORD, Integer: 1 << 40},
+		},
+		policyKey + `\Empty`: nil,
+	}
+	for key, values := range before {
+		if err := m.CreateKey(key); err != nil {
+			t.Fatal(err)
+		}
+		for name, v := range values {
+			if err := m.SetValue(key, name, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	snap, err := Capture(m, policyKey, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The fix: change a value, add a subkey, then delete the whole key
+	m.SetValue(policyKey, "EnableSmartScreen", Value{Type: SZ, String: "0"})
+	m.SetValue(policyKey+`\Added`, "x", Value{Type: DWORD})
+	if err := m.DeleteKey(policyKey); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.ListValues(policyKey + `\Audit`); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("DeleteKey left the subtree: %v", err)
+	}
+
+	var stored Snapshot
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if err := Restore(m, &stored); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	for key, values := range before {
+		got, err := m.ListValues(key)
+		if err != nil {
+			t.Errorf("%s: %v", key, err)
+			continue
+		}
+		if values == nil {
+			values = map[string]Value{}
+		}
+		if !reflect.DeepEqual(got, values) {
+			t.Errorf("%s holds %+v, want %+v", key, got, values)
+		}
+	}
+	if subkeys, _ := m.ListSubkeys(policyKey); !reflect.DeepEqual(subkeys, []string{"Audit", "Empty"}) {
+		t.Errorf("subkeys after the restore: %v", subkeys)
+	}
+}
+
+// Snapshots of things that didn't exist restore by deleting them.
+func TestCaptureRestoreMissing(t *testing.T) {
+	m := NewMemory()
+	for _, wholeKey := range []bool{false, true} {
+		snap, err := Capture(m, policyKey, "EnableSmartScreen", wholeKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snap.Value != nil || snap.Tree != nil {
+			t.Fatalf("snapshot of nothing holds %+v", snap)
+		}
+		if err := m.SetValue(policyKey, "EnableSmartScreen", Value{Type: DWORD, Integer: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if err := Restore(m, snap); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if _, err := m.GetValue(policyKey, "EnableSmartScreen"); !errors.Is(err, ErrNotExist) {
+			t.Errorf("whole key %v: the value is still there (%v)", wholeKey, err)
+		}
+		m.DeleteKey(policyKey)
+	}
+}
@@ -0,0 +1,169 @@
+package winreg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Memory is an in-memory registry for tests and the fake platform. Like the
+// real one it is case-insensitive but keeps the case names were created with.
+type Memory struct {
+	mu   sync.Mutex
+	keys map[string]*memKey // keyed by lower-cased canonical path
+}
+
+type memKey struct {
+	path   string
+	values map[string]memValue // keyed by lower-cased name
+}
+
+type memValue struct {
+	name  string
+	value Value
+}
+
+func NewMemory() *Memory {
+	return &Memory{keys: make(map[string]*memKey)}
+}
+
+// canonical validates key and returns it as ROOT\path.
+func canonical(key string) (string, error) {
+	root, path, err := SplitKey(key)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return root, nil
+	}
+	return root + `\` + path, nil
+}
+
+func (m *Memory) lookup(key string) (*memKey, error) {
+	path, err := canonical(key)
+	if err != nil {
+		return nil, err
+	}
+	k, ok := m.keys[strings.ToLower(path)]
+	if !ok {
+		return nil, ErrNotExist
+	}
+	return k, nil
+}
+
+// create makes the key and all of its parents.
+func (m *Memory) create(key string) (*memKey, error) {
+	path, err := canonical(key)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(path, `\`)
+	var k *memKey
+	for i := range parts {
+		p := strings.Join(parts[:i+1], `\`)
+		if k = m.keys[strings.ToLower(p)]; k == nil {
+			k = &memKey{path: p, values: make(map[string]memValue)}
+			m.keys[strings.ToLower(p)] = k
+		}
+	}
+	return k, nil
+}
+
+func (m *Memory) CreateKey(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := m.create(key)
+	return err
+}
+
+func (m *Memory) GetValue(key, name string) (Value, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, err := m.lookup(key)
+	if err != nil {
+		return Value{}, err
+	}
+	v, ok := k.values[strings.ToLower(name)]
+	if !ok {
+		return Value{}, ErrNotExist
+	}
+	return v.value, nil
+}
+
+func (m *Memory) SetValue(key, name string, v Value) error {
+	if !v.Type.storable() {
+		return fmt.Errorf("unsupported registry type %q", v.Type)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, err := m.create(key)
+	if err != nil {
+		return err
+	}
+	v.Strings = append([]string(nil), v.Strings...)
+	v.Bytes = append([]byte(nil), v.Bytes...)
+	k.values[strings.ToLower(name)] = memValue{name: name, value: v}
+	return nil
+}
+
+func (m *Memory) DeleteValue(key, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, err := m.lookup(key)
+	if err == ErrNotExist {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	delete(k.values, strings.ToLower(name))
+	return nil
+}
+
+func (m *Memory) DeleteKey(key string) error {
+	path, err := canonical(key)
+	if err != nil {
+		return err
+	}
+	lower := strings.ToLower(path)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for p := range m.keys {
+		if p == lower || strings.HasPrefix(p, lower+`\`) {
+			delete(m.keys, p)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListValues(key string) (map[string]Value, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, err := m.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]Value, len(k.values))
+	for _, v := range k.values {
+		values[v.name] = v.value
+	}
+	return values, nil
+}
+
+func (m *Memory) ListSubkeys(key string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, err := m.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.ToLower(k.path) + `\`
+	var names []string
+	for p, sub := range m.keys {
+		if strings.HasPrefix(p, prefix) && !strings.Contains(p[len(prefix):], `\`) {
+			names = append(names, sub.path[len(prefix):])
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
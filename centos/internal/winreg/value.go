@@ -0,0 +1,284 @@
+// Package winreg is the registry layer used by registry rules: typed values,
+// the Backend interface (the real registry on Windows, an in-memory one
+// everywhere) and snapshots of values or keys for exact rollback.
+package winreg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ValueType is a registry value type, named as in regedit.
+type ValueType string
+
+const (
+	SZ       ValueType = "REG_SZ"
+	ExpandSZ ValueType = "REG_EXPAND_SZ"
+	MultiSZ  ValueType = "REG_MULTI_SZ"
+	DWORD    ValueType = "REG_DWORD"
+	QWORD    ValueType = "REG_QWORD"
+	Binary   ValueType = "REG_BINARY"
+
+	// Absent is only used in expected values: the check passes when the
+	// value (or, with no value name, the key) does not exist.
+	Absent ValueType = "ABSENT"
+)
+
+// storable reports whether values of type t can be written.
+func (t ValueType) storable() bool {
+	switch t {
+	case SZ, ExpandSZ, MultiSZ, DWORD, QWORD, Binary:
+		return true
+	}
+	return false
+}
+
+// ErrNotExist is returned for a missing key or value.
+var ErrNotExist = errors.New("registry key or value does not exist")
+
+// Value is one typed registry value. Only the field matching Type is used.
+type Value struct {
+	Type    ValueType
+	String  string   // SZ, EXPAND_SZ
+	Strings []string // MULTI_SZ
+	Integer uint64   // DWORD, QWORD
+	Bytes   []byte   // BINARY
+}
+
+// Equal reports whether v and o hold the same data. DWORD and QWORD compare
+// as numbers, SZ and EXPAND_SZ as strings; other types must match exactly.
+func (v Value) Equal(o Value) bool {
+	switch {
+	case v.isInteger() && o.isInteger():
+		return v.Integer == o.Integer
+	case v.isString() && o.isString():
+		return v.String == o.String
+	case v.Type != o.Type:
+		return false
+	case v.Type == MultiSZ:
+		if len(v.Strings) != len(o.Strings) {
+			return false
+		}
+		for i := range v.Strings {
+			if v.Strings[i] != o.Strings[i] {
+				return false
+			}
+		}
+		return true
+	case v.Type == Binary:
+		return bytes.Equal(v.Bytes, o.Bytes)
+	}
+	return v.Type == o.Type
+}
+
+func (v Value) isInteger() bool { return v.Type == DWORD || v.Type == QWORD }
+func (v Value) isString() bool  { return v.Type == SZ || v.Type == ExpandSZ }
+
+// Display formats the value for reports, e.g. `REG_DWORD 4` or `REG_SZ "x"`.
+func (v Value) Display() string {
+	switch v.Type {
+	case SZ, ExpandSZ:
+		return fmt.Sprintf("%s %q", v.Type, v.String)
+	case MultiSZ:
+		data, _ := json.Marshal(v.Strings)
+		return fmt.Sprintf("%s %s", v.Type, data)
+	case DWORD, QWORD:
+		return fmt.Sprintf("%s %d", v.Type, v.Integer)
+	case Binary:
+		return fmt.Sprintf("%s %s", v.Type, hex.EncodeToString(v.Bytes))
+	}
+	return string(v.Type)
+}
+
+// Text is the value's data as plain text, for typed comparisons: integers in
+// decimal, REG_MULTI_SZ joined with commas, REG_BINARY in hex.
+func (v Value) Text() string {
+	switch v.Type {
+	case MultiSZ:
+		return strings.Join(v.Strings, ",")
+	case DWORD, QWORD:
+		return strconv.FormatUint(v.Integer, 10)
+	case Binary:
+		return hex.EncodeToString(v.Bytes)
+	}
+	return v.String
+}
+
+// wireValue is the JSON form of a Value, used in policies and snapshots:
+// {"type": "REG_DWORD", "data": 4}, {"type": "REG_BINARY", "data": "01ff"},
+// {"type": "REG_MULTI_SZ", "data": ["a", "b"]}.
+type wireValue struct {
+	Type ValueType   `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	w := wireValue{Type: v.Type}
+	switch v.Type {
+	case SZ, ExpandSZ:
+		w.Data = v.String
+	case MultiSZ:
+		w.Data = v.Strings
+	case DWORD, QWORD:
+		w.Data = v.Integer
+	case Binary:
+		w.Data = hex.EncodeToString(v.Bytes)
+	}
+	return json.Marshal(w)
+}
+
+func (v *Value) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var w wireValue
+	if err := dec.Decode(&w); err != nil {
+		return err
+	}
+	parsed, err := ParseValue(w.Data, w.Type)
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// ParseValue converts a value from a policy (a JSON string, number, array or
+// {"type", "data"} object) to a typed Value. With no type given, numbers
+// become DWORDs (QWORDs when they don't fit), strings REG_SZ and arrays
+// REG_MULTI_SZ.
+func ParseValue(data interface{}, typ ValueType) (Value, error) {
+	typ = ValueType(strings.ToUpper(string(typ)))
+	if obj, ok := data.(map[string]interface{}); ok {
+		t, _ := obj["type"].(string)
+		if t == "" {
+			return Value{}, fmt.Errorf("typed registry value needs a \"type\"")
+		}
+		return ParseValue(obj["data"], ValueType(t))
+	}
+
+	switch typ {
+	case "":
+		switch d := data.(type) {
+		case nil:
+			return Value{}, fmt.Errorf("no registry value given")
+		case string:
+			return Value{Type: SZ, String: d}, nil
+		case []interface{}, []string:
+			return ParseValue(d, MultiSZ)
+		}
+		n, err := toUint(data, math.MaxUint64)
+		if err != nil {
+			return Value{}, err
+		}
+		if n > math.MaxUint32 {
+			return Value{Type: QWORD, Integer: n}, nil
+		}
+		return Value{Type: DWORD, Integer: n}, nil
+
+	case SZ, ExpandSZ:
+		if data == nil {
+			return Value{Type: typ}, nil
+		}
+		s, ok := data.(string)
+		if !ok {
+			s = fmt.Sprintf("%v", data)
+		}
+		return Value{Type: typ, String: s}, nil
+
+	case MultiSZ:
+		switch d := data.(type) {
+		case nil:
+			return Value{Type: MultiSZ, Strings: []string{}}, nil
+		case []string:
+			return Value{Type: MultiSZ, Strings: append([]string{}, d...)}, nil
+		case []interface{}:
+			v := Value{Type: MultiSZ, Strings: make([]string, 0, len(d))}
+			for _, item := range d {
+				s, ok := item.(string)
+				if !ok {
+					return Value{}, fmt.Errorf("REG_MULTI_SZ entries must be strings, got %v", item)
+				}
+				v.Strings = append(v.Strings, s)
+			}
+			return v, nil
+		}
+		return Value{}, fmt.Errorf("REG_MULTI_SZ needs an array of strings")
+
+	case DWORD:
+		n, err := toUint(data, math.MaxUint32)
+		return Value{Type: DWORD, Integer: n}, err
+
+	case QWORD:
+		n, err := toUint(data, math.MaxUint64)
+		return Value{Type: QWORD, Integer: n}, err
+
+	case Binary:
+		s, ok := data.(string)
+		if !ok && data != nil {
+			return Value{}, fmt.Errorf("REG_BINARY needs a hex string")
+		}
+		b, err := hex.DecodeString(strings.NewReplacer(" ", "", ",", "", "0x", "").Replace(s))
+		if err != nil {
+			return Value{}, fmt.Errorf("REG_BINARY: %v", err)
+		}
+		return Value{Type: Binary, Bytes: b}, nil
+
+	case Absent:
+		return Value{Type: Absent}, nil
+	}
+	return Value{}, fmt.Errorf("unsupported registry type %q", typ)
+}
+
+// toUint reads a JSON number (float64 or json.Number), an int or a decimal
+// or 0x-prefixed string. Negative numbers are stored in two's complement,
+// as reg.exe does for DWORDs.
+func toUint(data interface{}, max uint64) (uint64, error) {
+	var s string
+	switch d := data.(type) {
+	case float64:
+		if d != math.Trunc(d) {
+			return 0, fmt.Errorf("registry integer %v is not whole", d)
+		}
+		s = strconv.FormatFloat(d, 'f', -1, 64)
+	case int:
+		s = strconv.Itoa(d)
+	case int64:
+		s = strconv.FormatInt(d, 10)
+	case uint64:
+		s = strconv.FormatUint(d, 10)
+	case json.Number:
+		s = d.String()
+	case string:
+		s = strings.TrimSpace(d)
+	default:
+		return 0, fmt.Errorf("registry integer expected, got %v", data)
+	}
+
+	if strings.HasPrefix(s, "-") {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("registry integer %q: %v", s, err)
+		}
+		if max == math.MaxUint32 {
+			if n < math.MinInt32 {
+				return 0, fmt.Errorf("registry integer %d does not fit in a DWORD", n)
+			}
+			return uint64(uint32(int32(n))), nil
+		}
+		return uint64(n), nil
+	}
+	n, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("registry integer %q: %v", s, err)
+	}
+	if n > max {
+		return 0, fmt.Errorf("registry integer %d does not fit in a DWORD", n)
+	}
+	return n, nil
+}
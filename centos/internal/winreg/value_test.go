@@ -0,0 +1,127 @@
+package winreg
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		typ  ValueType
+		want Value
+	}{
+		{"string", "Administrator", "", Value{Type: SZ, String: "Administrator"}},
+		{"typed string", 1, SZ, Value{Type: SZ, String: "1"}},
+		{"lower-case type", "x", "reg_sz", Value{Type: SZ, String: "x"}},
+		{"expand string", `%SystemRoot%\system32`, ExpandSZ, Value{Type: ExpandSZ, String: `%SystemRoot%\system32`}},
+		{"array", []interface{}{"a", "b"}, "", Value{Type: MultiSZ, Strings: []string{"a", "b"}}},
+		{"empty multi string", nil, MultiSZ, Value{Type: MultiSZ, Strings: []string{}}},
+		{"number", float64(4), "", Value{Type: DWORD, Integer: 4}},
+		{"json number", json.Number("4"), DWORD, Value{Type: DWORD, Integer: 4}},
+		{"hex string", "0x1F", DWORD, Value{Type: DWORD, Integer: 31}},
+		{"negative dword", -1, DWORD, Value{Type: DWORD, Integer: 0xFFFFFFFF}},
+		{"large number", float64(1 << 40), "", Value{Type: QWORD, Integer: 1 << 40}},
+		{"qword", "18446744073709551615", QWORD, Value{Type: QWORD, Integer: 1<<64 - 1}},
+		{"binary", "01 ff,0x2a", Binary, Value{Type: Binary, Bytes: []byte{0x01, 0xff, 0x2a}}},
+		{"typed object", map[string]interface{}{"type": "REG_QWORD", "data": float64(7)}, "", Value{Type: QWORD, Integer: 7}},
+		{"absent", nil, Absent, Value{Type: Absent}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseValue(tt.data, tt.typ)
+			if err != nil {
+				t.Fatalf("ParseValue(%v, %q): %v", tt.data, tt.typ, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseValue(%v, %q) = %+v, want %+v", tt.data, tt.typ, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValueErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		typ  ValueType
+	}{
+		{"no value", nil, ""},
+		{"dword overflow", float64(1 << 32), DWORD},
+		{"dword underflow", float64(-1 << 40), DWORD},
+		{"fraction", 1.5, DWORD},
+		{"not a number", "four", DWORD},
+		{"multi string with a number", []interface{}{"a", float64(1)}, MultiSZ},
+		{"binary not hex", "zz", Binary},
+		{"binary not a string", float64(1), Binary},
+		{"object without type", map[string]interface{}{"data": "x"}, ""},
+		{"unknown type", "x", "REG_LINK"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := ParseValue(tt.data, tt.typ); err == nil {
+				t.Errorf("ParseValue(%v, %q) = %+v, want an error", tt.data, tt.typ, got)
+			}
+		})
+	}
+}
+
+func TestValueEqual(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Value
+		want bool
+	}{
+		{"same string", Value{Type: SZ, String: "x"}, Value{Type: SZ, String: "x"}, true},
+		{"string case matters", Value{Type: SZ, String: "x"}, Value{Type: SZ, String: "X"}, false},
+		{"string and expand string", Value{Type: SZ, String: "x"}, Value{Type: ExpandSZ, String: "x"}, true},
+		{"dword and qword", Value{Type: DWORD, Integer: 1}, Value{Type: QWORD, Integer: 1}, true},
+		{"different numbers", Value{Type: DWORD, Integer: 1}, Value{Type: DWORD, Integer: 0}, false},
+		{"number and string", Value{Type: DWORD, Integer: 1}, Value{Type: SZ, String: "1"}, false},
+		{"same multi string", Value{Type: MultiSZ, Strings: []string{"a", "b"}}, Value{Type: MultiSZ, Strings: []string{"a", "b"}}, true},
+		{"multi string order", Value{Type: MultiSZ, Strings: []string{"a", "b"}}, Value{Type: MultiSZ, Strings: []string{"b", "a"}}, false},
+		{"multi string length", Value{Type: MultiSZ, Strings: []string{"a"}}, Value{Type: MultiSZ, Strings: []string{"a", ""}}, false},
+		{"empty multi strings", Value{Type: MultiSZ}, Value{Type: MultiSZ, Strings: []string{}}, true},
+		{"same binary", Value{Type: Binary, Bytes: []byte{1, 2}}, Value{Type: Binary, Bytes: []byte{1, 2}}, true},
+		{"different binary", Value{Type: Binary, Bytes: []byte{1, 2}}, Value{Type: Binary, Bytes: []byte{1}}, false},
+		{"binary and string", Value{Type: Binary, Bytes: []byte("x")}, Value{Type: SZ, String: "x"}, false},
+		{"absent", Value{Type: Absent}, Value{Type: Absent}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Equal(tt.b); got != tt.want {
+				t.Errorf("%+v.Equal(%+v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := tt.b.Equal(tt.a); got != tt.want {
+				t.Errorf("%+v.Equal(%+v) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+// Values survive the JSON form snapshots are stored in, type included.
+func TestValueJSON(t *testing.T) {
+	values := []Value{
+		{Type: SZ, String: "x"},
+		{Type: ExpandSZ, String: `%windir%`},
+		{Type: MultiSZ, Strings: []string{"a", "b"}},
+		{Type: DWORD, Integer: 0xFFFFFFFF},
+		{Type: QWORD, Integer: 1<<64 - 1},
+		{Type: Binary, Bytes: []byte{0, 0xff}},
+	}
+	for _, v := range values {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal %+v: %v", v, err)
+		}
+		var got Value
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("unmarshal %s: %v", data, err)
+		}
+		if !reflect.DeepEqual(got, v) {
+			t.Errorf("%s came back as %+v, want %+v", data, got, v)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+//go:build windows
+
+package winreg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Native is the host's registry. Keys are opened in the 64-bit view so a
+// 32-bit build doesn't get redirected to WOW6432Node.
+type Native struct{}
+
+func NewNative() *Native {
+	return &Native{}
+}
+
+var rootKeys = map[string]registry.Key{
+	"HKLM": registry.LOCAL_MACHINE,
+	"HKCU": registry.CURRENT_USER,
+	"HKU":  registry.USERS,
+	"HKCR": registry.CLASSES_ROOT,
+	"HKCC": registry.CURRENT_CONFIG,
+}
+
+func open(key string, access uint32) (registry.Key, error) {
+	root, path, err := SplitKey(key)
+	if err != nil {
+		return 0, err
+	}
+	k, err := registry.OpenKey(rootKeys[root], path, access|registry.WOW64_64KEY)
+	return k, notExist(err)
+}
+
+// notExist maps the "file not found" errors of missing keys and values to ErrNotExist.
+func notExist(err error) error {
+	if errors.Is(err, registry.ErrNotExist) || errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		return ErrNotExist
+	}
+	return err
+}
+
+func (n *Native) GetValue(key, name string) (Value, error) {
+	k, err := open(key, registry.QUERY_VALUE)
+	if err != nil {
+		return Value{}, err
+	}
+	defer k.Close()
+
+	_, valType, err := k.GetValue(name, nil)
+	if err != nil {
+		return Value{}, notExist(err)
+	}
+	v := Value{}
+	switch valType {
+	case registry.SZ:
+		v.Type = SZ
+		v.String, _, err = k.GetStringValue(name)
+	case registry.EXPAND_SZ:
+		v.Type = ExpandSZ
+		v.String, _, err = k.GetStringValue(name)
+	case registry.MULTI_SZ:
+		v.Type = MultiSZ
+		v.Strings, _, err = k.GetStringsValue(name)
+	case registry.DWORD:
+		v.Type = DWORD
+		v.Integer, _, err = k.GetIntegerValue(name)
+	case registry.QWORD:
+		v.Type = QWORD
+		v.Integer, _, err = k.GetIntegerValue(name)
+	case registry.BINARY:
+		v.Type = Binary
+		v.Bytes, _, err = k.GetBinaryValue(name)
+	default:
+		return Value{}, fmt.Errorf("%s\\%s: unsupported registry type %d", key, name, valType)
+	}
+	return v, err
+}
+
+func (n *Native) create(key string) (registry.Key, error) {
+	root, path, err := SplitKey(key)
+	if err != nil {
+		return 0, err
+	}
+	k, _, err := registry.CreateKey(rootKeys[root], path, registry.SET_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create/open key: %v", err)
+	}
+	return k, nil
+}
+
+func (n *Native) CreateKey(key string) error {
+	k, err := n.create(key)
+	if err != nil {
+		return err
+	}
+	return k.Close()
+}
+
+func (n *Native) SetValue(key, name string, v Value) error {
+	k, err := n.create(key)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+
+	switch v.Type {
+	case SZ:
+		return k.SetStringValue(name, v.String)
+	case ExpandSZ:
+		return k.SetExpandStringValue(name, v.String)
+	case MultiSZ:
+		return k.SetStringsValue(name, v.Strings)
+	case DWORD:
+		return k.SetDWordValue(name, uint32(v.Integer))
+	case QWORD:
+		return k.SetQWordValue(name, v.Integer)
+	case Binary:
+		return k.SetBinaryValue(name, v.Bytes)
+	}
+	return fmt.Errorf("unsupported registry type %q", v.Type)
+}
+
+func (n *Native) DeleteValue(key, name string) error {
+	k, err := open(key, registry.SET_VALUE)
+	if err == ErrNotExist {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer k.Close()
+	if err := notExist(k.DeleteValue(name)); err != ErrNotExist {
+		return err
+	}
+	return nil
+}
+
+// DeleteKey removes key and its subkeys (registry.DeleteKey only removes
+// keys without subkeys).
+func (n *Native) DeleteKey(key string) error {
+	root, path, err := SplitKey(key)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return fmt.Errorf("refusing to delete root key %s", root)
+	}
+	subkeys, err := n.ListSubkeys(key)
+	if err == ErrNotExist {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, sub := range subkeys {
+		if err := n.DeleteKey(key + `\` + sub); err != nil {
+			return err
+		}
+	}
+
+	parent, leaf := root, path
+	if i := strings.LastIndex(path, `\`); i >= 0 {
+		parent, leaf = root+`\`+path[:i], path[i+1:]
+	}
+	k, err := open(parent, registry.ENUMERATE_SUB_KEYS|registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if err := notExist(registry.DeleteKey(k, leaf)); err != ErrNotExist {
+		return err
+	}
+	return nil
+}
+
+func (n *Native) ListValues(key string) (map[string]Value, error) {
+	k, err := open(key, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	names, err := k.ReadValueNames(-1)
+	k.Close()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]Value, len(names))
+	for _, name := range names {
+		v, err := n.GetValue(key, name)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+func (n *Native) ListSubkeys(key string) ([]string, error) {
+	k, err := open(key, registry.ENUMERATE_SUB_KEYS|registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+	return k.ReadSubKeyNames(-1)
+}
@@ -0,0 +1,284 @@
+package xccdf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"sih2025/internal/policy"
+)
+
+// Options controls an import.
+type Options struct {
+	Profile  string // XCCDF profile ID to import; empty imports every rule
+	Platform string // policy.Rule.Platform for the generated rules (default "linux")
+}
+
+// Report is the outcome of an import.
+type Report struct {
+	Title     string
+	Profiles  []string // profile IDs found in the benchmark
+	Policy    *policy.Policy
+	Automated int // rules with an automated check
+	Review    int // rules marked NeedsReview
+}
+
+// ImportFiles reads an XCCDF benchmark (or a SCAP data stream containing
+// one) and optional OVAL definition files, and converts the benchmark's rules
+// into a policy. Without OVAL files, definitions embedded in the benchmark
+// file are used.
+func ImportFiles(xccdfPath string, ovalPaths []string, opts Options) (*Report, error) {
+	var bench benchmark
+	found, err := decodeFile(xccdfPath, "Benchmark", func(d *xml.Decoder, start xml.StartElement) error {
+		return d.DecodeElement(&bench, &start)
+	}, true)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s: no XCCDF Benchmark element", xccdfPath)
+	}
+
+	if len(ovalPaths) == 0 {
+		ovalPaths = []string{xccdfPath}
+	}
+	var docs []ovalDocument
+	for _, path := range ovalPaths {
+		_, err := decodeFile(path, "oval_definitions", func(d *xml.Decoder, start xml.StartElement) error {
+			var doc ovalDocument
+			if err := d.DecodeElement(&doc, &start); err != nil {
+				return err
+			}
+			docs = append(docs, doc)
+			return nil
+		}, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return convert(&bench, newOvalIndex(docs), opts)
+}
+
+// decodeFile calls fn for each element named local in the file, or only for
+// the first one if once is set.
+func decodeFile(path, local string, fn func(*xml.Decoder, xml.StartElement) error, once bool) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	d := xml.NewDecoder(f)
+	found := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return found, nil
+		}
+		if err != nil {
+			return found, fmt.Errorf("%s: %v", path, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != local {
+			continue
+		}
+		if err := fn(d, start); err != nil {
+			return found, fmt.Errorf("%s: %v", path, err)
+		}
+		found = true
+		if once {
+			return true, nil
+		}
+	}
+}
+
+func convert(bench *benchmark, ix *ovalIndex, opts Options) (*Report, error) {
+	if opts.Platform == "" {
+		opts.Platform = "linux"
+	}
+	rep := &Report{Title: plainText(bench.Title)}
+	for _, p := range bench.Profiles {
+		rep.Profiles = append(rep.Profiles, p.ID)
+	}
+
+	// A profile overrides the rules' default selection
+	var selected map[string]bool
+	if opts.Profile != "" {
+		for _, p := range bench.Profiles {
+			if p.ID == opts.Profile || shortID(p.ID, "_profile_") == opts.Profile {
+				selected = make(map[string]bool)
+				for _, s := range p.Selects {
+					selected[s.IDRef] = s.Selected
+				}
+			}
+		}
+		if selected == nil {
+			return nil, fmt.Errorf("profile %q not found in benchmark", opts.Profile)
+		}
+	}
+
+	pol := &policy.Policy{Version: bench.Version}
+	var walk func(groups []group, rules []rule, category string)
+	walk = func(groups []group, rules []rule, category string) {
+		for _, r := range rules {
+			if selected != nil {
+				on, listed := selected[r.ID]
+				if (listed && !on) || (!listed && r.Selected == "false") {
+					continue
+				}
+			}
+			pol.Rules = append(pol.Rules, convertRule(r, ix, category, opts.Platform))
+		}
+		for _, g := range groups {
+			cat := category
+			if cat == "" {
+				cat = plainText(g.Title)
+			}
+			walk(g.Groups, g.Rules, cat)
+		}
+	}
+	walk(bench.Groups, bench.Rules, "")
+
+	for _, r := range pol.Rules {
+		if r.Type != "manual" {
+			rep.Automated++
+		}
+		if r.NeedsReview {
+			rep.Review++
+		}
+	}
+	rep.Policy = pol
+	return rep, nil
+}
+
+func convertRule(r rule, ix *ovalIndex, category, platform string) policy.Rule {
+	out := policy.Rule{
+		ID:          shortID(r.ID, "_rule_"),
+		Name:        plainText(r.Title),
+		Description: plainText(r.Description.Inner),
+		Severity:    mapSeverity(r.Severity),
+		Platform:    platform,
+		Type:        "command",
+		Category:    category,
+		Rationale:   plainText(r.Rationale.Inner),
+		References:  references(r),
+		XCCDFRuleID: r.ID,
+	}
+
+	// --- 1. CHECK ---
+	checked, reason := compileCheck(r, ix)
+	if checked == nil {
+		out.Type = "manual"
+		out.NeedsReview = true
+		out.Check = policy.CheckAction{Cmd: "echo", Args: []string{"Manual check: " + reason}}
+	} else {
+		out.Check = policy.CheckAction{Cmd: checked.Cmd, Args: checked.Args, ExpectPattern: checked.Expect}
+	}
+
+	// --- 2. REMEDIATION ---
+	// Prefer a fix derived from the OVAL test, since it comes with a rollback
+	switch {
+	case checked != nil && checked.Fix != nil:
+		out.Remediation = bashAction(strings.Join(checked.Fix, " && "))
+		out.Rollback = bashAction(strings.Join(checked.Undo, " && "))
+	case fixScript(r) != "":
+		out.Remediation = bashAction(fixScript(r))
+		out.Rollback = manualAction("Manual rollback: the benchmark's fix script has no undo")
+		out.NeedsReview = true
+	default:
+		out.Remediation = manualAction("Manual: see the benchmark guidance for " + out.ID)
+		out.Rollback = manualAction("Manual rollback")
+		out.NeedsReview = true
+	}
+	return out
+}
+
+// compileCheck returns the rule's OVAL check, or why it can't be automated.
+func compileCheck(r rule, ix *ovalIndex) (*compiled, string) {
+	for _, c := range r.Checks {
+		if c.System != ovalSystem {
+			continue
+		}
+		for _, ref := range c.Refs {
+			if ref.Name == "" {
+				continue
+			}
+			checked, err := ix.compileDefinition(ref.Name, 0)
+			if err != nil {
+				return nil, err.Error()
+			}
+			return checked, ""
+		}
+	}
+	return nil, "no OVAL check in the benchmark"
+}
+
+// subPattern matches an XCCDF <sub> (value substitution) element.
+var subPattern = regexp.MustCompile(`<(\w+:)?sub\b`)
+
+// fixScript returns the rule's shell fix, if it has one that needs no
+// XCCDF value substitution.
+func fixScript(r rule) string {
+	for _, f := range r.Fixes {
+		if !strings.Contains(f.System, "sh") || subPattern.MatchString(f.Inner) {
+			continue
+		}
+		if script := scriptText(f.Inner); script != "" {
+			return script
+		}
+	}
+	return ""
+}
+
+func references(r rule) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(s string) {
+		if s != "" && !seen[s] {
+			seen[s] = true
+			refs = append(refs, s)
+		}
+	}
+	for _, id := range r.Idents {
+		add(strings.TrimSpace(id.Text))
+	}
+	for _, ref := range r.References {
+		if text := plainText(ref.Inner); text != "" {
+			add(text)
+		} else {
+			add(ref.Href)
+		}
+	}
+	return refs
+}
+
+// shortID strips the benchmark prefix from an XCCDF id, e.g.
+// xccdf_org.ssgproject.content_rule_sshd_disable_root_login -> sshd_disable_root_login.
+func shortID(id, marker string) string {
+	if i := strings.Index(id, marker); i >= 0 {
+		return id[i+len(marker):]
+	}
+	return id
+}
+
+func mapSeverity(s string) string {
+	switch strings.ToLower(s) {
+	case "high":
+		return "High"
+	case "medium":
+		return "Medium"
+	}
+	return "Low"
+}
+
+func bashAction(script string) policy.Action {
+	return policy.Action{Type: "command", Cmd: "bash", Args: []string{"-c", script}}
+}
+
+func manualAction(msg string) policy.Action {
+	return policy.Action{Type: "manual", Cmd: "echo", Args: []string{msg}}
+}
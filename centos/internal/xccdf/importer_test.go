@@ -0,0 +1,172 @@
+package xccdf
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sih2025/internal/policy"
+)
+
+const fixture = "testdata/benchmark.xml"
+
+// importFixture imports the fixture data stream (OVAL embedded) and returns
+// its rules by ID.
+func importFixture(t *testing.T, opts Options) (*Report, map[string]policy.Rule) {
+	t.Helper()
+	rep, err := ImportFiles(fixture, nil, opts)
+	if err != nil {
+		t.Fatalf("ImportFiles: %v", err)
+	}
+	rules := make(map[string]policy.Rule)
+	for _, r := range rep.Policy.Rules {
+		rules[r.ID] = r
+	}
+	return rep, rules
+}
+
+func TestImportBenchmark(t *testing.T) {
+	rep, rules := importFixture(t, Options{})
+	if rep.Title != "Fixture Benchmark" || rep.Policy.Version != "0.1" {
+		t.Errorf("title %q, version %q", rep.Title, rep.Policy.Version)
+	}
+	if !reflect.DeepEqual(rep.Profiles, []string{"xccdf_org.test_profile_server"}) {
+		t.Errorf("profiles %v", rep.Profiles)
+	}
+	if len(rules) != 6 || rep.Automated != 4 || rep.Review != 3 {
+		t.Errorf("%d rules, %d automated, %d to review; want 6, 4, 3", len(rules), rep.Automated, rep.Review)
+	}
+
+	ssh := rules["sshd_disable_root_login"]
+	if ssh.Name != "Disable SSH root login" || ssh.Severity != "High" || ssh.Category != "Services" ||
+		ssh.XCCDFRuleID != "xccdf_org.test_rule_sshd_disable_root_login" ||
+		ssh.Description != "The root user should not log in over SSH." {
+		t.Errorf("rule metadata %+v", ssh)
+	}
+	if !reflect.DeepEqual(ssh.References, []string{"CCE-80901-2", "5.2.10"}) {
+		t.Errorf("references %v", ssh.References)
+	}
+
+	// Rules the OVAL translation can't handle are kept, for manual review
+	owner := rules["file_owner_by_name"]
+	if owner.Type != "manual" || !owner.NeedsReview || !strings.Contains(owner.Check.Args[0], `user_id "root" is not a numeric ID`) {
+		t.Errorf("non-numeric owner: type %s, review %v, check %v", owner.Type, owner.NeedsReview, owner.Check.Args)
+	}
+}
+
+func TestImportProfile(t *testing.T) {
+	for _, profile := range []string{"xccdf_org.test_profile_server", "server"} {
+		_, rules := importFixture(t, Options{Profile: profile})
+		if _, ok := rules["deselected"]; ok || len(rules) != 5 {
+			t.Errorf("profile %s: %d rules, deselected one included: %v", profile, len(rules), ok)
+		}
+	}
+	if _, err := ImportFiles(fixture, nil, Options{Profile: "desktop"}); err == nil {
+		t.Error("an unknown profile was accepted")
+	}
+}
+
+func TestOVALTranslation(t *testing.T) {
+	_, rules := importFixture(t, Options{})
+	const (
+		sysctlFix  = `(f='/etc/sysctl.d/60-sentinelx-net.ipv4.ip_forward.conf'; [ -e "$f" ] || printf '# previous: %s\n' "$(sysctl -n 'net.ipv4.ip_forward')" > "$f"; sed -i '/^[^#]/d' "$f"; printf '%s = %s\n' 'net.ipv4.ip_forward' '0' >> "$f"; sysctl -q -w 'net.ipv4.ip_forward=0')`
+		sysctlUndo = `(f='/etc/sysctl.d/60-sentinelx-net.ipv4.ip_forward.conf'; [ -e "$f" ] || exit 0; old=$(sed -n 's/^# previous: //p' "$f"); rm -f "$f"; [ -z "$old" ] || sysctl -q -w 'net.ipv4.ip_forward'="$old")`
+
+		// The path has a quote, double quotes and a space
+		oddPath = `/etc/it's "odd"/a b.conf`
+		oddArg  = `'/etc/it'\''s "odd"/a b.conf'`
+		oddBak  = `'/var/lib/sentinelx/perms/etc_it'\''s "odd"_a b.conf'`
+	)
+	tests := []struct {
+		id          string
+		check       policy.CheckAction
+		remediation policy.Action
+		rollback    policy.Action
+	}{
+		{
+			id:    "sshd_disable_root_login", // textfilecontent54, fixed by the benchmark's script
+			check: policy.CheckAction{Cmd: "grep", Args: []string{"-Pq", "-e", `^\s*PermitRootLogin\s+no\s*$`, "/etc/ssh/sshd_config"}},
+			remediation: policy.Action{Type: "command", Cmd: "bash",
+				Args: []string{"-c", `sed -i 's/^PermitRootLogin.*/PermitRootLogin no/' /etc/ssh/sshd_config`}},
+			rollback: policy.Action{Type: "manual", Cmd: "echo", Args: []string{"Manual rollback: the benchmark's fix script has no undo"}},
+		},
+		{
+			id:          "sysctl_ip_forward",
+			check:       policy.CheckAction{Cmd: "sysctl", Args: []string{"-n", "net.ipv4.ip_forward"}, ExpectPattern: "^0$"},
+			remediation: policy.Action{Type: "command", Cmd: "bash", Args: []string{"-c", sysctlFix}},
+			rollback:    policy.Action{Type: "command", Cmd: "bash", Args: []string{"-c", sysctlUndo}},
+		},
+		{
+			id: "file_permissions_odd", // numeric owner and group, only bits that must be off
+			check: policy.CheckAction{Cmd: "bash", Args: []string{"-c", statScript, "sentinelx", oddPath},
+				ExpectPattern: "^[01234567][0246][0145][01] 0 42$"},
+			remediation: policy.Action{Type: "command", Cmd: "bash", Args: []string{"-c",
+				`(b=` + oddBak + `; mkdir -p /var/lib/sentinelx/perms; [ -e "$b" ] || stat -c '%a %u %g' ` + oddArg + ` > "$b"; ` +
+					`chown '0:42' ` + oddArg + ` && chmod u-x,g-w,o-rw ` + oddArg + `)`}},
+			rollback: policy.Action{Type: "command", Cmd: "bash", Args: []string{"-c",
+				`(b=` + oddBak + `; [ -e "$b" ] || exit 0; read -r m u g < "$b"; chown "$u:$g" ` + oddArg + ` && chmod "$m" ` + oddArg + ` && rm -f "$b")`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			r := rules[tt.id]
+			if r.Type != "command" {
+				t.Errorf("type %s, want command", r.Type)
+			}
+			if !reflect.DeepEqual(r.Check, tt.check) {
+				t.Errorf("check\n got %#v\nwant %#v", r.Check, tt.check)
+			}
+			if !reflect.DeepEqual(r.Remediation, tt.remediation) {
+				t.Errorf("remediation\n got %#v\nwant %#v", r.Remediation, tt.remediation)
+			}
+			if !reflect.DeepEqual(r.Rollback, tt.rollback) {
+				t.Errorf("rollback\n got %#v\nwant %#v", r.Rollback, tt.rollback)
+			}
+		})
+	}
+
+	// An AND of a sysctl definition and a file test is checked as one shell
+	// condition and fixed (and rolled back) test by test
+	both := rules["forwarding_and_shadow"]
+	if both.Check.Cmd != "bash" || both.Check.ExpectPattern != "" ||
+		!strings.Contains(both.Check.Args[1], `grep -Eqx '[01234567][01234567][01234567][0123] 0 [^ ]+'`) ||
+		!strings.Contains(both.Check.Args[1], `) && ([ "$(sysctl -n 'net.ipv4.ip_forward' 2>/dev/null | xargs)" = '0' ])`) {
+		t.Errorf("combined check %q", both.Check.Args)
+	}
+	if fix := both.Remediation.Args[1]; !strings.HasPrefix(fix, `(b='/var/lib/sentinelx/perms/etc_shadow';`) || !strings.HasSuffix(fix, ") && "+sysctlFix) {
+		t.Errorf("combined fix %q", fix)
+	}
+	if undo := both.Rollback.Args[1]; !strings.HasSuffix(undo, ") && "+sysctlUndo) {
+		t.Errorf("combined rollback %q", undo)
+	}
+}
+
+// Every generated script parses, and quoted paths come out of the shell as
+// they went in.
+func TestOVALScriptsParse(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("no bash")
+	}
+	_, rules := importFixture(t, Options{})
+	for id, r := range rules {
+		for _, a := range []struct {
+			cmd  string
+			args []string
+		}{{r.Check.Cmd, r.Check.Args}, {r.Remediation.Cmd, r.Remediation.Args}, {r.Rollback.Cmd, r.Rollback.Args}} {
+			if a.cmd != "bash" {
+				continue
+			}
+			if out, err := exec.Command("bash", "-n", "-c", a.args[1]).CombinedOutput(); err != nil {
+				t.Errorf("%s: %v: %s\n%s", id, err, out, a.args[1])
+			}
+		}
+	}
+
+	for _, s := range []string{`/etc/it's "odd"/a b.conf`, `$(echo injected)`, `a\b`, ""} {
+		out, err := exec.Command("bash", "-c", "printf %s "+shellQuote(s)).Output()
+		if err != nil || string(out) != s {
+			t.Errorf("shellQuote(%q) came back as %q (%v)", s, out, err)
+		}
+	}
+}
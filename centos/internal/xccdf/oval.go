@@ -0,0 +1,481 @@
+package xccdf
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxDepth bounds extend_definition chains.
+const maxDepth = 16
+
+// compiled is an OVAL test or criteria tree translated to engine terms.
+type compiled struct {
+	// Native form, used when the whole check is a single test
+	Cmd    string
+	Args   []string
+	Expect string
+
+	// Shell is the same check as a shell condition, for combining tests
+	Shell string
+
+	// Fix and Undo remediate and roll back the test; nil when the test can't
+	// be fixed automatically
+	Fix  []string
+	Undo []string
+}
+
+// asShell returns c in its combined (bash -c) form.
+func asShell(shell string) *compiled {
+	return &compiled{Cmd: "bash", Args: []string{"-c", shell}, Shell: shell}
+}
+
+type ovalIndex struct {
+	defs    map[string]definition
+	tests   map[string]node
+	objects map[string]node
+	states  map[string]node
+}
+
+func newOvalIndex(docs []ovalDocument) *ovalIndex {
+	ix := &ovalIndex{
+		defs:    make(map[string]definition),
+		tests:   make(map[string]node),
+		objects: make(map[string]node),
+		states:  make(map[string]node),
+	}
+	for _, doc := range docs {
+		for _, d := range doc.Definitions {
+			ix.defs[d.ID] = d
+		}
+		for _, n := range doc.Tests.Children {
+			ix.tests[n.attr("id")] = n
+		}
+		for _, n := range doc.Objects.Children {
+			ix.objects[n.attr("id")] = n
+		}
+		for _, n := range doc.States.Children {
+			ix.states[n.attr("id")] = n
+		}
+	}
+	return ix
+}
+
+func (ix *ovalIndex) compileDefinition(id string, depth int) (*compiled, error) {
+	if depth > maxDepth {
+		return nil, fmt.Errorf("definition %s nests too deeply", id)
+	}
+	def, ok := ix.defs[id]
+	if !ok {
+		return nil, fmt.Errorf("OVAL definition %s not found", id)
+	}
+	if def.Criteria == nil {
+		return nil, fmt.Errorf("definition %s has no criteria", id)
+	}
+	return ix.compileCriteria(*def.Criteria, depth)
+}
+
+func (ix *ovalIndex) compileCriteria(c criteria, depth int) (*compiled, error) {
+	var parts []*compiled
+	for _, t := range c.Criterion {
+		part, err := ix.compileTest(t.TestRef)
+		if err != nil {
+			return nil, err
+		}
+		if t.Negate {
+			part = negate(part)
+		}
+		parts = append(parts, part)
+	}
+	for _, sub := range c.Criteria {
+		part, err := ix.compileCriteria(sub, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	for _, ext := range c.Extends {
+		part, err := ix.compileDefinition(ext.DefinitionRef, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if ext.Negate {
+			part = negate(part)
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("empty criteria")
+	}
+
+	var result *compiled
+	if len(parts) == 1 {
+		result = parts[0]
+	} else {
+		joiner := " && "
+		switch strings.ToUpper(c.Operator) {
+		case "", "AND":
+		case "OR":
+			joiner = " || "
+		default:
+			return nil, fmt.Errorf("criteria operator %s", c.Operator)
+		}
+
+		shells := make([]string, len(parts))
+		for i, p := range parts {
+			shells[i] = "(" + p.Shell + ")"
+		}
+		result = asShell(strings.Join(shells, joiner))
+
+		// Only an AND of fixable tests is fixable: fix each one
+		if joiner == " && " {
+			for _, p := range parts {
+				if p.Fix == nil {
+					result.Fix, result.Undo = nil, nil
+					break
+				}
+				result.Fix = append(result.Fix, p.Fix...)
+				result.Undo = append(result.Undo, p.Undo...)
+			}
+		}
+	}
+	if c.Negate {
+		result = negate(result)
+	}
+	return result, nil
+}
+
+func negate(c *compiled) *compiled {
+	return asShell("! (" + c.Shell + ")")
+}
+
+// compileTest translates one OVAL test. Supported: textfilecontent54 (pattern
+// presence), sysctl (value equality) and file (permission bits and owner).
+func (ix *ovalIndex) compileTest(id string) (*compiled, error) {
+	test, ok := ix.tests[id]
+	if !ok {
+		return nil, fmt.Errorf("OVAL test %s not found", id)
+	}
+	objRef := ""
+	if o := test.child("object"); o != nil {
+		objRef = o.attr("object_ref")
+	}
+	obj, ok := ix.objects[objRef]
+	if !ok {
+		return nil, fmt.Errorf("OVAL object %s not found", objRef)
+	}
+	var states []node
+	for _, c := range test.Children {
+		if c.XMLName.Local == "state" {
+			st, ok := ix.states[c.attr("state_ref")]
+			if !ok {
+				return nil, fmt.Errorf("OVAL state %s not found", c.attr("state_ref"))
+			}
+			states = append(states, st)
+		}
+	}
+	existence := test.attr("check_existence")
+
+	switch test.XMLName.Local {
+	case "textfilecontent54_test":
+		return compileTextFileContent(obj, states, existence)
+	case "sysctl_test":
+		return compileSysctl(obj, states, existence)
+	case "file_test":
+		return compileFile(obj, states, existence)
+	}
+	return nil, fmt.Errorf("OVAL %s is not supported", test.XMLName.Local)
+}
+
+// --- TEXTFILECONTENT54 ---
+
+func compileTextFileContent(obj node, states []node, existence string) (*compiled, error) {
+	if len(states) > 0 {
+		return nil, fmt.Errorf("textfilecontent54 state comparison is not supported")
+	}
+	path, err := objectPath(obj)
+	if err != nil {
+		return nil, err
+	}
+	pat := obj.child("pattern")
+	if pat == nil || pat.attr("var_ref") != "" || pat.attr("operation") != "pattern match" {
+		return nil, fmt.Errorf("textfilecontent54 object without a literal pattern")
+	}
+	if strings.Contains(pat.Text, `\n`) {
+		return nil, fmt.Errorf("multi-line textfilecontent54 pattern")
+	}
+	if inst := obj.child("instance"); inst != nil {
+		op, val := inst.attr("operation"), strings.TrimSpace(inst.Text)
+		if inst.attr("var_ref") != "" || val != "1" || (op != "greater than or equal" && op != "equals" && op != "") {
+			return nil, fmt.Errorf("textfilecontent54 instance selection is not supported")
+		}
+	}
+
+	switch existence {
+	case "", "at_least_one_exists", "only_one_exists":
+		return &compiled{
+			Cmd:   "grep",
+			Args:  []string{"-Pq", "-e", pat.Text, path},
+			Shell: "grep -Pq -e " + shellQuote(pat.Text) + " " + shellQuote(path),
+		}, nil
+	case "none_exist":
+		return asShell("! grep -Pqs -e " + shellQuote(pat.Text) + " " + shellQuote(path)), nil
+	case "any_exist":
+		return asShell("true"), nil
+	}
+	return nil, fmt.Errorf("check_existence %s", existence)
+}
+
+// --- SYSCTL ---
+
+func compileSysctl(obj node, states []node, existence string) (*compiled, error) {
+	name := obj.child("name")
+	if name == nil || name.attr("var_ref") != "" || !isEquals(name.attr("operation")) {
+		return nil, fmt.Errorf("sysctl object without a literal name")
+	}
+	key := strings.TrimSpace(name.Text)
+	qKey := shellQuote(key)
+
+	if len(states) == 0 {
+		c := asShell("sysctl -n " + qKey + " >/dev/null 2>&1")
+		if existence == "none_exist" {
+			return negate(c), nil
+		}
+		return c, nil
+	}
+	if len(states) > 1 || (existence != "" && existence != "at_least_one_exists") {
+		return nil, fmt.Errorf("sysctl test shape is not supported")
+	}
+
+	value := ""
+	for _, c := range states[0].Children {
+		switch c.XMLName.Local {
+		case "value":
+			if c.attr("var_ref") != "" || !isEquals(c.attr("operation")) {
+				return nil, fmt.Errorf("sysctl value comparison %q is not supported", c.attr("operation"))
+			}
+			value = strings.Join(strings.Fields(c.Text), " ")
+		case "name":
+		default:
+			return nil, fmt.Errorf("sysctl state field %s is not supported", c.XMLName.Local)
+		}
+	}
+	if value == "" {
+		return nil, fmt.Errorf("sysctl state without a value")
+	}
+
+	var quoted []string
+	for _, f := range strings.Fields(value) {
+		quoted = append(quoted, regexp.QuoteMeta(f))
+	}
+
+	// The fix persists the value in its own sysctl.d file and records the
+	// value it replaced there, so the rollback can restore it exactly
+	conf := shellQuote("/etc/sysctl.d/60-sentinelx-" + key + ".conf")
+	setting := shellQuote(key + "=" + value)
+	fix := fmt.Sprintf(`(f=%s; [ -e "$f" ] || printf '# previous: %%s\n' "$(sysctl -n %s)" > "$f"; sed -i '/^[^#]/d' "$f"; printf '%%s = %%s\n' %s %s >> "$f"; sysctl -q -w %s)`,
+		conf, qKey, qKey, shellQuote(value), setting)
+	undo := fmt.Sprintf(`(f=%s; [ -e "$f" ] || exit 0; old=$(sed -n 's/^# previous: //p' "$f"); rm -f "$f"; [ -z "$old" ] || sysctl -q -w %s="$old")`,
+		conf, qKey)
+
+	return &compiled{
+		Cmd:    "sysctl",
+		Args:   []string{"-n", key},
+		Expect: "^" + strings.Join(quoted, `\s+`) + "$",
+		Shell:  fmt.Sprintf(`[ "$(sysctl -n %s 2>/dev/null | xargs)" = %s ]`, qKey, shellQuote(value)),
+		Fix:    []string{fix},
+		Undo:   []string{undo},
+	}, nil
+}
+
+// --- FILE PERMISSIONS ---
+
+// permBits maps file_state booleans to (octal digit index, bit) in a
+// four-digit mode such as 0644.
+var permBits = map[string][2]int{
+	"suid": {0, 4}, "sgid": {0, 2}, "sticky": {0, 1},
+	"uread": {1, 4}, "uwrite": {1, 2}, "uexec": {1, 1},
+	"gread": {2, 4}, "gwrite": {2, 2}, "gexec": {2, 1},
+	"oread": {3, 4}, "owrite": {3, 2}, "oexec": {3, 1},
+}
+
+const statScript = `printf '%04o %s %s\n' "0$(stat -c %a "$1")" $(stat -c '%u %g' "$1")`
+
+func compileFile(obj node, states []node, existence string) (*compiled, error) {
+	path, err := objectPath(obj)
+	if err != nil {
+		return nil, err
+	}
+	qPath := shellQuote(path)
+
+	if len(states) == 0 {
+		switch existence {
+		case "none_exist":
+			return asShell("[ ! -e " + qPath + " ]"), nil
+		case "", "at_least_one_exists", "only_one_exists":
+			return asShell("[ -e " + qPath + " ]"), nil
+		}
+		return nil, fmt.Errorf("check_existence %s", existence)
+	}
+	if len(states) > 1 {
+		return nil, fmt.Errorf("file test with several states")
+	}
+
+	var set, clear [4]int
+	uid, gid := "", ""
+	for _, c := range states[0].Children {
+		name := c.XMLName.Local
+		if c.attr("var_ref") != "" || !isEquals(c.attr("operation")) {
+			return nil, fmt.Errorf("file_state %s comparison is not supported", name)
+		}
+		value := strings.TrimSpace(c.Text)
+		if bit, ok := permBits[name]; ok {
+			if value == "true" || value == "1" {
+				set[bit[0]] |= bit[1]
+			} else {
+				clear[bit[0]] |= bit[1]
+			}
+			continue
+		}
+		switch name {
+		case "user_id", "group_id":
+			// Both end up in the stat pattern and the chown, so only numeric IDs
+			if _, err := strconv.ParseUint(value, 10, 32); err != nil {
+				return nil, fmt.Errorf("file_state %s %q is not a numeric ID", name, value)
+			}
+			if name == "user_id" {
+				uid = value
+			} else {
+				gid = value
+			}
+		default:
+			return nil, fmt.Errorf("file_state field %s is not supported", name)
+		}
+	}
+
+	// Build the expected "mode uid gid" line and the chmod that produces it
+	pattern := ""
+	var symbolic []string
+	classes := []string{"u", "g", "o"}
+	for i := 0; i < 4; i++ {
+		digits := ""
+		for d := 0; d < 8; d++ {
+			if d&clear[i] == 0 && d&set[i] == set[i] {
+				digits += fmt.Sprint(d)
+			}
+		}
+		pattern += "[" + digits + "]"
+		if i == 0 {
+			continue
+		}
+		if letters := modeLetters(clear[i]); letters != "" {
+			symbolic = append(symbolic, classes[i-1]+"-"+letters)
+		}
+		if letters := modeLetters(set[i]); letters != "" {
+			symbolic = append(symbolic, classes[i-1]+"+"+letters)
+		}
+	}
+	for _, special := range []struct {
+		bit   int
+		usage string
+	}{{4, "u%ss"}, {2, "g%ss"}, {1, "o%st"}} {
+		if clear[0]&special.bit != 0 {
+			symbolic = append(symbolic, fmt.Sprintf(special.usage, "-"))
+		}
+		if set[0]&special.bit != 0 {
+			symbolic = append(symbolic, fmt.Sprintf(special.usage, "+"))
+		}
+	}
+	pattern += " " + orAny(uid) + " " + orAny(gid)
+
+	if existence == "any_exist" {
+		return asShell(fmt.Sprintf("[ ! -e %s ] || bash -c %s sentinelx %s | grep -Eqx %s", qPath, shellQuote(statScript), qPath, shellQuote(pattern))), nil
+	}
+	if existence != "" && existence != "at_least_one_exists" && existence != "only_one_exists" {
+		return nil, fmt.Errorf("check_existence %s with a file state", existence)
+	}
+
+	// The fix keeps the original "mode uid gid" so the rollback can restore it
+	backup := shellQuote("/var/lib/sentinelx/perms/" + strings.ReplaceAll(strings.TrimPrefix(path, "/"), "/", "_"))
+	// chown clears setuid/setgid, so it has to run before chmod
+	var steps []string
+	switch {
+	case uid != "" && gid != "":
+		steps = append(steps, "chown "+shellQuote(uid+":"+gid)+" "+qPath)
+	case uid != "":
+		steps = append(steps, "chown "+shellQuote(uid)+" "+qPath)
+	case gid != "":
+		steps = append(steps, "chgrp "+shellQuote(gid)+" "+qPath)
+	}
+	if len(symbolic) > 0 {
+		steps = append(steps, "chmod "+strings.Join(symbolic, ",")+" "+qPath)
+	}
+	fix := fmt.Sprintf(`(b=%s; mkdir -p /var/lib/sentinelx/perms; [ -e "$b" ] || stat -c '%%a %%u %%g' %s > "$b"; %s)`,
+		backup, qPath, strings.Join(steps, " && "))
+	undo := fmt.Sprintf(`(b=%s; [ -e "$b" ] || exit 0; read -r m u g < "$b"; chown "$u:$g" %s && chmod "$m" %s && rm -f "$b")`,
+		backup, qPath, qPath)
+
+	return &compiled{
+		Cmd:    "bash",
+		Args:   []string{"-c", statScript, "sentinelx", path},
+		Expect: "^" + pattern + "$",
+		Shell:  fmt.Sprintf("bash -c %s sentinelx %s | grep -Eqx %s", shellQuote(statScript), qPath, shellQuote(pattern)),
+		Fix:    []string{fix},
+		Undo:   []string{undo},
+	}, nil
+}
+
+func modeLetters(bits int) string {
+	letters := ""
+	for _, b := range []struct {
+		bit    int
+		letter string
+	}{{4, "r"}, {2, "w"}, {1, "x"}} {
+		if bits&b.bit != 0 {
+			letters += b.letter
+		}
+	}
+	return letters
+}
+
+func orAny(id string) string {
+	if id == "" {
+		return "[^ ]+"
+	}
+	return regexp.QuoteMeta(id)
+}
+
+// --- HELPERS ---
+
+// objectPath returns the literal path of a file or textfilecontent object.
+func objectPath(obj node) (string, error) {
+	if b := obj.child("behaviors"); b != nil && b.attr("recurse_direction") != "" && b.attr("recurse_direction") != "none" {
+		return "", fmt.Errorf("recursive OVAL object")
+	}
+	if fp := obj.child("filepath"); fp != nil {
+		if fp.attr("var_ref") != "" || !isEquals(fp.attr("operation")) {
+			return "", fmt.Errorf("OVAL filepath %q uses a pattern or variable", fp.Text)
+		}
+		return strings.TrimSpace(fp.Text), nil
+	}
+	dir, file := obj.child("path"), obj.child("filename")
+	if dir == nil || file == nil {
+		return "", fmt.Errorf("OVAL object without a file path")
+	}
+	for _, n := range []*node{dir, file} {
+		if n.attr("var_ref") != "" || !isEquals(n.attr("operation")) {
+			return "", fmt.Errorf("OVAL path %q uses a pattern or variable", n.Text)
+		}
+	}
+	return strings.TrimRight(strings.TrimSpace(dir.Text), "/") + "/" + strings.TrimSpace(file.Text), nil
+}
+
+func isEquals(op string) bool {
+	return op == "" || op == "equals"
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,171 @@
+package xccdf
+
+import (
+	"encoding/xml"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// --- XCCDF 1.1 / 1.2 ---
+// Tags use local names only, so both namespaces (and SCAP data streams,
+// which embed the same elements) decode with one set of types.
+
+type benchmark struct {
+	ID       string    `xml:"id,attr"`
+	Title    string    `xml:"title"`
+	Version  string    `xml:"version"`
+	Profiles []profile `xml:"Profile"`
+	Groups   []group   `xml:"Group"`
+	Rules    []rule    `xml:"Rule"`
+}
+
+type profile struct {
+	ID      string      `xml:"id,attr"`
+	Title   string      `xml:"title"`
+	Selects []selectRef `xml:"select"`
+}
+
+type selectRef struct {
+	IDRef    string `xml:"idref,attr"`
+	Selected bool   `xml:"selected,attr"`
+}
+
+type group struct {
+	ID     string  `xml:"id,attr"`
+	Title  string  `xml:"title"`
+	Groups []group `xml:"Group"`
+	Rules  []rule  `xml:"Rule"`
+}
+
+type rule struct {
+	ID          string      `xml:"id,attr"`
+	Selected    string      `xml:"selected,attr"`
+	Severity    string      `xml:"severity,attr"`
+	Title       string      `xml:"title"`
+	Description richText    `xml:"description"`
+	Rationale   richText    `xml:"rationale"`
+	References  []reference `xml:"reference"`
+	Idents      []ident     `xml:"ident"`
+	Fixes       []fix       `xml:"fix"`
+	Checks      []check     `xml:"check"`
+}
+
+// richText is XHTML mixed content (descriptions, rationales).
+type richText struct {
+	Inner string `xml:",innerxml"`
+}
+
+type reference struct {
+	Href  string `xml:"href,attr"`
+	Inner string `xml:",innerxml"`
+}
+
+type ident struct {
+	System string `xml:"system,attr"`
+	Text   string `xml:",chardata"`
+}
+
+type fix struct {
+	System string `xml:"system,attr"`
+	Inner  string `xml:",innerxml"`
+}
+
+type check struct {
+	System string     `xml:"system,attr"`
+	Refs   []checkRef `xml:"check-content-ref"`
+}
+
+type checkRef struct {
+	Href string `xml:"href,attr"`
+	Name string `xml:"name,attr"`
+}
+
+// --- OVAL 5 ---
+
+const ovalSystem = "http://oval.mitre.org/XMLSchema/oval-definitions-5"
+
+type ovalDocument struct {
+	Definitions []definition `xml:"definitions>definition"`
+	Tests       node         `xml:"tests"`
+	Objects     node         `xml:"objects"`
+	States      node         `xml:"states"`
+}
+
+type definition struct {
+	ID       string    `xml:"id,attr"`
+	Title    string    `xml:"metadata>title"`
+	Criteria *criteria `xml:"criteria"`
+}
+
+type criteria struct {
+	Operator  string      `xml:"operator,attr"`
+	Negate    bool        `xml:"negate,attr"`
+	Criteria  []criteria  `xml:"criteria"`
+	Criterion []criterion `xml:"criterion"`
+	Extends   []extendDef `xml:"extend_definition"`
+}
+
+type criterion struct {
+	TestRef string `xml:"test_ref,attr"`
+	Negate  bool   `xml:"negate,attr"`
+}
+
+type extendDef struct {
+	DefinitionRef string `xml:"definition_ref,attr"`
+	Negate        bool   `xml:"negate,attr"`
+}
+
+// node is any OVAL test, object or state; their schemas differ per test type
+// so they are kept generic and interpreted in oval.go.
+type node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []node     `xml:",any"`
+}
+
+func (n node) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n node) child(name string) *node {
+	for i := range n.Children {
+		if n.Children[i].XMLName.Local == name {
+			return &n.Children[i]
+		}
+	}
+	return nil
+}
+
+// --- TEXT HELPERS ---
+
+var (
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+	spacePattern = regexp.MustCompile(`\s+`)
+	cdataPattern = regexp.MustCompile(`(?s)<!\[CDATA\[(.*?)\]\]>`)
+)
+
+// plainText flattens XHTML content to a single line of text.
+func plainText(inner string) string {
+	inner = cdataPattern.ReplaceAllString(inner, "$1")
+	text := html.UnescapeString(tagPattern.ReplaceAllString(inner, " "))
+	return strings.TrimSpace(spacePattern.ReplaceAllString(text, " "))
+}
+
+// scriptText returns a fix script body, keeping its line breaks.
+func scriptText(inner string) string {
+	if blocks := cdataPattern.FindAllStringSubmatch(inner, -1); blocks != nil {
+		var parts []string
+		for _, b := range blocks {
+			parts = append(parts, b[1])
+		}
+		return strings.TrimSpace(strings.Join(parts, "\n"))
+	}
+	return strings.TrimSpace(html.UnescapeString(inner))
+}